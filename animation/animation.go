@@ -0,0 +1,221 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+/*
+Package animation implements animations driven by the frame time of a
+layout.Context.
+
+Animations advance when their state is updated with the time in
+layout.Context.Now, and request a new frame through op.InvalidateCmd
+only while they are running, so an idle user interface doesn't redraw.
+
+A Transition animates the dimensions of keyed children of a layout
+between frames. For example, to smoothly resize a flexed child:
+
+	var tr animation.Transition
+
+	layout.Flex{}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return tr.Layout(gtx, "sidebar", true, sidebar)
+		}),
+		layout.Flexed(1, content),
+	)
+
+Motion is described either by a Curve applied over a fixed duration, or
+by the physics of a Spring.
+
+A Tween animates a Value such as a float32, f32.Point or color.NRGBA
+over a fixed duration, and a Follower moves a Value towards a target
+that may change at any time. Tweens scheduled to start in the future
+request a frame for their start time through op.InvalidateCmd.At. Timed
+animations are composed with Sequence and Parallel:
+
+	fade := &animation.Tween[float32]{From: 0, To: 1, Duration: 200 * time.Millisecond}
+	move := &animation.Tween[f32.Point]{From: a, To: b, Duration: time.Second, Curve: animation.EaseOut}
+	animation.Sequence{fade, move}.Start(gtx.Now)
+
+	alpha := fade.Value(gtx)
+	pos := move.Value(gtx)
+*/
+package animation
+
+import (
+	"math"
+	"time"
+)
+
+// Curve maps the linear progress of an animation, from 0 to 1, to the
+// progress of the animated value. Curves must map 0 to 0 and 1 to 1, but
+// may overshoot in between.
+type Curve func(t float32) float32
+
+// Linear progresses at a constant rate.
+func Linear(t float32) float32 {
+	return t
+}
+
+// EaseIn starts slowly and accelerates.
+func EaseIn(t float32) float32 {
+	return t * t * t
+}
+
+// EaseOut starts quickly and decelerates.
+func EaseOut(t float32) float32 {
+	t = 1 - t
+	return 1 - t*t*t
+}
+
+// EaseInOut accelerates until halfway, then decelerates.
+func EaseInOut(t float32) float32 {
+	if t < .5 {
+		return 4 * t * t * t
+	}
+	t = -2*t + 2
+	return 1 - t*t*t/2
+}
+
+// CubicBezier returns the Curve defined by a cubic Bézier curve from (0, 0)
+// to (1, 1) with control points (x1, y1) and (x2, y2), similar to the CSS
+// cubic-bezier timing function. The x coordinates must be in the range
+// [0, 1].
+func CubicBezier(x1, y1, x2, y2 float32) Curve {
+	bezier := func(t, p1, p2 float32) float32 {
+		u := 1 - t
+		return 3*u*u*t*p1 + 3*u*t*t*p2 + t*t*t
+	}
+	return func(x float32) float32 {
+		if x <= 0 || x >= 1 {
+			return x
+		}
+		// Solve bezier(t, x1, x2) = x by bisection; x is monotonic in t.
+		lo, hi := float32(0), float32(1)
+		t := x
+		for i := 0; i < 24; i++ {
+			if bezier(t, x1, x2) < x {
+				lo = t
+			} else {
+				hi = t
+			}
+			t = (lo + hi) / 2
+		}
+		return bezier(t, y1, y2)
+	}
+}
+
+// Spring describes the motion of a damped spring pulling a value towards
+// its target.
+type Spring struct {
+	// Stiffness is the force of the spring per unit of distance. Higher
+	// values result in faster motion.
+	Stiffness float32
+	// DampingRatio controls the oscillation of the spring. A ratio of 1
+	// is critically damped and settles without overshoot, lower values
+	// bounce. A zero ratio is treated as 1.
+	DampingRatio float32
+}
+
+// DefaultSpring is a critically damped spring suitable for most user
+// interface motion.
+var DefaultSpring = Spring{Stiffness: 400, DampingRatio: 1}
+
+// maxSpringStep is the largest time step for integrating spring motion.
+const maxSpringStep = 2 * time.Millisecond
+
+// Step advances the position x and velocity v of a value pulled towards
+// target by the spring for a duration of dt. Velocity is measured in units
+// per second.
+func (s Spring) Step(x, v, target float32, dt time.Duration) (float32, float32) {
+	damping := s.DampingRatio
+	if damping == 0 {
+		damping = 1
+	}
+	c := 2 * damping * float32(math.Sqrt(float64(s.Stiffness)))
+	for dt > 0 {
+		step := dt
+		if step > maxSpringStep {
+			step = maxSpringStep
+		}
+		dt -= step
+		h := float32(step.Seconds())
+		a := -s.Stiffness*(x-target) - c*v
+		v += a * h
+		x += v * h
+	}
+	return x, v
+}
+
+// motion describes how a value moves towards its target.
+type motion struct {
+	duration time.Duration
+	curve    Curve
+	spring   Spring
+}
+
+// value is a single animated quantity.
+type value struct {
+	from, to float32
+	// cur and vel are the current value and its velocity.
+	cur, vel float32
+	// start is the start time of a curve animation, and last the time of
+	// the most recent spring step.
+	start, last time.Time
+	active      bool
+}
+
+// set the value immediately, stopping any animation.
+func (v *value) set(x float32) {
+	*v = value{from: x, to: x, cur: x}
+}
+
+// animate the value towards target, starting at now.
+func (v *value) animate(now time.Time, target float32) {
+	if target == v.to {
+		return
+	}
+	v.from = v.cur
+	v.to = target
+	v.start = now
+	v.last = now
+	v.active = true
+}
+
+// step advances the animation to now and reports whether it is still
+// running. Values within precision of their target and moving slower
+// than precision per second are considered settled.
+func (v *value) step(now time.Time, m motion, precision float32) bool {
+	if !v.active {
+		return false
+	}
+	if now.IsZero() {
+		// Without a frame time there is no animation.
+		v.set(v.to)
+		return false
+	}
+	if m.spring.Stiffness > 0 {
+		if dt := now.Sub(v.last); dt > 0 {
+			v.cur, v.vel = m.spring.Step(v.cur, v.vel, v.to, dt)
+			v.last = now
+		}
+		if abs(v.cur-v.to) < precision && abs(v.vel) < precision {
+			v.set(v.to)
+		}
+		return v.active
+	}
+	elapsed := now.Sub(v.start)
+	if elapsed >= m.duration {
+		v.set(v.to)
+		return false
+	}
+	t := float32(elapsed) / float32(m.duration)
+	if m.curve != nil {
+		t = m.curve(t)
+	}
+	v.cur = v.from + (v.to-v.from)*t
+	return true
+}
+
+func abs(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package animation
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+)
+
+func TestCurves(t *testing.T) {
+	curves := map[string]Curve{
+		"Linear":      Linear,
+		"EaseIn":      EaseIn,
+		"EaseOut":     EaseOut,
+		"EaseInOut":   EaseInOut,
+		"CubicBezier": CubicBezier(.25, .1, .25, 1),
+	}
+	for name, c := range curves {
+		if got := c(0); abs(got) > 1e-4 {
+			t.Errorf("%s(0) = %v, want 0", name, got)
+		}
+		if got := c(1); abs(got-1) > 1e-4 {
+			t.Errorf("%s(1) = %v, want 1", name, got)
+		}
+	}
+	if got := CubicBezier(0, 0, 1, 1)(.3); abs(got-.3) > 1e-3 {
+		t.Errorf("linear CubicBezier(.3) = %v, want .3", got)
+	}
+}
+
+func TestSpringSettles(t *testing.T) {
+	s := DefaultSpring
+	x, v := float32(0), float32(0)
+	for i := 0; i < 60; i++ {
+		x, v = s.Step(x, v, 100, time.Second/60)
+		if x > 100.5 {
+			t.Fatalf("critically damped spring overshot: %v", x)
+		}
+	}
+	if abs(x-100) > .5 {
+		t.Errorf("spring didn't settle after a second: %v", x)
+	}
+}
+
+func TestTransition(t *testing.T) {
+	tr := Transition{Duration: 100 * time.Millisecond}
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Now:         time.Unix(1, 0),
+		Constraints: layout.Constraints{Max: image.Pt(1000, 1000)},
+	}
+	size := image.Pt(10, 10)
+	w := func(gtx layout.Context) layout.Dimensions {
+		return layout.Dimensions{Size: size}
+	}
+	frame := func(d time.Duration, visible bool) image.Point {
+		gtx.Now = gtx.Now.Add(d)
+		gtx.Ops.Reset()
+		return tr.Layout(gtx, "child", visible, w).Size
+	}
+	if got := frame(0, true); got != size {
+		t.Errorf("initial size %v, want %v", got, size)
+	}
+	size = image.Pt(30, 10)
+	if got, want := frame(time.Millisecond, true), image.Pt(10, 10); got != want {
+		t.Errorf("resize start: got %v, want %v", got, want)
+	}
+	if got, want := frame(50*time.Millisecond, true), image.Pt(20, 10); got != want {
+		t.Errorf("resize half-way: got %v, want %v", got, want)
+	}
+	if !tr.Animating("child") {
+		t.Error("child not animating")
+	}
+	if got, want := frame(50*time.Millisecond, true), image.Pt(30, 10); got != want {
+		t.Errorf("resize end: got %v, want %v", got, want)
+	}
+	if tr.Animating("child") {
+		t.Error("child still animating")
+	}
+	frame(time.Millisecond, false)
+	if got, want := frame(100*time.Millisecond, false), (image.Point{}); got != want {
+		t.Errorf("hidden size: got %v, want %v", got, want)
+	}
+}
+
+func TestTransitionAppear(t *testing.T) {
+	tr := Transition{Spring: DefaultSpring}
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Now:         time.Unix(1, 0),
+		Constraints: layout.Constraints{Max: image.Pt(1000, 1000)},
+	}
+	w := func(gtx layout.Context) layout.Dimensions {
+		return layout.Dimensions{Size: image.Pt(100, 100)}
+	}
+	tr.Layout(gtx, 1, true, w)
+	gtx.Now = gtx.Now.Add(time.Second / 60)
+	tr.Layout(gtx, 1, true, w)
+	if got := tr.Layout(gtx, 2, true, w).Size; got != (image.Point{}) {
+		t.Errorf("appearing child has size %v, want zero", got)
+	}
+	for i := 0; i < 120; i++ {
+		gtx.Now = gtx.Now.Add(time.Second / 60)
+		tr.Layout(gtx, 2, true, w)
+	}
+	if tr.Animating(2) {
+		t.Error("spring animation didn't settle")
+	}
+	if _, ok := tr.children[1]; ok {
+		t.Error("child omitted from layout wasn't forgotten")
+	}
+}
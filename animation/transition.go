@@ -0,0 +1,174 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package animation
+
+import (
+	"image"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+)
+
+// Transition animates the dimensions and offsets of keyed children of a
+// layout whenever they change between frames. The zero value animates
+// linearly with a zero duration, that is, without animation.
+type Transition struct {
+	// Duration of curve animations.
+	Duration time.Duration
+	// Curve of the animations. A nil Curve progresses linearly.
+	Curve Curve
+	// Spring, if its Stiffness is non-zero, replaces Duration and Curve with
+	// spring physics.
+	Spring Spring
+
+	now time.Time
+	// first tracks whether the current frame is the first.
+	first    bool
+	children map[any]*transitionChild
+}
+
+type transitionChild struct {
+	// size contains the width, height and baseline.
+	size    [3]value
+	sizeSet bool
+	offset  [2]value
+	offSet  bool
+	// seen tracks whether the child was laid out in the current frame.
+	seen bool
+}
+
+// Layout w and return its dimensions, animated from the dimensions of the
+// previous layout of the child identified by key. Children laid out for the
+// first time grow from zero size, except in the first frame of the
+// Transition. If visible is false, w is laid out but its dimensions
+// animate towards zero, after which Animating reports false and the
+// child may be omitted.
+//
+// The drawing of w is clipped to the animated dimensions while they are
+// animating.
+func (t *Transition) Layout(gtx layout.Context, key any, visible bool, w layout.Widget) layout.Dimensions {
+	first := t.frame(gtx.Now)
+	c := t.child(key)
+	macro := op.Record(gtx.Ops)
+	dims := w(gtx)
+	call := macro.Stop()
+	target := [3]int{dims.Size.X, dims.Size.Y, dims.Baseline}
+	if !visible {
+		target = [3]int{}
+	}
+	if !c.sizeSet {
+		c.sizeSet = true
+		for i := range c.size {
+			if first || !visible {
+				c.size[i].set(float32(target[i]))
+			} else {
+				c.size[i].set(0)
+			}
+		}
+	}
+	animating := false
+	var cur [3]int
+	for i := range c.size {
+		v := &c.size[i]
+		v.animate(gtx.Now, float32(target[i]))
+		if v.step(gtx.Now, t.motion(), .5) {
+			animating = true
+		}
+		cur[i] = int(v.cur + .5)
+	}
+	dims = layout.Dimensions{
+		Size:     gtx.Constraints.Constrain(image.Pt(cur[0], cur[1])),
+		Baseline: cur[2],
+	}
+	if !animating {
+		if visible {
+			call.Add(gtx.Ops)
+		}
+		return dims
+	}
+	gtx.Execute(op.InvalidateCmd{})
+	defer clip.Rect{Max: dims.Size}.Push(gtx.Ops).Pop()
+	call.Add(gtx.Ops)
+	return dims
+}
+
+// Offset returns the offset of the child identified by key, animated from
+// the previous target offsets to target. The first offset of a child is
+// not animated.
+func (t *Transition) Offset(gtx layout.Context, key any, target image.Point) image.Point {
+	t.frame(gtx.Now)
+	c := t.child(key)
+	if !c.offSet {
+		c.offSet = true
+		c.offset[0].set(float32(target.X))
+		c.offset[1].set(float32(target.Y))
+		return target
+	}
+	c.offset[0].animate(gtx.Now, float32(target.X))
+	c.offset[1].animate(gtx.Now, float32(target.Y))
+	ax := c.offset[0].step(gtx.Now, t.motion(), .5)
+	ay := c.offset[1].step(gtx.Now, t.motion(), .5)
+	if ax || ay {
+		gtx.Execute(op.InvalidateCmd{})
+	}
+	return image.Pt(int(c.offset[0].cur+.5), int(c.offset[1].cur+.5))
+}
+
+// Animating reports whether the child identified by key is animating.
+func (t *Transition) Animating(key any) bool {
+	c, ok := t.children[key]
+	if !ok {
+		return false
+	}
+	for _, v := range c.size {
+		if v.active {
+			return true
+		}
+	}
+	for _, v := range c.offset {
+		if v.active {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Transition) motion() motion {
+	return motion{duration: t.Duration, curve: t.Curve, spring: t.Spring}
+}
+
+func (t *Transition) child(key any) *transitionChild {
+	c, ok := t.children[key]
+	if !ok {
+		c = new(transitionChild)
+		t.children[key] = c
+	}
+	c.seen = true
+	return c
+}
+
+// frame prepares the transition for a new frame if now differs from the
+// time of the previous frame, forgetting the children that weren't laid
+// out in the previous frame. It reports whether this is the first frame.
+func (t *Transition) frame(now time.Time) bool {
+	if t.children == nil {
+		t.children = make(map[any]*transitionChild)
+		t.now = now
+		t.first = true
+		return true
+	}
+	if now.Equal(t.now) {
+		return t.first
+	}
+	t.now = now
+	t.first = false
+	for k, c := range t.children {
+		if !c.seen {
+			delete(t.children, k)
+		}
+		c.seen = false
+	}
+	return false
+}
@@ -0,0 +1,352 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package animation
+
+import (
+	"image/color"
+	"math"
+	"time"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op"
+)
+
+// Value is the set of types that can be animated.
+//
+// Values are animated component by component. In particular, an
+// f32.Affine2D is interpolated element by element, which doesn't preserve
+// the lengths of rotated vectors.
+type Value interface {
+	float32 | f32.Point | color.NRGBA | f32.Affine2D
+}
+
+// vec holds the components of a Value.
+type vec [6]float32
+
+// Lerp interpolates linearly between from and to, where t = 0
+// results in from and t = 1 in to.
+func Lerp[T Value](from, to T, t float32) T {
+	a, b := toVec(from), toVec(to)
+	for i := range a {
+		a[i] += (b[i] - a[i]) * t
+	}
+	return fromVec[T](a)
+}
+
+func toVec[T Value](v T) vec {
+	switch v := any(v).(type) {
+	case float32:
+		return vec{v}
+	case f32.Point:
+		return vec{v.X, v.Y}
+	case color.NRGBA:
+		return vec{float32(v.R), float32(v.G), float32(v.B), float32(v.A)}
+	case f32.Affine2D:
+		sx, hx, ox, hy, sy, oy := v.Elems()
+		return vec{sx, hx, ox, hy, sy, oy}
+	}
+	panic("unreachable")
+}
+
+func fromVec[T Value](v vec) T {
+	var res T
+	switch r := any(&res).(type) {
+	case *float32:
+		*r = v[0]
+	case *f32.Point:
+		*r = f32.Pt(v[0], v[1])
+	case *color.NRGBA:
+		*r = color.NRGBA{R: channel(v[0]), G: channel(v[1]), B: channel(v[2]), A: channel(v[3])}
+	case *f32.Affine2D:
+		*r = f32.NewAffine2D(v[0], v[1], v[2], v[3], v[4], v[5])
+	}
+	return res
+}
+
+// channel rounds and clamps a color channel.
+func channel(c float32) uint8 {
+	c = float32(math.Round(float64(c)))
+	switch {
+	case c < 0:
+		return 0
+	case c > 255:
+		return 255
+	}
+	return uint8(c)
+}
+
+// Timed is implemented by animations with a fixed length that can be
+// scheduled to start at a particular time.
+type Timed interface {
+	// Start the animation at the specified time, which may be in the
+	// future.
+	Start(at time.Time)
+	// Stop cancels the animation.
+	Stop()
+	// Length returns the duration of the animation.
+	Length() time.Duration
+}
+
+// Tween animates a value of type T from one value to another over a
+// fixed duration.
+type Tween[T Value] struct {
+	From, To T
+	Duration time.Duration
+	// Curve of the animation. A nil Curve progresses linearly.
+	Curve Curve
+
+	start   time.Time
+	started bool
+	stopped bool
+	// cur is the most recently computed value.
+	cur T
+}
+
+// Start the tween at the specified time. Before the start time, the
+// value of the tween is From.
+func (tw *Tween[T]) Start(at time.Time) {
+	tw.start = at
+	tw.started = true
+	tw.stopped = false
+	tw.cur = tw.From
+}
+
+// Stop the tween, freezing its value.
+func (tw *Tween[T]) Stop() {
+	tw.stopped = true
+}
+
+// Length returns the duration of the tween.
+func (tw *Tween[T]) Length() time.Duration {
+	return tw.Duration
+}
+
+// Running reports whether the tween is started and not yet stopped or
+// finished as of the most recent call to Value or At.
+func (tw *Tween[T]) Running() bool {
+	return tw.started && !tw.stopped
+}
+
+// At returns the value of the tween at the specified time, and the time
+// the value next changes. A zero time means the value won't change until
+// the tween is restarted.
+//
+// A tween that is never started has the value From. A stopped tween
+// retains the value it had when stopped.
+func (tw *Tween[T]) At(now time.Time) (T, time.Time) {
+	switch {
+	case tw.stopped:
+		return tw.cur, time.Time{}
+	case !tw.started:
+		return tw.From, time.Time{}
+	case now.Before(tw.start):
+		tw.cur = tw.From
+		return tw.cur, tw.start
+	}
+	elapsed := now.Sub(tw.start)
+	if elapsed >= tw.Duration {
+		tw.cur = tw.To
+		tw.stopped = true
+		return tw.cur, time.Time{}
+	}
+	t := float32(elapsed) / float32(tw.Duration)
+	if tw.Curve != nil {
+		t = tw.Curve(t)
+	}
+	tw.cur = Lerp(tw.From, tw.To, t)
+	// Request the next frame as soon as possible.
+	return tw.cur, now
+}
+
+// Value returns the value of the tween at gtx.Now, and schedules a new
+// frame for the time the value next changes.
+func (tw *Tween[T]) Value(gtx layout.Context) T {
+	v, next := tw.At(gtx.Now)
+	invalidateAt(gtx, next)
+	return v
+}
+
+// invalidateAt schedules a frame at t, unless t is zero.
+func invalidateAt(gtx layout.Context, t time.Time) {
+	switch {
+	case t.IsZero():
+	case !t.After(gtx.Now):
+		gtx.Execute(op.InvalidateCmd{})
+	default:
+		gtx.Execute(op.InvalidateCmd{At: t})
+	}
+}
+
+// Follower animates a value of type T towards a target that may change
+// at any time, using spring physics.
+type Follower[T Value] struct {
+	// Spring describes the motion. If its Stiffness is zero,
+	// DefaultSpring is used.
+	Spring Spring
+	// Precision is the largest difference from the target for any
+	// component of the value, at which the value settles. Zero means 0.5,
+	// suitable for pixel and color values.
+	Precision float32
+
+	init    bool
+	stopped bool
+	cur     vec
+	vel     vec
+	target  vec
+	last    time.Time
+}
+
+// Set the value immediately, without animation.
+func (f *Follower[T]) Set(v T) {
+	f.init = true
+	f.stopped = false
+	f.cur = toVec(v)
+	f.target = f.cur
+	f.vel = vec{}
+}
+
+// Stop the animation, freezing the value until the target changes.
+func (f *Follower[T]) Stop() {
+	f.stopped = true
+	f.target = f.cur
+	f.vel = vec{}
+}
+
+// Running reports whether the value is moving towards its target as of
+// the most recent call to Value or At.
+func (f *Follower[T]) Running() bool {
+	return f.init && !f.stopped && f.cur != f.target
+}
+
+// At advances the value towards target, and returns the value at the
+// specified time along with the time it next changes. The first target of
+// a Follower is its initial value.
+func (f *Follower[T]) At(now time.Time, target T) (T, time.Time) {
+	tv := toVec(target)
+	if !f.init {
+		f.Set(target)
+		f.last = now
+	}
+	if tv != f.target {
+		if f.stopped || f.cur == f.target {
+			// Start moving from rest as of now. A moving value keeps
+			// stepping from the previous frame, so that a target changing
+			// every frame is still followed.
+			f.last = now
+		}
+		f.target = tv
+		f.stopped = false
+	}
+	if f.stopped || f.cur == f.target {
+		return fromVec[T](f.cur), time.Time{}
+	}
+	if now.IsZero() {
+		f.Set(target)
+		return target, time.Time{}
+	}
+	s := f.Spring
+	if s.Stiffness == 0 {
+		s = DefaultSpring
+	}
+	prec := f.Precision
+	if prec == 0 {
+		prec = .5
+	}
+	if dt := now.Sub(f.last); dt > 0 {
+		f.last = now
+		settled := true
+		for i := range f.cur {
+			f.cur[i], f.vel[i] = s.Step(f.cur[i], f.vel[i], f.target[i], dt)
+			if abs(f.cur[i]-f.target[i]) >= prec || abs(f.vel[i]) >= prec {
+				settled = false
+			}
+		}
+		if settled {
+			f.cur = f.target
+			f.vel = vec{}
+			return target, time.Time{}
+		}
+	}
+	return fromVec[T](f.cur), now
+}
+
+// Value advances the value towards target as of gtx.Now, and schedules a
+// new frame while the value is moving.
+func (f *Follower[T]) Value(gtx layout.Context, target T) T {
+	v, next := f.At(gtx.Now, target)
+	invalidateAt(gtx, next)
+	return v
+}
+
+// Sequence is a Timed animation that plays its elements one after
+// another.
+type Sequence []Timed
+
+// Start each element at the end of the previous one, starting at the
+// specified time.
+func (s Sequence) Start(at time.Time) {
+	for _, a := range s {
+		a.Start(at)
+		at = at.Add(a.Length())
+	}
+}
+
+// Stop every element of the sequence.
+func (s Sequence) Stop() {
+	for _, a := range s {
+		a.Stop()
+	}
+}
+
+// Length returns the sum of the lengths of the elements.
+func (s Sequence) Length() time.Duration {
+	var l time.Duration
+	for _, a := range s {
+		l += a.Length()
+	}
+	return l
+}
+
+// Parallel is a Timed animation that plays its elements simultaneously.
+type Parallel []Timed
+
+// Start every element at the specified time.
+func (p Parallel) Start(at time.Time) {
+	for _, a := range p {
+		a.Start(at)
+	}
+}
+
+// Stop every element.
+func (p Parallel) Stop() {
+	for _, a := range p {
+		a.Stop()
+	}
+}
+
+// Length returns the length of the longest element.
+func (p Parallel) Length() time.Duration {
+	var l time.Duration
+	for _, a := range p {
+		if al := a.Length(); al > l {
+			l = al
+		}
+	}
+	return l
+}
+
+// Delay is a Timed animation that does nothing for a duration, for
+// inserting pauses in a Sequence.
+type Delay time.Duration
+
+// Start does nothing.
+func (Delay) Start(at time.Time) {}
+
+// Stop does nothing.
+func (Delay) Stop() {}
+
+// Length returns the delay.
+func (d Delay) Length() time.Duration {
+	return time.Duration(d)
+}
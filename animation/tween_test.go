@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package animation
+
+import (
+	"image/color"
+	"testing"
+	"time"
+
+	"gioui.org/f32"
+)
+
+func TestLerp(t *testing.T) {
+	if got, want := Lerp(f32.Pt(0, 10), f32.Pt(10, 20), .5), f32.Pt(5, 15); got != want {
+		t.Errorf("point: got %v, want %v", got, want)
+	}
+	c1 := color.NRGBA{R: 0, G: 100, B: 255, A: 255}
+	c2 := color.NRGBA{R: 255, G: 200, B: 255, A: 0}
+	if got, want := Lerp(c1, c2, .5), (color.NRGBA{R: 128, G: 150, B: 255, A: 128}); got != want {
+		t.Errorf("color: got %v, want %v", got, want)
+	}
+	a := f32.Affine2D{}.Offset(f32.Pt(10, 0))
+	if got, want := Lerp(f32.Affine2D{}, a, .5), (f32.Affine2D{}).Offset(f32.Pt(5, 0)); got != want {
+		t.Errorf("affine: got %v, want %v", got, want)
+	}
+}
+
+func TestTween(t *testing.T) {
+	start := time.Unix(10, 0)
+	tw := Tween[float32]{From: 0, To: 100, Duration: time.Second}
+	if v, next := tw.At(start); v != 0 || !next.IsZero() {
+		t.Errorf("unstarted tween: got %v, %v", v, next)
+	}
+	tw.Start(start.Add(time.Second))
+	if v, next := tw.At(start); v != 0 || !next.Equal(start.Add(time.Second)) {
+		t.Errorf("delayed tween: got %v, %v", v, next)
+	}
+	if v, _ := tw.At(start.Add(1500 * time.Millisecond)); v != 50 {
+		t.Errorf("running tween: got %v, want 50", v)
+	}
+	tw.Stop()
+	if v, next := tw.At(start.Add(1800 * time.Millisecond)); v != 50 || !next.IsZero() {
+		t.Errorf("stopped tween: got %v, %v", v, next)
+	}
+	if tw.Running() {
+		t.Error("stopped tween is running")
+	}
+}
+
+func TestSequence(t *testing.T) {
+	start := time.Unix(10, 0)
+	a := &Tween[float32]{From: 0, To: 1, Duration: time.Second}
+	b := &Tween[f32.Point]{From: f32.Pt(0, 0), To: f32.Pt(10, 10), Duration: time.Second}
+	s := Sequence{a, Delay(time.Second), b}
+	if got, want := s.Length(), 3*time.Second; got != want {
+		t.Errorf("length: got %v, want %v", got, want)
+	}
+	s.Start(start)
+	now := start.Add(2500 * time.Millisecond)
+	if v, _ := a.At(now); v != 1 {
+		t.Errorf("first tween: got %v, want 1", v)
+	}
+	if v, _ := b.At(now); v != f32.Pt(5, 5) {
+		t.Errorf("second tween: got %v, want (5,5)", v)
+	}
+	p := Parallel{a, s}
+	if got, want := p.Length(), 3*time.Second; got != want {
+		t.Errorf("parallel length: got %v, want %v", got, want)
+	}
+}
+
+func TestFollower(t *testing.T) {
+	var f Follower[f32.Point]
+	now := time.Unix(10, 0)
+	if v, next := f.At(now, f32.Pt(1, 1)); v != f32.Pt(1, 1) || !next.IsZero() {
+		t.Errorf("initial value: got %v, %v", v, next)
+	}
+	target := f32.Pt(100, 50)
+	for i := 0; i < 120; i++ {
+		now = now.Add(time.Second / 60)
+		f.At(now, target)
+	}
+	if f.Running() {
+		t.Error("follower didn't settle")
+	}
+	if v, _ := f.At(now, target); v != target {
+		t.Errorf("settled value: got %v, want %v", v, target)
+	}
+}
+
+func TestFollowerMovingTarget(t *testing.T) {
+	var f Follower[float32]
+	now := time.Unix(10, 0)
+	f.At(now, 0)
+	// A target changing every frame, such as the pointer position.
+	var v float32
+	for i := 1; i <= 60; i++ {
+		now = now.Add(time.Second / 60)
+		v, _ = f.At(now, float32(i))
+	}
+	if v < 30 || v > 60 {
+		t.Errorf("got value %v following a moving target, want in [30, 60]", v)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	fontapi "github.com/go-text/typesetting/opentype/api/font"
 	"github.com/go-text/typesetting/opentype/api/metadata"
 	"github.com/go-text/typesetting/opentype/loader"
+	"github.com/go-text/typesetting/opentype/tables"
 )
 
 // Face is a thread-safe representation of a loaded font. For efficiency, applications
@@ -28,6 +29,17 @@ import (
 type Face struct {
 	face font.Font
 	font giofont.Font
+	// axes is a pointer to keep Face comparable.
+	axes *[]Axis
+}
+
+// Axis describes a variation axis of a variable font.
+type Axis struct {
+	// Tag identifies the axis, such as "wght" for the weight axis.
+	Tag string
+	// Min, Default and Max are the range and default value of the axis, in
+	// design units.
+	Min, Default, Max float32
 }
 
 // Parse constructs a Face from source bytes.
@@ -43,6 +55,7 @@ func Parse(src []byte) (Face, error) {
 	return Face{
 		face: font,
 		font: md,
+		axes: parseAxes(ld),
 	}, nil
 }
 
@@ -66,6 +79,7 @@ func ParseCollection(src []byte) ([]giofont.FontFace, error) {
 		ff := Face{
 			face: face,
 			font: md,
+			axes: parseAxes(ld),
 		}
 		out[i] = giofont.FontFace{
 			Face: ff,
@@ -76,6 +90,48 @@ func ParseCollection(src []byte) ([]giofont.FontFace, error) {
 	return out, nil
 }
 
+// ReadAxes returns the variation axes of the font at index in a font file or
+// collection, without parsing the rest of the font. It returns nil for fonts
+// that are not variable fonts.
+func ReadAxes(src font.Resource, index int) ([]Axis, error) {
+	lds, err := loader.NewLoaders(src)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(lds) {
+		return nil, fmt.Errorf("font index %d out of range", index)
+	}
+	if axes := parseAxes(lds[index]); axes != nil {
+		return *axes, nil
+	}
+	return nil, nil
+}
+
+// parseAxes returns the axes of the variation table of the loader, if any.
+func parseAxes(ld *loader.Loader) *[]Axis {
+	raw, err := ld.RawTable(loader.MustNewTag("fvar"))
+	if err != nil {
+		return nil
+	}
+	fvar, _, err := tables.ParseFvar(raw)
+	if err != nil {
+		return nil
+	}
+	if len(fvar.FvarRecords.Axis) == 0 {
+		return nil
+	}
+	var axes []Axis
+	for _, a := range fvar.FvarRecords.Axis {
+		axes = append(axes, Axis{
+			Tag:     a.Tag.String(),
+			Min:     a.Minimum,
+			Default: a.Default,
+			Max:     a.Maximum,
+		})
+	}
+	return &axes
+}
+
 func DescriptionToFont(md metadata.Description) giofont.Font {
 	return giofont.Font{
 		Typeface: giofont.Typeface(md.Family),
@@ -111,6 +167,15 @@ func (f Face) Face() font.Face {
 	return &fontapi.Face{Font: f.face}
 }
 
+// Axes returns the variation axes of the font. It returns nil if the font is
+// not a variable font.
+func (f Face) Axes() []Axis {
+	if f.axes == nil {
+		return nil
+	}
+	return *f.axes
+}
+
 // FontFace returns a text.Font with populated font metadata for the
 // font.
 // BUG(whereswaldon): the only Variant that can be detected automatically is
@@ -11,14 +11,24 @@ import (
 )
 
 const (
-	debugVariable = "GIODEBUG"
-	textSubsystem = "text"
-	silentFeature = "silent"
+	debugVariable       = "GIODEBUG"
+	textSubsystem       = "text"
+	layoutSubsystem     = "layout"
+	layoutTreeSubsystem = "layouttree"
+	silentFeature       = "silent"
 )
 
 // Text controls whether the text subsystem has debug logging enabled.
 var Text atomic.Bool
 
+// Layout controls whether layouts record their constraints and dimensions
+// and draw their bounds on top of the frame.
+var Layout atomic.Bool
+
+// LayoutTree controls whether the layout tree of a frame is logged
+// whenever it changes. It implies Layout.
+var LayoutTree atomic.Bool
+
 var parseOnce sync.Once
 
 // Parse processes the current value of GIODEBUG. If it is unset, it does nothing.
@@ -38,6 +48,11 @@ func Parse() {
 			switch part {
 			case textSubsystem:
 				Text.Store(true)
+			case layoutSubsystem:
+				Layout.Store(true)
+			case layoutTreeSubsystem:
+				Layout.Store(true)
+				LayoutTree.Store(true)
 			case silentFeature:
 				silent = true
 			default:
@@ -50,8 +65,10 @@ func Parse() {
 	A comma-delimited list of debug subsystems to enable. Currently recognized systems:
 
 	- %s: text debug info including system font resolution
+	- %s: draw the bounds and baselines of layouts
+	- %s: like %s, and log the layout tree whenever it changes
 	- %s: silence this usage message even if GIODEBUG contains invalid content
-`, debugVariable, textSubsystem, silentFeature)
+`, debugVariable, textSubsystem, layoutSubsystem, layoutTreeSubsystem, layoutSubsystem, silentFeature)
 		}
 	})
 }
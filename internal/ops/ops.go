@@ -186,6 +186,11 @@ func Reset(o *Ops) {
 	o.version++
 }
 
+// Version returns the number of times o has been reset.
+func Version(o *Ops) uint32 {
+	return o.version
+}
+
 func Write(o *Ops, n int) []byte {
 	if o.multipOp {
 		panic("cannot mix multi ops with single ones")
@@ -22,6 +22,9 @@ const (
 	LTR TextDirection = TextDirection(Horizontal<<axisShift) | TextDirection(FromOrigin<<progressionShift)
 	// RTL is right-to-left text.
 	RTL TextDirection = TextDirection(Horizontal<<axisShift) | TextDirection(TowardOrigin<<progressionShift)
+	// TTB is top-to-bottom text, with lines progressing from right to left
+	// as is usual for vertical Chinese, Japanese and Korean text.
+	TTB TextDirection = TextDirection(Vertical<<axisShift) | TextDirection(FromOrigin<<progressionShift)
 )
 
 // Axis returns the axis of the text layout.
@@ -38,6 +41,8 @@ func (d TextDirection) String() string {
 	switch d {
 	case RTL:
 		return "RTL"
+	case TTB:
+		return "TTB"
 	default:
 		return "LTR"
 	}
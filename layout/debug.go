@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package layout
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"strings"
+	"sync"
+
+	"gioui.org/internal/debug"
+	"gioui.org/internal/ops"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+)
+
+// debugFrames tracks the layout trees of the frames being laid out, when
+// layout debugging is enabled through GIODEBUG.
+var debugFrames struct {
+	mu     sync.Mutex
+	frames map[*op.Ops]*debugFrame
+}
+
+// maxDebugFrames is the maximum number of Ops tracked by debugFrames.
+const maxDebugFrames = 32
+
+// debugFrame is the layout tree of a single frame.
+type debugFrame struct {
+	// version is the ops version of the frame.
+	version uint32
+	// nodes contains the layout tree in pre-order.
+	nodes []debugNode
+	// prev is the tree of the previous frame.
+	prev  []debugNode
+	depth int
+}
+
+type debugNode struct {
+	name  string
+	depth int
+	cs    Constraints
+	dims  Dimensions
+}
+
+// debugLayout represents a layout being recorded.
+type debugLayout struct {
+	frame *debugFrame
+	index int
+}
+
+// debugColors are the colors of the bounds of layouts, indexed by depth.
+var debugColors = [...]color.NRGBA{
+	{R: 0xe0, G: 0x20, B: 0x20, A: 0xc0},
+	{R: 0x20, G: 0xa0, B: 0x20, A: 0xc0},
+	{R: 0x20, G: 0x40, B: 0xe0, A: 0xc0},
+	{R: 0xd0, G: 0x90, B: 0x00, A: 0xc0},
+	{R: 0xa0, G: 0x20, B: 0xc0, A: 0xc0},
+}
+
+// debugBaselineColor is the color of baselines.
+var debugBaselineColor = color.NRGBA{R: 0x00, G: 0xb0, B: 0xc0, A: 0xc0}
+
+// beginDebug records the start of a layout named name. It must be followed
+// by a call to end.
+func beginDebug(gtx Context, name string) debugLayout {
+	debugFrames.mu.Lock()
+	defer debugFrames.mu.Unlock()
+	if debugFrames.frames == nil || len(debugFrames.frames) > maxDebugFrames {
+		// Forget frames from discarded Ops.
+		debugFrames.frames = make(map[*op.Ops]*debugFrame)
+	}
+	f := debugFrames.frames[gtx.Ops]
+	if f == nil {
+		f = new(debugFrame)
+		debugFrames.frames[gtx.Ops] = f
+	}
+	if v := ops.Version(&gtx.Ops.Internal); v != f.version {
+		// The ops were reset, so the previous frame is complete.
+		f.finish()
+		f.version = v
+	}
+	f.nodes = append(f.nodes, debugNode{name: name, depth: f.depth, cs: gtx.Constraints})
+	f.depth++
+	return debugLayout{frame: f, index: len(f.nodes) - 1}
+}
+
+// end the layout with its resulting dimensions, and draw its bounds on top
+// of the frame.
+func (d debugLayout) end(gtx Context, dims Dimensions) Dimensions {
+	debugFrames.mu.Lock()
+	n := &d.frame.nodes[d.index]
+	n.dims = dims
+	d.frame.depth = n.depth
+	depth := n.depth
+	debugFrames.mu.Unlock()
+
+	m := op.Record(gtx.Ops)
+	sz := dims.Size
+	col := debugColors[depth%len(debugColors)]
+	paint.FillShape(gtx.Ops, col, clip.Stroke{
+		Path:  clip.Rect{Max: sz}.Path(),
+		Width: 1,
+	}.Op())
+	if b := dims.Baseline; b != 0 {
+		y := sz.Y - b
+		paint.FillShape(gtx.Ops, debugBaselineColor, clip.Rect{Min: image.Pt(0, y), Max: image.Pt(sz.X, y+1)}.Op())
+	}
+	op.Defer(gtx.Ops, m.Stop())
+	return dims
+}
+
+// finish the frame, logging its tree if it differs from the tree of the
+// previous frame.
+func (f *debugFrame) finish() {
+	f.depth = 0
+	if len(f.nodes) == 0 {
+		return
+	}
+	if debug.LayoutTree.Load() && !equalNodes(f.nodes, f.prev) {
+		log.Printf("[layout] frame tree:\n%s", formatTree(f.nodes))
+	}
+	f.prev, f.nodes = f.nodes, f.prev[:0]
+}
+
+func equalNodes(a, b []debugNode) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// formatTree formats a tree of nodes, one per line, indented by depth.
+func formatTree(nodes []debugNode) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		fmt.Fprintf(&b, "%s%s min=%v max=%v size=%v", strings.Repeat("  ", n.depth), n.name, n.cs.Min, n.cs.Max, n.dims.Size)
+		if n.dims.Baseline != 0 {
+			fmt.Fprintf(&b, " baseline=%d", n.dims.Baseline)
+		}
+		if n.dims.Size.X > n.cs.Max.X || n.dims.Size.Y > n.cs.Max.Y ||
+			n.dims.Size.X < n.cs.Min.X || n.dims.Size.Y < n.cs.Min.Y {
+			b.WriteString(" (violates constraints)")
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package layout
+
+import (
+	"bytes"
+	"image"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"gioui.org/internal/debug"
+	"gioui.org/op"
+)
+
+func TestDebugLayoutTree(t *testing.T) {
+	debug.Layout.Store(true)
+	debug.LayoutTree.Store(true)
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() {
+		debug.Layout.Store(false)
+		debug.LayoutTree.Store(false)
+		log.SetOutput(os.Stderr)
+	}()
+
+	gtx := Context{
+		Ops:         new(op.Ops),
+		Constraints: Exact(image.Pt(100, 50)),
+	}
+	frame := func() {
+		gtx.Ops.Reset()
+		Flex{}.Layout(gtx,
+			Rigid(func(gtx Context) Dimensions {
+				return UniformInset(5).Layout(gtx, func(gtx Context) Dimensions {
+					return Dimensions{Size: image.Pt(10, 10), Baseline: 2}
+				})
+			}),
+		)
+	}
+	frame()
+	frame()
+	got := buf.String()
+	want := "Flex min=(100,50) max=(100,50) size=(100,50) baseline=37\n" +
+		"  Inset min=(0,50) max=(100,50) size=(20,20) baseline=7 (violates constraints)\n"
+	if !strings.Contains(got, want) {
+		t.Errorf("logged tree:\n%s\nwant:\n%s", got, want)
+	}
+	// An unchanged tree is logged once.
+	buf.Reset()
+	frame()
+	if buf.Len() > 0 {
+		t.Errorf("unchanged tree logged:\n%s", buf.String())
+	}
+}
@@ -5,6 +5,7 @@ package layout
 import (
 	"image"
 
+	"gioui.org/internal/debug"
 	"gioui.org/op"
 )
 
@@ -81,6 +82,14 @@ func Flexed(weight float32, widget Widget) FlexChild {
 // determined by the specified order, but Rigid children are laid out
 // before Flexed children.
 func (f Flex) Layout(gtx Context, children ...FlexChild) Dimensions {
+	if debug.Layout.Load() {
+		dbg := beginDebug(gtx, "Flex")
+		return dbg.end(gtx, f.layout(gtx, children...))
+	}
+	return f.layout(gtx, children...)
+}
+
+func (f Flex) layout(gtx Context, children ...FlexChild) Dimensions {
 	size := 0
 	cs := gtx.Constraints
 	mainMin, mainMax := f.Axis.mainConstraint(cs)
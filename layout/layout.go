@@ -6,6 +6,7 @@ import (
 	"image"
 
 	"gioui.org/f32"
+	"gioui.org/internal/debug"
 	"gioui.org/op"
 	"gioui.org/unit"
 )
@@ -141,6 +142,14 @@ type Inset struct {
 
 // Layout a widget.
 func (in Inset) Layout(gtx Context, w Widget) Dimensions {
+	if debug.Layout.Load() {
+		dbg := beginDebug(gtx, "Inset")
+		return dbg.end(gtx, in.layout(gtx, w))
+	}
+	return in.layout(gtx, w)
+}
+
+func (in Inset) layout(gtx Context, w Widget) Dimensions {
 	top := gtx.Dp(in.Top)
 	right := gtx.Dp(in.Right)
 	bottom := gtx.Dp(in.Bottom)
@@ -183,6 +192,14 @@ func UniformInset(v unit.Dp) Inset {
 // Layout a widget according to the direction.
 // The widget is called with the context constraints minimum cleared.
 func (d Direction) Layout(gtx Context, w Widget) Dimensions {
+	if debug.Layout.Load() {
+		dbg := beginDebug(gtx, "Direction")
+		return dbg.end(gtx, d.layout(gtx, w))
+	}
+	return d.layout(gtx, w)
+}
+
+func (d Direction) layout(gtx Context, w Widget) Dimensions {
 	macro := op.Record(gtx.Ops)
 	csn := gtx.Constraints.Min
 	switch d {
@@ -240,6 +257,14 @@ type Spacer struct {
 }
 
 func (s Spacer) Layout(gtx Context) Dimensions {
+	if debug.Layout.Load() {
+		dbg := beginDebug(gtx, "Spacer")
+		return dbg.end(gtx, s.layout(gtx))
+	}
+	return s.layout(gtx)
+}
+
+func (s Spacer) layout(gtx Context) Dimensions {
 	return Dimensions{
 		Size: gtx.Constraints.Constrain(image.Point{
 			X: gtx.Dp(s.Width),
@@ -7,6 +7,7 @@ import (
 	"math"
 
 	"gioui.org/gesture"
+	"gioui.org/internal/debug"
 	"gioui.org/io/pointer"
 	"gioui.org/op"
 	"gioui.org/op/clip"
@@ -15,6 +16,16 @@ import (
 type scrollChild struct {
 	size image.Point
 	call op.CallOp
+	key  any
+}
+
+// keyedChild records the identity and position of a laid out child.
+type keyedChild struct {
+	key   any
+	index int
+	// offset is the distance in pixels from the leading edge of the list
+	// to the leading edge of the child.
+	offset int
 }
 
 // List displays a subsection of a potentially infinitely
@@ -29,6 +40,12 @@ type List struct {
 	ScrollToEnd bool
 	// Alignment is the cross axis alignment of list elements.
 	Alignment Alignment
+	// Key, if non-nil, maps an element index to a comparable value that
+	// identifies the element across frames. When the underlying data
+	// changes such that the visible elements move to other indices, the
+	// List adjusts Position to keep them in place. Key may be called for
+	// every index when the first visible element moved.
+	Key func(index int) any
 
 	cs          Constraints
 	scroll      gesture.Scroll
@@ -46,6 +63,18 @@ type List struct {
 	maxSize  int
 	children []scrollChild
 	dir      iterationDir
+
+	// keyed tracks the keyed children of the most recent layout, the
+	// visible children first.
+	keyed []keyedChild
+	// anchorFirst and anchorOffset are the values of Position.First and
+	// Position.Offset when keyed was recorded. Different values mean
+	// Position was changed by the program and the keyed children must not
+	// override it.
+	anchorFirst, anchorOffset int
+	// keys maps the keys of all elements to their indices, built when a
+	// keyed child moved.
+	keys map[any]int
 }
 
 // ListElement is a function that computes the dimensions of
@@ -96,6 +125,7 @@ func (l *List) init(gtx Context, len int) {
 	l.maxSize = 0
 	l.children = l.children[:0]
 	l.len = len
+	l.reanchor()
 	l.update(gtx)
 	if l.Position.First < 0 {
 		l.Position.Offset = 0
@@ -111,6 +141,10 @@ func (l *List) init(gtx Context, len int) {
 // by the callback w. Layout can handle very large lists because it only calls
 // w to fill its viewport and the distance scrolled, if any.
 func (l *List) Layout(gtx Context, len int, w ListElement) Dimensions {
+	var dbg debugLayout
+	if debug.Layout.Load() {
+		dbg = beginDebug(gtx, "List")
+	}
 	l.init(gtx, len)
 	crossMin, crossMax := l.Axis.crossConstraint(gtx.Constraints)
 	gtx.Constraints = l.Axis.constraints(0, inf, crossMin, crossMax)
@@ -132,7 +166,63 @@ func (l *List) Layout(gtx Context, len int, w ListElement) Dimensions {
 	} else {
 		l.Position.Length = 0
 	}
-	return l.layout(gtx.Ops, macro)
+	dims := l.layout(gtx.Ops, macro)
+	if dbg.frame != nil {
+		dbg.end(gtx, dims)
+	}
+	return dims
+}
+
+// reanchor adjusts Position to the new indices of the keyed children
+// from the previous layout.
+func (l *List) reanchor() {
+	if l.Key == nil || len(l.keyed) == 0 || l.scrollToEnd() {
+		return
+	}
+	if l.Position.First != l.anchorFirst || l.Position.Offset != l.anchorOffset {
+		return
+	}
+	// Fast path: the anchor child didn't move.
+	if c := l.keyed[0]; c.index < l.len && l.Key(c.index) == c.key {
+		return
+	}
+	l.indexKeys()
+	for _, c := range l.keyed {
+		if idx, ok := l.keys[c.key]; ok {
+			l.Position.First = idx
+			l.Position.Offset = -c.offset
+			return
+		}
+	}
+}
+
+// indexKeys maps the keys of all elements to their indices.
+func (l *List) indexKeys() {
+	if l.keys == nil {
+		l.keys = make(map[any]int, l.len)
+	}
+	for k := range l.keys {
+		delete(l.keys, k)
+	}
+	for i := 0; i < l.len; i++ {
+		l.keys[l.Key(i)] = i
+	}
+}
+
+// ElementOffset returns the distance in pixels from the leading edge of the
+// list to the leading edge of the element identified by key, as of the most
+// recent call to Layout. It returns false if Key is nil or if the element
+// was not laid out.
+//
+// Elements may compare their offset before and after Layout to animate
+// their movement, insertion or removal.
+func (l *List) ElementOffset(key any) (int, bool) {
+	for _, c := range l.keyed {
+		if c.key == key {
+			return c.offset, true
+		}
+	}
+	return 0, false
 }
 
 func (l *List) scrollToEnd() bool {
@@ -234,7 +324,10 @@ func (l *List) nextDir() iterationDir {
 
 // End the current child by specifying its dimensions.
 func (l *List) end(dims Dimensions, call op.CallOp) {
-	child := scrollChild{dims.Size, call}
+	child := scrollChild{size: dims.Size, call: call}
+	if l.Key != nil {
+		child.key = l.Key(l.index())
+	}
 	mainSize := l.Axis.Convert(child.size).X
 	l.maxSize += mainSize
 	switch l.dir {
@@ -298,7 +391,11 @@ func (l *List) layout(ops *op.Ops, macro op.MacroOp) Dimensions {
 		l.Position.Offset -= space
 	}
 	pos := -l.Position.Offset
-	layout := func(child scrollChild) {
+	l.keyed = l.keyed[:0]
+	layout := func(child scrollChild, index int) {
+		if l.Key != nil {
+			l.keyed = append(l.keyed, keyedChild{key: child.key, index: index, offset: pos})
+		}
 		sz := l.Axis.Convert(child.size)
 		var cross int
 		switch l.Alignment {
@@ -322,15 +419,22 @@ func (l *List) layout(ops *op.Ops, macro op.MacroOp) Dimensions {
 	if first != (scrollChild{}) {
 		sz := l.Axis.Convert(first.size)
 		pos -= sz.X
-		layout(first)
+		layout(first, l.Position.First-1)
 	}
-	for _, child := range children {
-		layout(child)
+	for i, child := range children {
+		layout(child, l.Position.First+i)
 	}
 	// Lay out trailing invisible child.
 	if last != (scrollChild{}) {
-		layout(last)
+		layout(last, l.Position.First+len(children))
+	}
+	// Prefer visible children for anchoring.
+	if first != (scrollChild{}) && len(l.keyed) > 0 {
+		lead := l.keyed[0]
+		copy(l.keyed, l.keyed[1:])
+		l.keyed[len(l.keyed)-1] = lead
 	}
+	l.anchorFirst, l.anchorOffset = l.Position.First, l.Position.Offset
 	atStart := l.Position.First == 0 && l.Position.Offset <= 0
 	atEnd := l.Position.First+len(children) == l.len && mainMax >= pos
 	if atStart && l.scrollDelta < 0 || atEnd && l.scrollDelta > 0 {
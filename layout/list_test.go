@@ -195,3 +195,84 @@ func TestExtraChildren(t *testing.T) {
 		t.Errorf("laid out %d of %d children", count, all)
 	}
 }
+
+func TestListKeyedReorder(t *testing.T) {
+	gtx := Context{
+		Ops:         new(op.Ops),
+		Constraints: Exact(image.Pt(10, 20)),
+	}
+	items := []string{"a", "b", "c", "d", "e", "f"}
+	l := List{
+		Axis: Vertical,
+		Key:  func(i int) any { return items[i] },
+	}
+	el := func(gtx Context, idx int) Dimensions {
+		return Dimensions{Size: image.Pt(10, 10)}
+	}
+	l.Position.First = 2
+	l.Position.Offset = 3
+	l.Layout(gtx, len(items), el)
+	if off, ok := l.ElementOffset("c"); !ok || off != -3 {
+		t.Errorf("ElementOffset(c) = %d, %v; want -3, true", off, ok)
+	}
+
+	// Insert elements before the first visible element.
+	items = append([]string{"x", "y"}, items...)
+	l.Layout(gtx, len(items), el)
+	if got, want := l.Position.First, 4; got != want {
+		t.Errorf("after insert: first is %d, want %d", got, want)
+	}
+	if got, want := l.Position.Offset, 3; got != want {
+		t.Errorf("after insert: offset is %d, want %d", got, want)
+	}
+
+	// Remove the first visible element. The next element stays in place.
+	items = append(items[:4:4], items[5:]...)
+	l.Layout(gtx, len(items), el)
+	if off, ok := l.ElementOffset("d"); !ok || off != 7 {
+		t.Errorf("after removal: ElementOffset(d) = %d, %v; want 7, true", off, ok)
+	}
+	if _, ok := l.ElementOffset("c"); ok {
+		t.Error("after removal: removed element still laid out")
+	}
+
+	// Programmatic scrolling takes precedence over keys.
+	l.ScrollTo(0)
+	l.Layout(gtx, len(items), el)
+	if got, want := l.Position.First, 0; got != want {
+		t.Errorf("after ScrollTo: first is %d, want %d", got, want)
+	}
+}
+
+func TestListKeyedScrollBy(t *testing.T) {
+	gtx := Context{
+		Ops:         new(op.Ops),
+		Constraints: Exact(image.Pt(10, 20)),
+	}
+	items := []string{"a", "b", "c", "d", "e", "f"}
+	l := List{
+		Axis: Vertical,
+		Key:  func(i int) any { return items[i] },
+	}
+	el := func(gtx Context, idx int) Dimensions {
+		return Dimensions{Size: image.Pt(10, 10)}
+	}
+	l.Layout(gtx, len(items), el)
+	assertPos := func(name string, first, offset int) {
+		t.Helper()
+		if p := l.Position; p.First != first || p.Offset != offset {
+			t.Errorf("after %s: position is %d+%d, want %d+%d", name, p.First, p.Offset, first, offset)
+		}
+	}
+
+	// Changes to Offset alone take precedence over keys.
+	l.Position.Offset = 5
+	l.Layout(gtx, len(items), el)
+	assertPos("setting Offset", 0, 5)
+	l.ScrollBy(0.5)
+	l.Layout(gtx, len(items), el)
+	assertPos("ScrollBy(0.5)", 1, 0)
+	l.ScrollBy(0.3)
+	l.Layout(gtx, len(items), el)
+	assertPos("ScrollBy(0.3)", 1, 3)
+}
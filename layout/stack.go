@@ -5,6 +5,7 @@ package layout
 import (
 	"image"
 
+	"gioui.org/internal/debug"
 	"gioui.org/op"
 )
 
@@ -48,6 +49,14 @@ func Expanded(w Widget) StackChild {
 // determined by the specified order, but Stacked children are laid out
 // before Expanded children.
 func (s Stack) Layout(gtx Context, children ...StackChild) Dimensions {
+	if debug.Layout.Load() {
+		dbg := beginDebug(gtx, "Stack")
+		return dbg.end(gtx, s.layout(gtx, children...))
+	}
+	return s.layout(gtx, children...)
+}
+
+func (s Stack) layout(gtx Context, children ...StackChild) Dimensions {
 	var maxSZ image.Point
 	// First lay out Stacked children.
 	cgtx := gtx
@@ -124,7 +133,15 @@ func (s Stack) Layout(gtx Context, children ...StackChild) Dimensions {
 type Background struct{}
 
 // Layout a widget and then add a background to it.
-func (Background) Layout(gtx Context, background, widget Widget) Dimensions {
+func (b Background) Layout(gtx Context, background, widget Widget) Dimensions {
+	if debug.Layout.Load() {
+		dbg := beginDebug(gtx, "Background")
+		return dbg.end(gtx, b.layout(gtx, background, widget))
+	}
+	return b.layout(gtx, background, widget)
+}
+
+func (Background) layout(gtx Context, background, widget Widget) Dimensions {
 	macro := op.Record(gtx.Ops)
 	wdims := widget(gtx)
 	baseline := wdims.Baseline
@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package text
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/go-text/typesetting/fontscan"
+	"github.com/go-text/typesetting/language"
+	"github.com/go-text/typesetting/shaping"
+
+	giofont "gioui.org/font"
+)
+
+// Fallback lists the typefaces preferred for the runes of a script in text
+// of a language, when the typeface of the text has no glyphs for them.
+type Fallback struct {
+	// Language is a BCP 47 tag such as "ja" or "zh-Hans". The fallback
+	// applies to text whose locale language has the tag as a prefix, or to
+	// text of any language if empty.
+	Language string
+	// Script is an ISO 15924 code such as "Hani" or "Arab". The fallback
+	// applies to the runes of the script, or to runes of any script if
+	// empty.
+	Script string
+	// Typeface is a list of font families in the format of
+	// [giofont.Font.Typeface].
+	Typeface giofont.Typeface
+}
+
+// fallback is a Fallback in the form used for resolving faces.
+type fallback struct {
+	language string
+	// anyScript is set if the fallback applies to runes of any script.
+	anyScript bool
+	script    language.Script
+	families  []string
+}
+
+// setFallbacks parses and configures the fallbacks of the shaper. Invalid
+// fallbacks are logged and ignored.
+func (s *shaperImpl) setFallbacks(fallbacks []Fallback) {
+	for _, f := range fallbacks {
+		fb := fallback{
+			language:  normalizeLanguage(f.Language),
+			anyScript: f.Script == "",
+		}
+		if !fb.anyScript {
+			script, err := language.ParseScript(strings.ToLower(f.Script))
+			if err != nil {
+				s.logger.Printf("Invalid fallback script %q: %v", f.Script, err)
+				continue
+			}
+			fb.script = script
+		}
+		families, err := s.parser.parse(string(f.Typeface))
+		if err != nil {
+			s.logger.Printf("Unable to parse fallback typeface %q: %v", f.Typeface, err)
+			continue
+		}
+		// The parser reuses the memory of its results.
+		fb.families = append([]string(nil), families...)
+		s.fallbacks = append(s.fallbacks, fb)
+	}
+}
+
+// setLanguage selects the fallbacks applying to text of lang.
+func (s *shaperImpl) setLanguage(lang string) {
+	lang = normalizeLanguage(lang)
+	if lang == s.language {
+		return
+	}
+	s.language = lang
+	s.langFallbacks = s.langFallbacks[:0]
+	for _, fb := range s.fallbacks {
+		if fb.language == "" || lang == fb.language || strings.HasPrefix(lang, fb.language+"-") {
+			s.langFallbacks = append(s.langFallbacks, fb)
+		}
+	}
+	clear(s.scriptQueries)
+}
+
+// queryFor returns the font map query for resolving the face of r, which
+// tries the families of the fallbacks for its script after the families of
+// the query set by setQuery.
+func (s *shaperImpl) queryFor(r rune) fontscan.Query {
+	if len(s.langFallbacks) == 0 {
+		return s.query
+	}
+	script := language.LookupScript(r)
+	if q, ok := s.scriptQueries[script]; ok {
+		return q
+	}
+	q := s.query
+	q.Families = append([]string(nil), q.Families...)
+	for _, fb := range s.langFallbacks {
+		if fb.anyScript || fb.script == script {
+			q.Families = append(q.Families, fb.families...)
+		}
+	}
+	if s.scriptQueries == nil {
+		s.scriptQueries = make(map[language.Script]fontscan.Query)
+	}
+	s.scriptQueries[script] = q
+	return q
+}
+
+// recordMissing records the runes of out that are displayed with the
+// missing glyph of its face.
+func (s *shaperImpl) recordMissing(out shaping.Output, txt []rune) {
+	cluster := -1
+	for _, g := range out.Glyphs {
+		if g.GlyphID != 0 || g.ClusterIndex == cluster {
+			continue
+		}
+		cluster = g.ClusterIndex
+		s.recordMissingRunes(txt[g.ClusterIndex : g.ClusterIndex+g.RuneCount])
+	}
+}
+
+// recordMissingRunes records runes without glyphs, ignoring the white space
+// and control characters that have no visible glyphs.
+func (s *shaperImpl) recordMissingRunes(runes []rune) {
+	for _, r := range runes {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || r == softHyphen {
+			continue
+		}
+		s.missing = append(s.missing, r)
+	}
+}
+
+// reportMissing logs the runes recorded by recordMissing since the last
+// report, and passes them to the function configured by WithMissingGlyphs.
+func (s *shaperImpl) reportMissing() {
+	if len(s.missing) == 0 {
+		return
+	}
+	missing := s.missing
+	s.missing = s.missing[:0]
+	s.logger.Printf("No glyphs for runes %q", string(missing))
+	if s.missingGlyphs != nil {
+		s.missingGlyphs(missing)
+	}
+}
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package text
+
+import (
+	"testing"
+
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+
+	giofont "gioui.org/font"
+	"gioui.org/font/opentype"
+	"gioui.org/io/system"
+)
+
+func TestFallbacks(t *testing.T) {
+	regular, _ := opentype.Parse(goregular.TTF)
+	mono, _ := opentype.Parse(gomono.TTF)
+	collection := []FontFace{
+		{Font: giofont.Font{Typeface: "Go"}, Face: regular},
+		{Font: giofont.Font{Typeface: "Go Mono"}, Face: mono},
+	}
+	fallbacks := []Fallback{
+		{Language: "ja", Typeface: "Go Mono"},
+		{Language: "en", Script: "Grek", Typeface: "Go Mono"},
+	}
+	for _, tc := range []struct {
+		name     string
+		language string
+		txt      string
+		want     giofont.Typeface
+	}{
+		{name: "language", language: "ja-JP", txt: "a", want: "Go Mono"},
+		{name: "other language", language: "en", txt: "a", want: "Go"},
+		{name: "script", language: "en-US", txt: "α", want: "Go Mono"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			shaper := NewShaper(NoSystemFonts(), WithCollection(collection), WithFallbacks(fallbacks...))
+			shaper.LayoutString(Parameters{
+				Font:     giofont.Font{Typeface: "Unknown"},
+				PxPerEm:  fixed.I(16),
+				MaxWidth: 1000,
+				Locale:   system.Locale{Language: tc.language, Direction: system.LTR},
+			}, tc.txt)
+			g, ok := shaper.NextGlyph()
+			if !ok {
+				t.Fatal("no glyphs")
+			}
+			f, ok := shaper.GlyphFont(g.ID)
+			if !ok {
+				t.Fatalf("no font for glyph %v", g.ID)
+			}
+			if f.Typeface != tc.want {
+				t.Errorf("got typeface %q, want %q", f.Typeface, tc.want)
+			}
+		})
+	}
+}
+
+func TestMissingGlyphs(t *testing.T) {
+	regular, _ := opentype.Parse(goregular.TTF)
+	var missing []rune
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: regular}}), WithMissingGlyphs(func(m []rune) {
+		missing = append(missing, m...)
+	}))
+	params := Parameters{
+		PxPerEm:  fixed.I(16),
+		MaxWidth: 1000,
+		Locale:   english,
+	}
+	shaper.LayoutString(params, "a 漢\tb字")
+	if got, want := string(missing), "漢字"; got != want {
+		t.Errorf("got missing runes %q, want %q", got, want)
+	}
+	missing = nil
+	shaper.LayoutString(params, "ab")
+	if len(missing) > 0 {
+		t.Errorf("got missing runes %q for covered text", string(missing))
+	}
+}
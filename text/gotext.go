@@ -4,18 +4,27 @@ package text
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"image"
 	"io"
 	"log"
+	"math"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
 
 	"github.com/go-text/typesetting/di"
 	"github.com/go-text/typesetting/font"
 	"github.com/go-text/typesetting/fontscan"
 	"github.com/go-text/typesetting/language"
 	"github.com/go-text/typesetting/opentype/api"
+	fontapi "github.com/go-text/typesetting/opentype/api/font"
 	"github.com/go-text/typesetting/opentype/api/metadata"
+	"github.com/go-text/typesetting/opentype/loader"
 	"github.com/go-text/typesetting/shaping"
 	"golang.org/x/exp/slices"
 	"golang.org/x/image/math/fixed"
@@ -92,6 +101,14 @@ type line struct {
 	direction system.TextDirection
 	// runeCount is the number of text runes represented by this line's runs.
 	runeCount int
+	// final marks the last line of a paragraph, which is not justified.
+	final bool
+	// hang is the width of the whitespace at the logical end of the line,
+	// which hangs past the edge of justified lines.
+	hang fixed.Int26_6
+	// words and chars are the numbers of glyphs stretched to justify the
+	// line between words or between characters.
+	words, chars int
 
 	yOffset int
 }
@@ -177,8 +194,16 @@ type glyph struct {
 	// bounds describes the visual bounding box of the glyph relative to
 	// its dot.
 	bounds fixed.Rectangle26_6
+	// justify is the set of justification modes that stretch the glyph.
+	justify uint8
 }
 
+// Justification modes of glyphs.
+const (
+	justifyWord uint8 = 1 << iota
+	justifyChar
+)
+
 type runLayout struct {
 	// VisualPosition describes the relative position of this run of text within
 	// its line. It should be a valid index into the containing line's VisualOrder
@@ -205,17 +230,68 @@ type runLayout struct {
 	// truncator indicates that this run is a text truncator standing in for remaining
 	// text.
 	truncator bool
+	// upright indicates that this run was shaped top-to-bottom, and that its
+	// glyphs are rotated such that they are upright in vertical text.
+	upright bool
+	// span is the index of the span containing the run.
+	span int
+	// ascent and descent are the line bounds of the face of the run.
+	ascent, descent fixed.Int26_6
+	// words and chars are the numbers of glyphs of the run stretched to
+	// justify its line, and wordsBefore and charsBefore the numbers in the
+	// runs visually before it.
+	words, chars             int
+	wordsBefore, charsBefore int
 }
 
-// shaperImpl implements the shaping and line-wrapping of opentype fonts.
-type shaperImpl struct {
-	// Fields for tracking fonts/faces.
-	fontMap      *fontscan.FontMap
-	faces        []font.Face
-	faceToIndex  map[font.Font]int
-	faceMeta     []giofont.Font
+// paragraphSpan is a Span clipped to a paragraph of text.
+type paragraphSpan struct {
+	Span
+	// index is the index of the span in the spans of the text.
+	index int
+}
+
+// faceRegistry tracks the fonts and faces of a Shaper, and assigns the face
+// indices of glyph ids. It is shared by the shaperImpls laying out text
+// concurrently, and its fields are guarded by mu.
+type faceRegistry struct {
+	mu sync.Mutex
+	// fontMap resolves the faces of runes, and mapQuery is the query last
+	// set on it.
+	fontMap     *fontscan.FontMap
+	mapQuery    fontscan.Query
+	faces       []font.Face
+	faceToIndex map[font.Font]int
+	faceMeta    []giofont.Font
+	// defaultFaces are the typefaces of the collection. It is not modified
+	// after the registry is created.
 	defaultFaces []string
-	logger       interface {
+	// axes caches the variation axes of fonts.
+	axes map[font.Font][]opentype.Axis
+	// instances maps fonts and variation coordinates to the faces
+	// registered for them. At most maxInstances are kept.
+	instances map[instanceKey]*fontInstance
+	// clock orders the uses of instances.
+	clock uint64
+	// evictions counts the evicted instances, whose glyph ids are no longer
+	// drawn.
+	evictions uint64
+	// retired are the face indices of the evicted instances, in the order
+	// they were evicted. They are not reused unless every face index is
+	// taken, so that glyph ids of evicted instances don't refer to other
+	// faces.
+	retired []int
+	logger  interface {
+		Printf(format string, args ...any)
+	}
+}
+
+// shaperImpl implements the shaping and line-wrapping of opentype fonts. A
+// shaperImpl is used by one layout at a time, and shares its faces with the
+// other shaperImpls of a Shaper.
+type shaperImpl struct {
+	faces  *faceRegistry
+	logger interface {
 		Printf(format string, args ...any)
 	}
 	parser parser
@@ -231,8 +307,68 @@ type shaperImpl struct {
 	outScratchBuf                []shaping.Output
 	scratchRunes                 []rune
 
-	// bitmapGlyphCache caches extracted bitmap glyph images.
-	bitmapGlyphCache bitmapCache
+	// features caches parsed lists of font features.
+	features map[string][]shaping.FontFeature
+	// variations caches parsed lists of variation axis values.
+	variations map[string][]variation
+	// hyphenators maps languages to their hyphenators.
+	hyphenators map[string]*Hyphenator
+	// hyphenScratch and hyphenPoints are scratch buffers for hyphenation.
+	hyphenScratch []rune
+	hyphenPoints  []int
+	// query is the font map query for the font of the text being shaped.
+	query fontscan.Query
+	// fallbacks are the configured fallbacks, and langFallbacks those
+	// applying to text of language.
+	fallbacks     []fallback
+	language      string
+	langFallbacks []fallback
+	// scriptQueries caches the queries extended by the fallbacks for runes
+	// of each script.
+	scriptQueries map[language.Script]fontscan.Query
+	// missing are the runes without glyphs shaped since the last call to
+	// reportMissing, which passes them to missingGlyphs if non-nil.
+	missing       []rune
+	missingGlyphs func(missing []rune)
+}
+
+// shapeStyle describes the style of text for shaping.
+type shapeStyle struct {
+	ppem       fixed.Int26_6
+	locale     system.Locale
+	font       giofont.Font
+	features   []shaping.FontFeature
+	variations []variation
+}
+
+// variation is a value for a variation axis of a font.
+type variation struct {
+	tag   string
+	value float32
+}
+
+// fontInstance is a variable font instance registered as a face.
+type fontInstance struct {
+	face font.Face
+	// used is the clock of the most recent use of the instance.
+	used uint64
+}
+
+const (
+	// maxInstances is the maximum number of variable font instances kept
+	// by a Shaper.
+	maxInstances = 64
+	// instanceSteps is the number of steps that instance coordinates are
+	// rounded to between the default and the extremes of an axis.
+	instanceSteps = 64
+)
+
+// instanceKey identifies a variable font instance.
+type instanceKey struct {
+	font font.Font
+	// coords is the design coordinates of the instance, encoded as a
+	// string.
+	coords string
 }
 
 // debugLogger only logs messages if debug.Text is true.
@@ -250,47 +386,133 @@ func (d debugLogger) Printf(format string, args ...any) {
 	}
 }
 
-func newShaperImpl(systemFonts bool, collection []FontFace) *shaperImpl {
-	var shaper shaperImpl
-	shaper.logger = newDebugLogger()
-	shaper.fontMap = fontscan.NewFontMap(shaper.logger)
-	shaper.faceToIndex = make(map[font.Font]int)
+func newFaceRegistry(systemFonts bool, collection []FontFace) *faceRegistry {
+	r := &faceRegistry{
+		logger:      newDebugLogger(),
+		faceToIndex: make(map[font.Font]int),
+		axes:        make(map[font.Font][]opentype.Axis),
+		instances:   make(map[instanceKey]*fontInstance),
+	}
+	r.fontMap = fontscan.NewFontMap(r.logger)
+	r.fontMap.SetQuery(r.mapQuery)
 	if systemFonts {
 		str, err := os.UserCacheDir()
 		if err != nil {
-			shaper.logger.Printf("failed resolving font cache dir: %v", err)
-			shaper.logger.Printf("skipping system font load")
+			r.logger.Printf("failed resolving font cache dir: %v", err)
+			r.logger.Printf("skipping system font load")
 		}
-		if err := shaper.fontMap.UseSystemFonts(str); err != nil {
-			shaper.logger.Printf("failed loading system fonts: %v", err)
+		if err := r.fontMap.UseSystemFonts(str); err != nil {
+			r.logger.Printf("failed loading system fonts: %v", err)
 		}
 	}
 	for _, f := range collection {
-		shaper.Load(f)
-		shaper.defaultFaces = append(shaper.defaultFaces, string(f.Font.Typeface))
+		r.Load(f)
+		r.defaultFaces = append(r.defaultFaces, string(f.Font.Typeface))
+	}
+	return r
+}
+
+func newShaperImpl(systemFonts bool, collection []FontFace) *shaperImpl {
+	return newFaceRegistry(systemFonts, collection).newShaperImpl()
+}
+
+// newShaperImpl returns a shaperImpl for the faces of r.
+func (r *faceRegistry) newShaperImpl() *shaperImpl {
+	shaper := &shaperImpl{
+		faces:  r,
+		logger: r.logger,
 	}
 	shaper.shaper.SetFontCacheSize(32)
-	return &shaper
+	return shaper
 }
 
 // Load registers the provided FontFace with the shaper, if it is compatible.
 // It returns whether the face is now available for use. FontFaces are prioritized
 // in the order in which they are loaded, with the first face being the default.
-func (s *shaperImpl) Load(f FontFace) {
+func (r *faceRegistry) Load(f FontFace) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	desc := opentype.FontToDescription(f.Font)
-	s.fontMap.AddFace(f.Face.Face(), fontscan.Location{File: fmt.Sprint(desc)}, desc)
-	s.addFace(f.Face.Face(), f.Font)
+	r.fontMap.AddFace(f.Face.Face(), fontscan.Location{File: fmt.Sprint(desc)}, desc)
+	face := f.Face.Face()
+	r.addFace(face, f.Font)
+	var axes []opentype.Axis
+	if of, ok := f.Face.(opentype.Face); ok {
+		axes = of.Axes()
+	}
+	r.axes[face.Font] = axes
 }
 
-func (s *shaperImpl) addFace(f font.Face, md giofont.Font) {
-	if _, ok := s.faceToIndex[f.Font]; ok {
+// addFace registers f, if not already registered. The caller must hold
+// r.mu.
+func (r *faceRegistry) addFace(f font.Face, md giofont.Font) {
+	if _, ok := r.faceToIndex[f.Font]; ok {
 		return
 	}
-	s.logger.Printf("loaded face %s(style:%s, weight:%d)", md.Typeface, md.Style, md.Weight)
-	idx := len(s.faces)
-	s.faceToIndex[f.Font] = idx
-	s.faces = append(s.faces, f)
-	s.faceMeta = append(s.faceMeta, md)
+	r.logger.Printf("loaded face %s(style:%s, weight:%d)", md.Typeface, md.Style, md.Weight)
+	if len(r.faces) == 1<<facebits && len(r.retired) > 0 {
+		// Reuse the index retired the longest time ago.
+		idx := r.retired[0]
+		r.retired = r.retired[:copy(r.retired, r.retired[1:])]
+		r.faceToIndex[f.Font] = idx
+		r.faces[idx] = f
+		r.faceMeta[idx] = md
+		return
+	}
+	idx := len(r.faces)
+	r.faceToIndex[f.Font] = idx
+	r.faces = append(r.faces, f)
+	r.faceMeta = append(r.faceMeta, md)
+}
+
+// face returns the face with index idx, or nil if there is none.
+func (r *faceRegistry) face(idx int) font.Face {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if idx < 0 || idx >= len(r.faces) {
+		return nil
+	}
+	return r.faces[idx]
+}
+
+// meta returns the font of the face with index idx.
+func (r *faceRegistry) meta(idx int) (giofont.Font, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if idx < 0 || idx >= len(r.faceMeta) {
+		return giofont.Font{}, false
+	}
+	return r.faceMeta[idx], true
+}
+
+// index returns the index of the face of f.
+func (r *faceRegistry) index(f font.Font) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.faceToIndex[f]
+}
+
+// resolveFace resolves the face for rn with the font map query q, and
+// registers it.
+func (r *faceRegistry) resolveFace(q fontscan.Query, rn rune) font.Face {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if q.Aspect != r.mapQuery.Aspect || !slices.Equal(q.Families, r.mapQuery.Families) {
+		// Avoid resetting the font map unless the query changes.
+		r.mapQuery = q
+		r.fontMap.SetQuery(q)
+	}
+	face := r.fontMap.ResolveFace(rn)
+	if face != nil {
+		family, aspect := r.fontMap.FontMetadata(face.Font)
+		md := opentype.DescriptionToFont(metadata.Description{
+			Family: family,
+			Aspect: aspect,
+		})
+		r.addFace(face, md)
+		return face
+	}
+	return nil
 }
 
 // splitByScript divides the inputs into new, smaller inputs on script boundaries
@@ -374,21 +596,48 @@ func (s *shaperImpl) splitBidi(input shaping.Input) []shaping.Input {
 	return splitInputs
 }
 
-// ResolveFace allows shaperImpl to implement shaping.FontMap, wrapping its fontMap
-// field and ensuring that any faces loaded as part of the search are registered with
-// ids so that they can be referred to by a GlyphID.
+// ResolveFace allows shaperImpl to implement shaping.FontMap, wrapping the font
+// map of its faces and ensuring that any faces loaded as part of the search are
+// registered with ids so that they can be referred to by a GlyphID.
 func (s *shaperImpl) ResolveFace(r rune) font.Face {
-	face := s.fontMap.ResolveFace(r)
-	if face != nil {
-		family, aspect := s.fontMap.FontMetadata(face.Font)
-		md := opentype.DescriptionToFont(metadata.Description{
-			Family: family,
-			Aspect: aspect,
-		})
-		s.addFace(face, md)
-		return face
+	return s.faces.resolveFace(s.queryFor(r), r)
+}
+
+// splitBySpans divides the inputs at the boundaries of the spans, and by
+// font coverage in the fonts of the spans. It will use the slice provided in
+// buf as the backing storage of the returned slice if buf is non-nil.
+func (s *shaperImpl) splitBySpans(inputs []shaping.Input, spans []paragraphSpan, vars []variation, buf []shaping.Input) []shaping.Input {
+	split := buf[:0]
+	for _, input := range inputs {
+		if input.RunStart == input.RunEnd {
+			if sp := spans[0]; sp.PxPerEm != 0 {
+				input.Size = sp.PxPerEm
+			}
+			split = append(split, input)
+			continue
+		}
+		start := 0
+		for _, sp := range spans {
+			end := start + sp.Runes
+			lo, hi := max(start, input.RunStart), min(end, input.RunEnd)
+			start = end
+			if lo >= hi {
+				continue
+			}
+			in := input
+			in.RunStart, in.RunEnd = lo, hi
+			if sp.PxPerEm != 0 {
+				in.Size = sp.PxPerEm
+			}
+			s.setQuery(sp.Font)
+			n := len(split)
+			split = append(split, shaping.SplitByFace(in, s)...)
+			for i := n; i < len(split); i++ {
+				split[i].Face = s.faces.instance(split[i].Face, sp.Font.Weight, vars)
+			}
+		}
 	}
-	return nil
+	return split
 }
 
 // splitByFaces divides the inputs by font coverage in the provided faces. It will use the slice provided in buf
@@ -407,24 +656,38 @@ func (s *shaperImpl) splitByFaces(inputs []shaping.Input, buf []shaping.Input) [
 }
 
 // shapeText invokes the text shaper and returns the raw text data in the shaper's native
-// format. It does not wrap lines.
-func (s *shaperImpl) shapeText(ppem fixed.Int26_6, lc system.Locale, txt []rune) []shaping.Output {
+// format. It does not wrap lines. If spans is non-empty, it must cover txt and
+// its styles replace the size and font of style.
+func (s *shaperImpl) shapeText(style shapeStyle, spans []paragraphSpan, txt []rune) []shaping.Output {
 	lcfg := langConfig{
-		Language:  language.NewLanguage(lc.Language),
-		Direction: mapDirection(lc.Direction),
+		Language:  language.NewLanguage(style.locale.Language),
+		Direction: mapDirection(style.locale.Direction),
 	}
+	s.setLanguage(style.locale.Language)
 	// Create an initial input.
-	input := toInput(nil, ppem, lcfg, txt)
-	if input.RunStart == input.RunEnd && len(s.faces) > 0 {
+	input := toInput(nil, style.ppem, lcfg, txt)
+	input.FontFeatures = style.features
+	if input.RunStart == input.RunEnd {
 		// Give the empty string a face. This is a necessary special case because
 		// the face splitting process works by resolving faces for each rune, and
 		// the empty string contains no runes.
-		input.Face = s.faces[0]
+		input.Face = s.faces.face(0)
 	}
 	// Break input on font glyph coverage.
 	inputs := s.splitBidi(input)
-	inputs = s.splitByFaces(inputs, s.splitScratch1[:0])
+	if len(spans) > 0 {
+		inputs = s.splitBySpans(inputs, spans, style.variations, s.splitScratch1[:0])
+	} else {
+		inputs = s.splitByFaces(inputs, s.splitScratch1[:0])
+		for i := range inputs {
+			inputs[i].Face = s.faces.instance(inputs[i].Face, style.font.Weight, style.variations)
+		}
+	}
 	inputs = splitByScript(inputs, lcfg.Direction, s.splitScratch2[:0])
+	vertical := style.locale.Direction.Axis() == system.Vertical
+	if vertical {
+		inputs = splitByOrientation(inputs, s.splitScratch1[:0])
+	}
 	// Shape all inputs.
 	if needed := len(inputs) - len(s.outScratchBuf); needed > 0 {
 		s.outScratchBuf = slices.Grow(s.outScratchBuf, needed)
@@ -432,8 +695,16 @@ func (s *shaperImpl) shapeText(ppem fixed.Int26_6, lc system.Locale, txt []rune)
 	s.outScratchBuf = s.outScratchBuf[:0]
 	for _, input := range inputs {
 		if input.Face != nil {
-			s.outScratchBuf = append(s.outScratchBuf, s.shaper.Shape(input))
+			out := s.shaper.Shape(input)
+			if input.Direction.IsVertical() {
+				uprightOutput(&out)
+			} else if vertical {
+				centerSideways(&out)
+			}
+			s.recordMissing(out, txt)
+			s.outScratchBuf = append(s.outScratchBuf, out)
 		} else {
+			s.recordMissingRunes(input.Text[input.RunStart:input.RunEnd])
 			s.outScratchBuf = append(s.outScratchBuf, shaping.Output{
 				// Use the text size as the advance of the entire fake run so that
 				// it doesn't occupy zero space.
@@ -488,36 +759,278 @@ func wrapPolicyToGoText(p WrapPolicy) shaping.LineBreakPolicy {
 	}
 }
 
+// setQuery configures the font map to resolve faces for f.
+func (s *shaperImpl) setQuery(f giofont.Font) {
+	families := s.faces.defaultFaces
+	if f.Typeface != "" {
+		parsed, err := s.parser.parse(string(f.Typeface))
+		if err != nil {
+			s.logger.Printf("Unable to parse typeface %q: %v", f.Typeface, err)
+		} else {
+			families = parsed
+		}
+	}
+	query := fontscan.Query{
+		Families: families,
+		Aspect:   opentype.FontToDescription(f).Aspect,
+	}
+	if query.Aspect != s.query.Aspect || !slices.Equal(query.Families, s.query.Families) {
+		// The parser reuses the memory of its results.
+		query.Families = append([]string(nil), query.Families...)
+		s.query = query
+		clear(s.scriptQueries)
+	}
+}
+
 // shapeAndWrapText invokes the text shaper and returns wrapped lines in the shaper's native format.
-func (s *shaperImpl) shapeAndWrapText(params Parameters, txt []rune) (_ []shaping.Line, truncated int) {
+func (s *shaperImpl) shapeAndWrapText(params Parameters, spans []paragraphSpan, txt []rune) (_ []shaping.Line, truncated int) {
 	wc := shaping.WrapConfig{
 		TruncateAfterLines: params.MaxLines,
 		TextContinues:      params.forceTruncate,
 		BreakPolicy:        wrapPolicyToGoText(params.WrapPolicy),
 	}
-	families := s.defaultFaces
-	if params.Font.Typeface != "" {
-		parsed, err := s.parser.parse(string(params.Font.Typeface))
-		if err != nil {
-			s.logger.Printf("Unable to parse typeface %q: %v", params.Font.Typeface, err)
-		} else {
-			families = parsed
-		}
+	s.setQuery(params.Font)
+	style := shapeStyle{
+		ppem:       params.PxPerEm,
+		locale:     params.Locale,
+		font:       params.Font,
+		features:   s.fontFeatures(params.Features),
+		variations: s.fontVariations(params.Variations),
 	}
-	s.fontMap.SetQuery(fontscan.Query{
-		Families: families,
-		Aspect:   opentype.FontToDescription(params.Font).Aspect,
-	})
 	if wc.TruncateAfterLines > 0 {
 		if len(params.Truncator) == 0 {
 			params.Truncator = "…"
 		}
 		// We only permit a single run as the truncator, regardless of whether more were generated.
 		// Just use the first one.
-		wc.Truncator = s.shapeText(params.PxPerEm, params.Locale, []rune(params.Truncator))[0]
+		wc.Truncator = s.shapeText(style, nil, []rune(params.Truncator))[0]
+	}
+	maxWidth := params.MaxWidth
+	if params.WrapPolicy == WrapHyphenate {
+		// Leave room for the hyphens displayed at the ends of lines.
+		if hyphen := s.shapeText(style, nil, []rune{'-'}); len(hyphen) > 0 {
+			maxWidth -= hyphen[0].Advance.Ceil()
+		}
 	}
 	// Wrap outputs into lines.
-	return s.wrapper.WrapParagraph(wc, params.MaxWidth, txt, shaping.NewSliceIterator(s.shapeText(params.PxPerEm, params.Locale, txt)))
+	return s.wrapper.WrapParagraph(wc, maxWidth, txt, shaping.NewSliceIterator(s.shapeText(style, spans, txt)))
+}
+
+// maxFeatureLists is the maximum number of parsed feature lists cached by a
+// shaper.
+const maxFeatureLists = 32
+
+// fontFeatures returns the parsed form of a list of features in the format
+// of Parameters.Features. Invalid features are logged and ignored.
+func (s *shaperImpl) fontFeatures(list string) []shaping.FontFeature {
+	if list == "" {
+		return nil
+	}
+	if f, ok := s.features[list]; ok {
+		return f
+	}
+	if s.features == nil || len(s.features) >= maxFeatureLists {
+		s.features = make(map[string][]shaping.FontFeature)
+	}
+	var features []shaping.FontFeature
+	for _, f := range strings.FieldsFunc(list, isFeatureSeparator) {
+		feature, err := parseFeature(f)
+		if err != nil {
+			s.logger.Printf("Ignoring font feature: %v", err)
+			continue
+		}
+		features = append(features, feature)
+	}
+	s.features[list] = features
+	return features
+}
+
+// fontVariations returns the parsed form of a list of variations in the
+// format of Parameters.Variations. Invalid values are logged and ignored.
+func (s *shaperImpl) fontVariations(list string) []variation {
+	if list == "" {
+		return nil
+	}
+	if v, ok := s.variations[list]; ok {
+		return v
+	}
+	if s.variations == nil || len(s.variations) >= maxFeatureLists {
+		s.variations = make(map[string][]variation)
+	}
+	var vars []variation
+	for _, f := range strings.FieldsFunc(list, isFeatureSeparator) {
+		tag, v, ok := strings.Cut(f, "=")
+		if !ok || len(tag) != 4 {
+			s.logger.Printf("Ignoring font variation: invalid variation %q", f)
+			continue
+		}
+		value, err := strconv.ParseFloat(v, 32)
+		if err != nil {
+			s.logger.Printf("Ignoring font variation: invalid value in %q: %v", f, err)
+			continue
+		}
+		vars = append(vars, variation{tag: tag, value: float32(value)})
+	}
+	s.variations[list] = vars
+	return vars
+}
+
+// fontAxes returns the variation axes of f. The caller must hold r.mu.
+func (r *faceRegistry) fontAxes(f font.Font) []opentype.Axis {
+	if axes, ok := r.axes[f]; ok {
+		return axes
+	}
+	// Read the axes of system fonts from their files.
+	var axes []opentype.Axis
+	if loc := r.fontMap.FontLocation(f); loc.File != "" {
+		if file, err := os.Open(loc.File); err == nil {
+			axes, err = opentype.ReadAxes(file, int(loc.Index))
+			file.Close()
+			if err != nil {
+				r.logger.Printf("failed reading variation axes of %s: %v", loc.File, err)
+			}
+		}
+	}
+	r.axes[f] = axes
+	return axes
+}
+
+// instance returns the instance of face for the weight and variations,
+// registering it if necessary. It returns face if face is not a variable
+// font, or the instance is the default instance.
+func (r *faceRegistry) instance(face font.Face, weight giofont.Weight, vars []variation) font.Face {
+	if face == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	axes := r.fontAxes(face.Font)
+	if len(axes) == 0 {
+		return face
+	}
+	coords := make([]float32, len(axes))
+	isDefault := true
+	for i, a := range axes {
+		v := a.Default
+		if a.Tag == "wght" {
+			v = float32(400 + weight)
+		}
+		for _, vr := range vars {
+			if vr.tag == a.Tag {
+				v = vr.value
+			}
+		}
+		v = quantizeCoord(a, v)
+		coords[i] = v
+		if v != a.Default {
+			isDefault = false
+		}
+	}
+	if isDefault {
+		return face
+	}
+	key := make([]byte, 0, 4*len(coords))
+	for _, c := range coords {
+		key = binary.LittleEndian.AppendUint32(key, math.Float32bits(c))
+	}
+	k := instanceKey{font: face.Font, coords: string(key)}
+	r.clock++
+	if inst, ok := r.instances[k]; ok {
+		inst.used = r.clock
+		return inst.face
+	}
+	// The instance needs a distinct font to be distinguished from other
+	// instances by the shaping caches, and to get distinct glyph ids.
+	fnt := *face.Font
+	inst := &fontapi.Face{Font: &fnt, Coords: face.Font.NormalizeVariations(coords)}
+	md := giofont.Font{Weight: weight}
+	if idx, ok := r.faceToIndex[face.Font]; ok {
+		md = r.faceMeta[idx]
+	}
+	if len(r.instances) == maxInstances {
+		r.evictInstance()
+	}
+	r.addFace(inst, md)
+	r.axes[inst.Font] = axes
+	r.instances[k] = &fontInstance{face: inst, used: r.clock}
+	return inst
+}
+
+// evictInstance evicts the least recently used instance, and retires its
+// face index. The glyphs of the instance are no longer drawn. The caller
+// must hold r.mu.
+func (r *faceRegistry) evictInstance() {
+	var oldest instanceKey
+	var old *fontInstance
+	for k, i := range r.instances {
+		if old == nil || i.used < old.used {
+			oldest, old = k, i
+		}
+	}
+	delete(r.instances, oldest)
+	idx := r.faceToIndex[old.face.Font]
+	delete(r.faceToIndex, old.face.Font)
+	delete(r.axes, old.face.Font)
+	r.faces[idx] = nil
+	r.retired = append(r.retired, idx)
+	r.evictions++
+}
+
+// evicted returns the number of evicted instances.
+func (r *faceRegistry) evicted() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.evictions
+}
+
+// quantizeCoord clamps the design coordinate v to the range of the axis
+// a, and rounds it to a step of the axis, such that values changing
+// continuously create a limited number of instances.
+func quantizeCoord(a opentype.Axis, v float32) float32 {
+	if v < a.Min {
+		v = a.Min
+	} else if v > a.Max {
+		v = a.Max
+	}
+	span := a.Max - a.Default
+	if v < a.Default {
+		span = a.Default - a.Min
+	}
+	if span <= 0 {
+		return v
+	}
+	steps := math.Round(float64((v - a.Default) / span * instanceSteps))
+	return a.Default + float32(steps)*span/instanceSteps
+}
+
+func isFeatureSeparator(r rune) bool {
+	return r == ',' || unicode.IsSpace(r)
+}
+
+// parseFeature parses a single font feature such as "tnum", "-liga" or
+// "ss01=1".
+func parseFeature(f string) (shaping.FontFeature, error) {
+	value := uint32(1)
+	tag := f
+	switch tag[0] {
+	case '+':
+		tag = tag[1:]
+	case '-':
+		value = 0
+		tag = tag[1:]
+	default:
+		if t, v, ok := strings.Cut(tag, "="); ok {
+			n, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				return shaping.FontFeature{}, fmt.Errorf("invalid value in %q: %w", f, err)
+			}
+			tag, value = t, uint32(n)
+		}
+	}
+	if len(tag) != 4 {
+		return shaping.FontFeature{}, fmt.Errorf("invalid tag in %q", f)
+	}
+	return shaping.FontFeature{Tag: loader.NewTag(tag[0], tag[1], tag[2], tag[3]), Value: value}, nil
 }
 
 // replaceControlCharacters replaces problematic unicode
@@ -576,6 +1089,12 @@ func calculateYOffsets(lines []line) {
 
 // LayoutRunes shapes and wraps the text, and returns the result in Gio's shaped text format.
 func (s *shaperImpl) LayoutRunes(params Parameters, txt []rune) document {
+	return s.layoutRunes(params, nil, txt)
+}
+
+// layoutRunes is like LayoutRunes, but styles the text with spans if
+// non-empty.
+func (s *shaperImpl) layoutRunes(params Parameters, spans []paragraphSpan, txt []rune) document {
 	hasNewline := len(txt) > 0 && txt[len(txt)-1] == '\n'
 	var ls []shaping.Line
 	var truncated int
@@ -587,7 +1106,20 @@ func (s *shaperImpl) LayoutRunes(params Parameters, txt []rune) document {
 		// on the final line (if we hit the limit).
 		params.forceTruncate = true
 	}
-	ls, truncated = s.shapeAndWrapText(params, replaceControlCharacters(txt))
+	// inserted are the indices of soft hyphens inserted into txt, and
+	// shapeSpans are the spans extended to cover them.
+	var inserted []int
+	shapeSpans := spans
+	if params.WrapPolicy == WrapHyphenate && params.Locale.Direction.Progression() == system.FromOrigin {
+		if h := s.hyphenator(params.Locale.Language); h != nil {
+			txt, inserted = s.hyphenate(h, txt)
+			shapeSpans = insertSpanRunes(spans, inserted)
+		}
+	}
+	ls, truncated = s.shapeAndWrapText(params, shapeSpans, replaceControlCharacters(txt))
+	s.reportMissing()
+	// Don't count inserted runes as truncated text.
+	truncated -= len(inserted) - sort.SearchInts(inserted, len(txt)-truncated)
 
 	hasTruncator := truncated > 0 || (params.forceTruncate && params.MaxLines == len(ls))
 	if hasTruncator && hasNewline {
@@ -600,8 +1132,9 @@ func (s *shaperImpl) LayoutRunes(params Parameters, txt []rune) document {
 	// Convert to Lines.
 	textLines := make([]line, len(ls))
 	maxHeight := fixed.Int26_6(0)
+	s.faces.mu.Lock()
 	for i := range ls {
-		otLine := toLine(s.faceToIndex, ls[i], params.Locale.Direction)
+		otLine := toLine(s.faces.faceToIndex, ls[i], params.Locale.Direction)
 		if otLine.lineHeight > maxHeight {
 			maxHeight = otLine.lineHeight
 		}
@@ -615,6 +1148,23 @@ func (s *shaperImpl) LayoutRunes(params Parameters, txt []rune) document {
 		}
 		textLines[i] = otLine
 	}
+	s.faces.mu.Unlock()
+	if params.WrapPolicy == WrapHyphenate {
+		s.showHyphens(textLines, txt)
+	}
+	if len(inserted) > 0 {
+		removeInsertedRunes(textLines, inserted)
+	}
+	if params.TabStops.enabled() {
+		s.applyTabStops(textLines, txt, params.TabStops)
+	}
+	for i := range textLines {
+		markJustification(&textLines[i], txt)
+	}
+	textLines[len(textLines)-1].final = true
+	// The inserted runes are removed from the lines, so the original spans
+	// apply.
+	applySpans(textLines, spans)
 	if params.LineHeight != 0 {
 		maxHeight = params.LineHeight
 	}
@@ -634,6 +1184,225 @@ func (s *shaperImpl) LayoutRunes(params Parameters, txt []rune) document {
 	}
 }
 
+// applySpans records the span of every run of lines, and applies the
+// baseline shifts of the spans to their glyphs and lines.
+func applySpans(lines []line, spans []paragraphSpan) {
+	if len(spans) == 0 {
+		return
+	}
+	si, runeOff, spanEnd := 0, 0, spans[0].Runes
+	for i := range lines {
+		l := &lines[i]
+		for j := range l.runs {
+			run := &l.runs[j]
+			for si < len(spans)-1 && runeOff >= spanEnd {
+				si++
+				spanEnd += spans[si].Runes
+			}
+			runeOff += run.Runes.Count
+			sp := spans[si]
+			run.span = sp.index
+			shift := sp.BaselineShift
+			if shift == 0 || run.truncator {
+				continue
+			}
+			for k := range run.Glyphs {
+				g := &run.Glyphs[k]
+				g.yOffset += shift
+				g.bounds.Min.Y -= shift
+				g.bounds.Max.Y -= shift
+			}
+			if a := run.ascent + shift; a > l.ascent {
+				l.ascent = a
+			}
+			if d := run.descent - shift; d > l.descent {
+				l.descent = d
+			}
+		}
+	}
+}
+
+// applyTabStops sets the advances of the tab characters of left-to-right
+// lines such that the text following them is aligned to the tab stops.
+// Tabs are displayed as spaces.
+func (s *shaperImpl) applyTabStops(lines []line, txt []rune, tabs TabStops) {
+	for i := range lines {
+		l := &lines[i]
+		if l.direction.Progression() != system.FromOrigin {
+			continue
+		}
+		var x fixed.Int26_6
+		for vi, ri := range l.visualOrder {
+			run := &l.runs[ri]
+			for gi := range run.Glyphs {
+				g := &run.Glyphs[gi]
+				if run.truncator || g.clusterIndex >= len(txt) || txt[g.clusterIndex] != '\t' {
+					x += g.xAdvance
+					continue
+				}
+				stop, ok := tabs.next(x)
+				if !ok {
+					x += g.xAdvance
+					continue
+				}
+				adv := stop.Position - x
+				if stop.Alignment != TabStart {
+					adv -= tabSegmentWidth(l, txt, vi, gi+1, stop.Alignment == TabDecimal)
+				}
+				if adv < 0 {
+					adv = 0
+				}
+				if run.face != nil {
+					if gid, ok := run.face.NominalGlyph(' '); ok {
+						g.id = newGlyphID(run.PPEM, s.faces.index(run.face.Font), gid)
+					}
+				}
+				g.bounds = fixed.Rectangle26_6{}
+				run.Advance += adv - g.xAdvance
+				l.width += adv - g.xAdvance
+				g.xAdvance = adv
+				x += adv
+			}
+		}
+		positionRuns(l)
+	}
+}
+
+// tabSegmentWidth returns the width of the text of a left-to-right line that
+// starts at glyph gi of the visual run vi and ends before the next tab or the
+// end of the line. If decimal is set, the segment ends before its first
+// decimal point, if any.
+func tabSegmentWidth(l *line, txt []rune, vi, gi int, decimal bool) fixed.Int26_6 {
+	var w, point fixed.Int26_6
+	hasPoint := false
+	for ; vi < len(l.visualOrder); vi, gi = vi+1, 0 {
+		run := &l.runs[l.visualOrder[vi]]
+		for ; gi < len(run.Glyphs); gi++ {
+			g := run.Glyphs[gi]
+			if !run.truncator && g.clusterIndex < len(txt) {
+				switch r := txt[g.clusterIndex]; {
+				case r == '\t':
+					if decimal && hasPoint {
+						return point
+					}
+					return w
+				case r == '.' && !hasPoint:
+					hasPoint = true
+					point = w
+				}
+			}
+			w += g.xAdvance
+		}
+	}
+	if decimal && hasPoint {
+		return point
+	}
+	return w
+}
+
+// markJustification marks the glyphs of l that are stretched to justify it,
+// and computes the width of the whitespace hanging at its end.
+func markJustification(l *line, txt []rune) {
+	if len(l.runs) == 0 {
+		return
+	}
+	// Find the start of the trailing whitespace of the line.
+	start, end := len(txt), 0
+	for _, run := range l.runs {
+		if run.truncator {
+			continue
+		}
+		for _, g := range run.Glyphs {
+			if g.glyphCount == 0 || g.clusterIndex >= len(txt) {
+				continue
+			}
+			start = min(start, g.clusterIndex)
+			end = max(end, g.clusterIndex+g.runeCount)
+		}
+	}
+	for end > start && unicode.IsSpace(txt[end-1]) {
+		end--
+	}
+	hangs := func(run *runLayout, g glyph) bool {
+		return !run.truncator && g.clusterIndex >= end
+	}
+	// lastChar is the visually last glyph of the content, which is never
+	// stretched.
+	var lastChar *glyph
+	var words, chars int
+	for _, ri := range l.visualOrder {
+		run := &l.runs[ri]
+		run.wordsBefore, run.charsBefore = words, chars
+		for gi := range run.Glyphs {
+			g := &run.Glyphs[gi]
+			g.justify = 0
+			if hangs(run, *g) {
+				l.hang += g.xAdvance
+				continue
+			}
+			if g.glyphCount == 0 {
+				continue
+			}
+			if g.clusterIndex < len(txt) && unicode.Is(unicode.Zs, txt[g.clusterIndex]) {
+				g.justify |= justifyWord
+				run.words++
+				words++
+			}
+			// Stretch after the visually last glyph of every cluster.
+			last := gi == len(run.Glyphs)-1 || run.Glyphs[gi+1].clusterIndex != g.clusterIndex
+			if last {
+				g.justify |= justifyChar
+				run.chars++
+				chars++
+				lastChar = g
+			}
+		}
+	}
+	if lastChar != nil {
+		// Nothing follows the last character, so recount the stretched
+		// characters without it.
+		lastChar.justify &^= justifyChar
+		chars = 0
+		for _, ri := range l.visualOrder {
+			run := &l.runs[ri]
+			run.charsBefore = chars
+			run.chars = 0
+			for _, g := range run.Glyphs {
+				if g.justify&justifyChar != 0 {
+					run.chars++
+				}
+			}
+			chars += run.chars
+		}
+	}
+	l.words, l.chars = words, chars
+}
+
+// justification returns the space to distribute among the glyphs of l
+// stretched to justify it to width, the justification mode of the
+// glyphs, and their number. It returns a zero mode if l isn't
+// justified.
+func (l *line) justification(a Alignment, width int) (fixed.Int26_6, uint8, int) {
+	if !a.justified() || l.final {
+		return 0, 0, 0
+	}
+	mode, n := justifyWord, l.words
+	if a == JustifyCharacters {
+		mode, n = justifyChar, l.chars
+	}
+	extra := fixed.I(width) - (l.width - l.hang)
+	if n == 0 || extra <= 0 {
+		return 0, 0, 0
+	}
+	return extra, mode, n
+}
+
+// justifyOffset returns the part of extra distributed among the first i of
+// n stretched glyphs.
+func justifyOffset(extra fixed.Int26_6, i, n int) fixed.Int26_6 {
+	return fixed.Int26_6(int64(extra) * int64(i) / int64(n))
+}
+
 func alignWidth(minWidth int, lines []line) int {
 	for _, l := range lines {
 		minWidth = max(minWidth, l.width.Ceil())
@@ -643,7 +1412,7 @@ func alignWidth(minWidth int, lines []line) int {
 
 // Shape converts the provided glyphs into a path. The path will enclose the forms
 // of all vector glyphs.
-func (s *shaperImpl) Shape(pathOps *op.Ops, gs []Glyph) clip.PathSpec {
+func (r *faceRegistry) Shape(pathOps *op.Ops, gs []Glyph) clip.PathSpec {
 	var lastPos f32.Point
 	var x fixed.Int26_6
 	var builder clip.Path
@@ -653,10 +1422,7 @@ func (s *shaperImpl) Shape(pathOps *op.Ops, gs []Glyph) clip.PathSpec {
 			x = g.X
 		}
 		ppem, faceIdx, gid := splitGlyphID(g.ID)
-		if faceIdx >= len(s.faces) {
-			continue
-		}
-		face := s.faces[faceIdx]
+		face := r.face(faceIdx)
 		if face == nil {
 			continue
 		}
@@ -673,6 +1439,7 @@ func (s *shaperImpl) Shape(pathOps *op.Ops, gs []Glyph) clip.PathSpec {
 			builder.Move(pos.Sub(lastPos))
 			lastPos = pos
 			var lastArg f32.Point
+			upright := g.Flags&FlagUpright != 0
 
 			// Convert fonts.Segments to relative segments.
 			for _, fseg := range outline.Segments {
@@ -689,6 +1456,13 @@ func (s *shaperImpl) Shape(pathOps *op.Ops, gs []Glyph) clip.PathSpec {
 						X: fseg.Args[i].X * scaleFactor,
 						Y: -fseg.Args[i].Y * scaleFactor,
 					}
+					if upright {
+						// Rotate the glyph counter-clockwise.
+						a = f32.Point{
+							X: -fseg.Args[i].Y * scaleFactor,
+							Y: -fseg.Args[i].X * scaleFactor,
+						}
+					}
 					args[i] = a.Sub(lastArg)
 					if i == nargs-1 {
 						lastArg = a
@@ -724,8 +1498,8 @@ func floatToFixed(f float32) fixed.Int26_6 {
 // Bitmaps returns an op.CallOp that will display all bitmap glyphs within gs.
 // The positioning of the bitmaps uses the same logic as Shape(), so the returned
 // CallOp can be added at the same offset as the path data returned by Shape()
-// and will align correctly.
-func (s *shaperImpl) Bitmaps(ops *op.Ops, gs []Glyph) op.CallOp {
+// and will align correctly. The bitmap images are cached in cache.
+func (r *faceRegistry) Bitmaps(ops *op.Ops, cache *bitmapCache, gs []Glyph) op.CallOp {
 	var x fixed.Int26_6
 	bitmapMacro := op.Record(ops)
 	for i, g := range gs {
@@ -733,10 +1507,7 @@ func (s *shaperImpl) Bitmaps(ops *op.Ops, gs []Glyph) op.CallOp {
 			x = g.X
 		}
 		_, faceIdx, gid := splitGlyphID(g.ID)
-		if faceIdx >= len(s.faces) {
-			continue
-		}
-		face := s.faces[faceIdx]
+		face := r.face(faceIdx)
 		if face == nil {
 			continue
 		}
@@ -745,7 +1516,7 @@ func (s *shaperImpl) Bitmaps(ops *op.Ops, gs []Glyph) op.CallOp {
 		case api.GlyphBitmap:
 			var imgOp paint.ImageOp
 			var imgSize image.Point
-			bitmapData, ok := s.bitmapGlyphCache.Get(g.ID)
+			bitmapData, ok := cache.Get(g.ID)
 			if !ok {
 				var img image.Image
 				switch glyphData.Format {
@@ -761,17 +1532,11 @@ func (s *shaperImpl) Bitmaps(ops *op.Ops, gs []Glyph) op.CallOp {
 				}
 				imgOp = paint.NewImageOp(img)
 				imgSize = img.Bounds().Size()
-				s.bitmapGlyphCache.Put(g.ID, bitmap{img: imgOp, size: imgSize})
+				cache.Put(g.ID, bitmap{img: imgOp, size: imgSize})
 			} else {
 				imgOp = bitmapData.img
 				imgSize = bitmapData.size
 			}
-			off := op.Affine(f32.Affine2D{}.Offset(f32.Point{
-				X: fixedToFloat((g.X - x) - g.Offset.X),
-				Y: fixedToFloat(g.Offset.Y + g.Bounds.Min.Y),
-			})).Push(ops)
-			cl := clip.Rect{Max: imgSize}.Push(ops)
-
 			glyphSize := image.Rectangle{
 				Min: image.Point{
 					X: g.Bounds.Min.X.Round(),
@@ -782,6 +1547,26 @@ func (s *shaperImpl) Bitmaps(ops *op.Ops, gs []Glyph) op.CallOp {
 					Y: g.Bounds.Max.Y.Round(),
 				},
 			}.Size()
+			if g.Flags&FlagUpright != 0 {
+				// Rotate the image counter-clockwise, with its top left
+				// corner at the bottom left corner of the glyph bounds.
+				rot := op.Affine(f32.NewAffine2D(
+					0, float32(glyphSize.X)/float32(imgSize.Y), fixedToFloat((g.X-x)+g.Bounds.Min.X),
+					-float32(glyphSize.Y)/float32(imgSize.X), 0, fixedToFloat(g.Bounds.Max.Y),
+				)).Push(ops)
+				cl := clip.Rect{Max: imgSize}.Push(ops)
+				imgOp.Add(ops)
+				paint.PaintOp{}.Add(ops)
+				cl.Pop()
+				rot.Pop()
+				continue
+			}
+			off := op.Affine(f32.Affine2D{}.Offset(f32.Point{
+				X: fixedToFloat((g.X - x) - g.Offset.X),
+				Y: fixedToFloat(g.Offset.Y + g.Bounds.Min.Y),
+			})).Push(ops)
+			cl := clip.Rect{Max: imgSize}.Push(ops)
+
 			aff := op.Affine(f32.Affine2D{}.Scale(f32.Point{}, f32.Point{
 				X: float32(glyphSize.X) / float32(imgSize.X),
 				Y: float32(glyphSize.Y) / float32(imgSize.Y),
@@ -796,6 +1581,41 @@ func (s *shaperImpl) Bitmaps(ops *op.Ops, gs []Glyph) op.CallOp {
 	return bitmapMacro.Stop()
 }
 
+// decorationLine returns the position of the top of the decoration line d
+// relative to the dot of g, and its thickness.
+func (r *faceRegistry) decorationLine(g Glyph, d Decoration) (y, thickness fixed.Int26_6) {
+	ppem, faceIdx, _ := splitGlyphID(g.ID)
+	var pos, size float32
+	if face := r.face(faceIdx); face != nil {
+		scale := fixedToFloat(ppem) / float32(face.Upem())
+		switch d {
+		case Strikethrough:
+			pos = face.LineMetric(api.StrikethroughPosition) * scale
+			size = face.LineMetric(api.StrikethroughThickness) * scale
+		default:
+			pos = face.LineMetric(api.UnderlinePosition) * scale
+			size = face.LineMetric(api.UnderlineThickness) * scale
+		}
+	}
+	// Substitute conventional values for metrics missing from the font.
+	if size <= 0 {
+		size = fixedToFloat(ppem) / 14
+	}
+	if pos == 0 {
+		switch d {
+		case Strikethrough:
+			pos = fixedToFloat(g.Ascent) / 3
+		default:
+			pos = -fixedToFloat(g.Descent) / 2
+		}
+	}
+	thickness = floatToFixed(size)
+	if d == Overline {
+		return -g.Ascent, thickness
+	}
+	return -floatToFixed(pos), thickness
+}
+
 // langConfig describes the language and writing system of a body of text.
 type langConfig struct {
 	// Language the text is written in.
@@ -842,9 +1662,13 @@ func unmapDirection(d di.Direction) system.TextDirection {
 
 // toGioGlyphs converts text shaper glyphs into the minimal representation
 // that Gio needs.
-func toGioGlyphs(in []shaping.Glyph, ppem fixed.Int26_6, faceIdx int) []glyph {
+func toGioGlyphs(in []shaping.Glyph, ppem fixed.Int26_6, faceIdx int, upright bool) []glyph {
 	out := make([]glyph, 0, len(in))
 	for _, g := range in {
+		if upright {
+			out = append(out, toUprightGlyph(g, ppem, faceIdx))
+			continue
+		}
 		// To better understand how to calculate the bounding box, see here:
 		// https://freetype.org/freetype2/docs/glyphs/glyph-metrics-3.svg
 		var bounds fixed.Rectangle26_6
@@ -886,23 +1710,26 @@ func toLine(faceToIndex map[font.Font]int, o shaping.Line, dir system.TextDirect
 			font = run.Face.Font
 		}
 		line.runs[i] = runLayout{
-			Glyphs: toGioGlyphs(run.Glyphs, run.Size, faceToIndex[font]),
+			Glyphs: toGioGlyphs(run.Glyphs, run.Size, faceToIndex[font], run.Direction.IsVertical()),
 			Runes: Range{
 				Count:  run.Runes.Count,
 				Offset: line.runeCount,
 			},
 			Direction: unmapDirection(run.Direction),
 			face:      run.Face,
+			upright:   run.Direction.IsVertical(),
 			Advance:   run.Advance,
 			PPEM:      run.Size,
+			ascent:    run.LineBounds.Ascent,
+			descent:   -run.LineBounds.Descent + run.LineBounds.Gap,
 		}
 		line.runeCount += run.Runes.Count
 		line.width += run.Advance
-		if line.ascent < run.LineBounds.Ascent {
-			line.ascent = run.LineBounds.Ascent
+		if line.ascent < line.runs[i].ascent {
+			line.ascent = line.runs[i].ascent
 		}
-		if line.descent < -run.LineBounds.Descent+run.LineBounds.Gap {
-			line.descent = -run.LineBounds.Descent + run.LineBounds.Gap
+		if line.descent < line.runs[i].descent {
+			line.descent = line.runs[i].descent
 		}
 	}
 	line.lineHeight = maxSize
@@ -960,7 +1787,12 @@ func computeVisualOrder(l *line) {
 		// We ended iteration within a bidi segment, resolve it.
 		resolveBidi(bidiRangeStart, len(l.runs))
 	}
-	// Iterate and resolve the X of each run.
+	positionRuns(l)
+}
+
+// positionRuns resolves the X of each run of l from the advances of the
+// runs visually before it.
+func positionRuns(l *line) {
 	x := fixed.Int26_6(0)
 	for _, runIdx := range l.visualOrder {
 		l.runs[runIdx].X = x
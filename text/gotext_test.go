@@ -9,6 +9,7 @@ import (
 
 	nsareg "eliasnaur.com/font/noto/sans/arabic/regular"
 	"github.com/go-text/typesetting/font"
+	"github.com/go-text/typesetting/opentype/loader"
 	"github.com/go-text/typesetting/shaping"
 	"golang.org/x/exp/slices"
 	"golang.org/x/image/font/gofont/goregular"
@@ -300,13 +301,13 @@ func makeTestText(shaper *shaperImpl, primaryDir system.TextDirection, fontSize,
 		PxPerEm:  fixed.I(fontSize),
 		MaxWidth: lineWidth,
 		Locale:   locale,
-	}, []rune(simpleSource))
+	}, nil, []rune(simpleSource))
 	simpleText = copyLines(simpleText)
 	complexText, _ := shaper.shapeAndWrapText(Parameters{
 		PxPerEm:  fixed.I(fontSize),
 		MaxWidth: lineWidth,
 		Locale:   locale,
-	}, []rune(complexSource))
+	}, nil, []rune(complexSource))
 	complexText = copyLines(complexText)
 	testShaper(rtlFace, ltrFace)
 	return simpleText, complexText
@@ -386,7 +387,7 @@ func TestToLine(t *testing.T) {
 					totalInputGlyphs += len(run.Glyphs)
 					totalInputRunes += run.Runes.Count
 				}
-				output := toLine(shaper.faceToIndex, input, tc.dir)
+				output := toLine(shaper.faces.faceToIndex, input, tc.dir)
 				if output.direction != tc.dir {
 					t.Errorf("line %d: expected direction %v, got %v", i, tc.dir, output.direction)
 				}
@@ -709,3 +710,170 @@ func TestGlyphIDPacking(t *testing.T) {
 		})
 	}
 }
+
+func TestParseFeature(t *testing.T) {
+	for _, tc := range []struct {
+		in    string
+		tag   string
+		value uint32
+		err   bool
+	}{
+		{in: "tnum", tag: "tnum", value: 1},
+		{in: "+smcp", tag: "smcp", value: 1},
+		{in: "-liga", tag: "liga", value: 0},
+		{in: "ss01=2", tag: "ss01", value: 2},
+		{in: "long", tag: "long", value: 1},
+		{in: "lig", err: true},
+		{in: "aalt=x", err: true},
+	} {
+		f, err := parseFeature(tc.in)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%q: expected error", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tc.in, err)
+			continue
+		}
+		if want := loader.MustNewTag(tc.tag); f.Tag != want || f.Value != tc.value {
+			t.Errorf("%q: got %v=%d, want %v=%d", tc.in, f.Tag, f.Value, want, tc.value)
+		}
+	}
+}
+
+// TestFeatures checks that font features affect shaping and take part in
+// the layout cache key.
+func TestFeatures(t *testing.T) {
+	arabicFace, err := opentype.Parse(nsareg.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: arabicFace}}))
+	ids := func(features string) []GlyphID {
+		shaper.LayoutString(Parameters{
+			PxPerEm:  fixed.I(20),
+			MaxWidth: 1000,
+			Locale:   arabic,
+			Features: features,
+		}, "سلام")
+		var ids []GlyphID
+		for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+			ids = append(ids, g.ID)
+		}
+		return ids
+	}
+	joined := ids("")
+	isolated := ids("-init, -medi, -fina")
+	if slices.Equal(joined, isolated) {
+		t.Errorf("disabling joining features didn't change glyphs %v", joined)
+	}
+	if again := ids(""); !slices.Equal(joined, again) {
+		t.Errorf("got glyphs %v after re-enabling features, want %v", again, joined)
+	}
+}
+
+func TestFontVariations(t *testing.T) {
+	var s shaperImpl
+	s.logger = newDebugLogger()
+	got := s.fontVariations("wdth=75, opsz=12 slnt=-10.5, bad, long=1=2, x=3")
+	want := []variation{{tag: "wdth", value: 75}, {tag: "opsz", value: 12}, {tag: "slnt", value: -10.5}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got variations %v, want %v", got, want)
+	}
+}
+
+func TestVariableInstances(t *testing.T) {
+	face, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: face}}))
+	// Pretend the face has a weight axis.
+	shaper.faces.axes[face.Face().Font] = []opentype.Axis{{Tag: "wght", Min: 100, Default: 400, Max: 900}}
+	ids := func(weight giofont.Weight, variations string) []GlyphID {
+		shaper.LayoutString(Parameters{
+			Font:       giofont.Font{Weight: weight},
+			PxPerEm:    fixed.I(20),
+			MaxWidth:   1000,
+			Locale:     english,
+			Variations: variations,
+		}, "Hello")
+		var ids []GlyphID
+		for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+			ids = append(ids, g.ID)
+		}
+		return ids
+	}
+	regular := ids(giofont.Normal, "")
+	if got := ids(giofont.Normal, "wght=400, wdth=50"); !slices.Equal(regular, got) {
+		t.Errorf("default instance has glyphs %v, want %v", got, regular)
+	}
+	bold := ids(giofont.Bold, "")
+	if slices.Equal(regular, bold) {
+		t.Errorf("bold instance has the same glyphs %v as the default", bold)
+	}
+	if got := ids(giofont.Normal, "wght=700"); !slices.Equal(bold, got) {
+		t.Errorf("explicit weight has glyphs %v, want %v", got, bold)
+	}
+	if got := ids(giofont.Normal, "wght=1000"); !slices.Equal(ids(giofont.Normal, "wght=900"), got) {
+		t.Errorf("out of range weight wasn't clamped")
+	}
+	if n := len(shaper.faces.instances); n != 2 {
+		t.Errorf("got %d instances, want 2", n)
+	}
+}
+
+func TestVariableInstanceEviction(t *testing.T) {
+	face, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: face}}))
+	shaper.faces.axes[face.Face().Font] = []opentype.Axis{{Tag: "wght", Min: 100, Default: 400, Max: 900}}
+	layout := func(weight giofont.Weight) []GlyphID {
+		shaper.LayoutString(Parameters{
+			Font:     giofont.Font{Weight: weight},
+			PxPerEm:  fixed.I(20),
+			MaxWidth: 1000,
+			Locale:   english,
+		}, "Hello")
+		var ids []GlyphID
+		for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+			ids = append(ids, g.ID)
+		}
+		return ids
+	}
+	oldBold := layout(giofont.Bold)
+	// Animate the weight through every value.
+	for w := giofont.Weight(-300); w <= 500; w++ {
+		layout(w)
+	}
+	if n := len(shaper.faces.instances); n > maxInstances {
+		t.Errorf("got %d instances, want at most %d", n, maxInstances)
+	}
+	faces := 0
+	for _, f := range shaper.faces.faces {
+		if f != nil {
+			faces++
+		}
+	}
+	if faces > 1+maxInstances {
+		t.Errorf("got %d faces, want at most %d", faces, 1+maxInstances)
+	}
+	// The glyphs of the evicted bold instance don't refer to another face.
+	for _, id := range oldBold {
+		if _, idx, _ := splitGlyphID(id); shaper.faces.face(idx) != nil {
+			t.Errorf("glyph %v of an evicted instance refers to a face", id)
+		}
+	}
+	// The evicted bold instance is laid out again, not with the instance
+	// that replaced it.
+	bold := shaper.faces.instance(face.Face(), giofont.Bold, nil)
+	for _, id := range layout(giofont.Bold) {
+		if _, idx, _ := splitGlyphID(id); shaper.faces.face(idx) != bold {
+			t.Errorf("glyph %v is not from the bold instance", id)
+		}
+	}
+}
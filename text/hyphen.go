@@ -0,0 +1,322 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package text
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/math/fixed"
+
+	"gioui.org/io/system"
+)
+
+// softHyphen marks the points where words may be hyphenated.
+const softHyphen = '\u00ad'
+
+// Hyphenator finds the points where words may be hyphenated, using Liang's
+// algorithm with TeX hyphenation patterns.
+type Hyphenator struct {
+	// MinLeft and MinRight are the minimum numbers of runes before and
+	// after a hyphenation point. NewHyphenator sets them to 2 and 3.
+	MinLeft, MinRight int
+
+	patterns   trieNode
+	exceptions map[string][]int
+}
+
+// trieNode is a node of the trie of hyphenation patterns, indexed by the
+// letters of the patterns.
+type trieNode struct {
+	children map[rune]*trieNode
+	// values are the inter-letter values of the pattern ending at the node,
+	// if any.
+	values []uint8
+}
+
+// NewHyphenator returns a Hyphenator for hyphenation patterns and
+// exceptions in the format of TeX. Patterns are separated by white space,
+// and interleave letters with digits such as "hy3ph", where the start and
+// end of words are matched by '.'. Exceptions are words separated by white
+// space with hyphens at their hyphenation points, such as "as-so-ciate".
+//
+// Patterns for many languages are available from the hyph-utf8 project.
+func NewHyphenator(patterns, exceptions string) (*Hyphenator, error) {
+	h := &Hyphenator{
+		MinLeft:    2,
+		MinRight:   3,
+		exceptions: make(map[string][]int),
+	}
+	for _, p := range strings.Fields(patterns) {
+		n := &h.patterns
+		// values has a slot for every position between and around the
+		// letters of the pattern.
+		values := []uint8{0}
+		for _, r := range p {
+			if '0' <= r && r <= '9' {
+				values[len(values)-1] = uint8(r - '0')
+				continue
+			}
+			r = unicode.ToLower(r)
+			if n.children == nil {
+				n.children = make(map[rune]*trieNode)
+			}
+			c, ok := n.children[r]
+			if !ok {
+				c = new(trieNode)
+				n.children[r] = c
+			}
+			n = c
+			values = append(values, 0)
+		}
+		if n == &h.patterns {
+			return nil, fmt.Errorf("text: hyphenation pattern %q has no letters", p)
+		}
+		n.values = values
+	}
+	for _, e := range strings.Fields(exceptions) {
+		var word strings.Builder
+		var points []int
+		runes := 0
+		for _, r := range e {
+			if r == '-' {
+				points = append(points, runes)
+				continue
+			}
+			word.WriteRune(unicode.ToLower(r))
+			runes++
+		}
+		h.exceptions[word.String()] = points
+	}
+	return h, nil
+}
+
+// Hyphenate returns the offsets in runes of the points where word may be
+// hyphenated.
+func (h *Hyphenator) Hyphenate(word string) []int {
+	return h.points(nil, []rune(word))
+}
+
+// points appends the hyphenation points of word to dst.
+func (h *Hyphenator) points(dst []int, word []rune) []int {
+	n := len(word)
+	if n < h.MinLeft+h.MinRight || n == 0 {
+		return dst
+	}
+	// The word surrounded by the '.' markers of its start and end.
+	w := make([]rune, 0, n+2)
+	w = append(w, '.')
+	for _, r := range word {
+		w = append(w, unicode.ToLower(r))
+	}
+	w = append(w, '.')
+	if points, ok := h.exceptions[string(w[1:n+1])]; ok {
+		return append(dst, points...)
+	}
+	levels := make([]uint8, len(w)+1)
+	for i := range w {
+		node := &h.patterns
+		for j := i; j < len(w); j++ {
+			node = node.children[w[j]]
+			if node == nil {
+				break
+			}
+			for k, v := range node.values {
+				if v > levels[i+k] {
+					levels[i+k] = v
+				}
+			}
+		}
+	}
+	// The level between word[i-1] and word[i] is levels[i+1].
+	for i := max(h.MinLeft, 1); i <= n-h.MinRight; i++ {
+		if levels[i+1]%2 == 1 {
+			dst = append(dst, i)
+		}
+	}
+	return dst
+}
+
+// normalizeLanguage converts a language tag to lower case with '-'
+// separators.
+func normalizeLanguage(lang string) string {
+	return strings.ReplaceAll(strings.ToLower(lang), "_", "-")
+}
+
+// hyphenator returns the hyphenator for lang, or nil.
+func (s *shaperImpl) hyphenator(lang string) *Hyphenator {
+	if len(s.hyphenators) == 0 {
+		return nil
+	}
+	lang = normalizeLanguage(lang)
+	for {
+		if h, ok := s.hyphenators[lang]; ok {
+			return h
+		}
+		i := strings.LastIndexByte(lang, '-')
+		if i == -1 {
+			return nil
+		}
+		lang = lang[:i]
+	}
+}
+
+// hyphenate returns txt with soft hyphens inserted at the hyphenation points
+// of its words, and the indices of the inserted runes in the result.
+func (s *shaperImpl) hyphenate(h *Hyphenator, txt []rune) ([]rune, []int) {
+	out := s.hyphenScratch[:0]
+	var inserted []int
+	for i := 0; i < len(txt); {
+		if !unicode.IsLetter(txt[i]) {
+			out = append(out, txt[i])
+			i++
+			continue
+		}
+		end := i
+		for end < len(txt) && (unicode.IsLetter(txt[end]) || unicode.Is(unicode.Mn, txt[end])) {
+			end++
+		}
+		word := txt[i:end]
+		s.hyphenPoints = h.points(s.hyphenPoints[:0], word)
+		prev := 0
+		for _, p := range s.hyphenPoints {
+			out = append(out, word[prev:p]...)
+			inserted = append(inserted, len(out))
+			out = append(out, softHyphen)
+			prev = p
+		}
+		out = append(out, word[prev:]...)
+		i = end
+	}
+	s.hyphenScratch = out
+	return out, inserted
+}
+
+// insertSpanRunes returns spans extended to cover the runes inserted into
+// their text at the given indices. Runes inserted at the boundary of two
+// spans belong to the first.
+func insertSpanRunes(spans []paragraphSpan, inserted []int) []paragraphSpan {
+	if len(spans) == 0 || len(inserted) == 0 {
+		return spans
+	}
+	spans = append([]paragraphSpan(nil), spans...)
+	start, j := 0, 0
+	for i := range spans {
+		end := start + spans[i].Runes
+		for j < len(inserted) && (inserted[j] <= end || i == len(spans)-1) {
+			spans[i].Runes++
+			end++
+			j++
+		}
+		start = end
+	}
+	return spans
+}
+
+// showHyphens replaces the soft hyphens at the ends of all but the final
+// line with visible hyphens.
+func (s *shaperImpl) showHyphens(lines []line, txt []rune) {
+	for i := 0; i < len(lines)-1; i++ {
+		l := &lines[i]
+		if len(l.runs) == 0 {
+			continue
+		}
+		run := &l.runs[len(l.runs)-1]
+		if run.truncator || run.face == nil || len(run.Glyphs) == 0 || run.Direction.Progression() != system.FromOrigin {
+			continue
+		}
+		g := &run.Glyphs[len(run.Glyphs)-1]
+		if g.clusterIndex >= len(txt) || txt[g.clusterIndex] != softHyphen {
+			continue
+		}
+		gid, ok := run.face.NominalGlyph('-')
+		if !ok {
+			continue
+		}
+		scale := fixedToFloat(run.PPEM) / float32(run.face.Upem())
+		adv := floatToFixed(run.face.HorizontalAdvance(gid) * scale)
+		g.id = newGlyphID(run.PPEM, s.faces.index(run.face.Font), gid)
+		g.bounds = fixed.Rectangle26_6{}
+		if ext, ok := run.face.GlyphExtents(gid); ok {
+			g.bounds.Min = fixed.Point26_6{X: floatToFixed(ext.XBearing * scale), Y: -floatToFixed(ext.YBearing * scale)}
+			g.bounds.Max = g.bounds.Min.Add(fixed.Point26_6{X: floatToFixed(ext.Width * scale), Y: -floatToFixed(ext.Height * scale)})
+		}
+		run.Advance += adv - g.xAdvance
+		l.width += adv - g.xAdvance
+		g.xAdvance = adv
+		positionRuns(l)
+	}
+}
+
+// removeInsertedRunes removes the runes inserted into the text of lines at
+// the given indices from the rune counts of the lines, such that the lines
+// describe the text without them. The glyphs of inserted runes join the
+// clusters preceding them.
+func removeInsertedRunes(lines []line, inserted []int) {
+	// countInserted returns the number of inserted runes in [start, end).
+	countInserted := func(start, end int) int {
+		return sort.SearchInts(inserted, end) - sort.SearchInts(inserted, start)
+	}
+	lineStart := 0
+	for i := range lines {
+		l := &lines[i]
+		runStart := lineStart
+		offset := 0
+		for j := range l.runs {
+			run := &l.runs[j]
+			count := run.Runes.Count
+			if !run.truncator {
+				fixInsertedClusters(run, countInserted)
+				run.Runes.Count -= countInserted(runStart, runStart+count)
+			}
+			runStart += count
+			run.Runes.Offset = offset
+			offset += run.Runes.Count
+		}
+		lineStart = runStart
+		l.runeCount = offset
+	}
+}
+
+// fixInsertedClusters removes the inserted runes from the clusters of run.
+func fixInsertedClusters(run *runLayout, countInserted func(start, end int) int) {
+	rtl := run.Direction.Progression() == system.TowardOrigin
+	for k := range run.Glyphs {
+		gi := k
+		if rtl {
+			// Visit glyphs in logical order.
+			gi = len(run.Glyphs) - 1 - k
+		}
+		g := &run.Glyphs[gi]
+		n := countInserted(g.clusterIndex, g.clusterIndex+g.runeCount)
+		if n == 0 || g.glyphCount == 0 {
+			continue
+		}
+		if n < g.runeCount {
+			// The cluster contains text runes as well.
+			g.runeCount -= n
+			continue
+		}
+		// The cluster is a single inserted rune; join it to the logically
+		// preceding cluster.
+		prev := gi - 1
+		if rtl {
+			prev = gi + 1
+		}
+		if prev < 0 || prev >= len(run.Glyphs) {
+			g.runeCount = 0
+			continue
+		}
+		p := run.Glyphs[prev]
+		for c := range run.Glyphs {
+			if run.Glyphs[c].clusterIndex == p.clusterIndex {
+				run.Glyphs[c].glyphCount++
+			}
+		}
+		g.clusterIndex = p.clusterIndex
+		g.runeCount = p.runeCount
+		g.glyphCount = p.glyphCount + 1
+	}
+}
@@ -0,0 +1,105 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package text
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+
+	"gioui.org/font/opentype"
+)
+
+// knuthPatterns hyphenate "hyphenation" as in The TeXbook.
+const knuthPatterns = "hy3ph he2n hena4 hen5at 1na n2at 1tio 2io o2n"
+
+func TestHyphenator(t *testing.T) {
+	h, err := NewHyphenator(knuthPatterns, "ta-ble")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for word, want := range map[string][]int{
+		"hyphenation": {2, 6},
+		"Hyphenation": {2, 6},
+		"table":       {2},
+		"hyph":        nil,
+	} {
+		if got := h.Hyphenate(word); !reflect.DeepEqual(got, want) {
+			t.Errorf("Hyphenate(%q) = %v, want %v", word, got, want)
+		}
+	}
+	if _, err := NewHyphenator("12", ""); err == nil {
+		t.Error("pattern without letters didn't fail")
+	}
+}
+
+func TestWrapHyphenate(t *testing.T) {
+	h, err := NewHyphenator(knuthPatterns, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	face, _ := opentype.Parse(goregular.TTF)
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: face}}), WithHyphenation("en", h))
+	hyphen, _ := face.Face().NominalGlyph('-')
+	const txt = "a hyphenation hyphenation"
+	for _, policy := range []WrapPolicy{WrapHeuristically, WrapHyphenate} {
+		shaper.LayoutString(Parameters{
+			PxPerEm:    fixed.I(20),
+			MaxWidth:   90,
+			Locale:     english,
+			WrapPolicy: policy,
+		}, txt)
+		runes, hyphens := 0, 0
+		for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+			runes += int(g.Runes)
+			if g.X+g.Advance > fixed.I(90) {
+				t.Errorf("%v: glyph at %v with advance %v exceeds the width", policy, g.X, g.Advance)
+			}
+			if _, _, gid := splitGlyphID(g.ID); gid == hyphen {
+				hyphens++
+				if g.Flags&FlagLineBreak == 0 {
+					t.Errorf("%v: hyphen isn't at the end of a line", policy)
+				}
+			}
+		}
+		if want := len([]rune(txt)); runes != want {
+			t.Errorf("%v: glyphs represent %d runes, want %d", policy, runes, want)
+		}
+		if want := map[WrapPolicy]int{WrapHyphenate: 4}[policy]; hyphens != want {
+			t.Errorf("%v: got %d hyphens, want %d", policy, hyphens, want)
+		}
+	}
+}
+
+func TestWrapHyphenateSpans(t *testing.T) {
+	h, err := NewHyphenator(knuthPatterns, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	face, _ := opentype.Parse(goregular.TTF)
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: face}}), WithHyphenation("en", h))
+	const txt = "hyphenation hyphenation XYZ"
+	spans := []Span{{Runes: 24}, {Runes: 3, BaselineShift: fixed.I(4)}}
+	shaper.LayoutSpans(Parameters{
+		PxPerEm:    fixed.I(20),
+		MaxWidth:   90,
+		Locale:     english,
+		WrapPolicy: WrapHyphenate,
+	}, spans, txt)
+	runes := 0
+	for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+		want := 0
+		if runes >= spans[0].Runes {
+			want = 1
+		}
+		if g.Span != want {
+			t.Errorf("glyph of rune %d (%q) has span %d, want %d", runes, []rune(txt)[runes], g.Span, want)
+		}
+		runes += int(g.Runes)
+	}
+	if want := len([]rune(txt)); runes != want {
+		t.Errorf("glyphs represent %d runes, want %d", runes, want)
+	}
+}
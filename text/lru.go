@@ -3,6 +3,7 @@
 package text
 
 import (
+	"fmt"
 	"image"
 	"sync/atomic"
 
@@ -93,7 +94,7 @@ type glyphLRU[V any] struct {
 
 var seed uint32
 
-// hashGlyphs computes a hash key based on the ID and X offset of
+// hashGlyphs computes a hash key based on the ID and X and Y offsets of
 // every glyph in the slice.
 func (c *glyphLRU[V]) hashGlyphs(gs []Glyph) uint64 {
 	if c.seed == 0 {
@@ -106,7 +107,10 @@ func (c *glyphLRU[V]) hashGlyphs(gs []Glyph) uint64 {
 	h := c.seed
 	firstX := gs[0].X
 	for _, g := range gs {
-		h += uint64(g.X - firstX)
+		h += uint64(g.X-firstX) ^ uint64(g.Offset.Y)<<32
+		if g.Flags&FlagUpright != 0 {
+			h = ^h
+		}
 		h *= 6585573582091643
 		h += uint64(g.ID)
 		h *= 3650802748644053
@@ -131,7 +135,7 @@ func (c *glyphLRU[V]) Put(key uint64, glyphs []Glyph, v V) {
 			firstX = glyph.X
 		}
 		// Cache glyph X offsets relative to the first glyph.
-		gids[i] = glyphInfo{ID: glyph.ID, X: glyph.X - firstX}
+		gids[i] = glyphInfo{ID: glyph.ID, X: glyph.X - firstX, Y: glyph.Offset.Y, Upright: glyph.Flags&FlagUpright != 0}
 	}
 	val := glyphValue[V]{
 		glyphs: gids,
@@ -147,6 +151,12 @@ type bitmapShapeCache = glyphLRU[op.CallOp]
 type glyphInfo struct {
 	ID GlyphID
 	X  fixed.Int26_6
+	// Y is the vertical offset of the glyph, which is non-zero for glyphs
+	// with a baseline shift.
+	Y fixed.Int26_6
+	// Upright is set for glyphs with FlagUpright, whose outlines are
+	// rotated.
+	Upright bool
 }
 
 type layoutKey struct {
@@ -161,6 +171,11 @@ type layoutKey struct {
 	wrapPolicy         WrapPolicy
 	lineHeight         fixed.Int26_6
 	lineHeightScale    float32
+	features           string
+	variations         string
+	tabStops           TabStops
+	// spans encodes the styles of text laid out with spans.
+	spans string
 }
 
 const maxSize = 1000
@@ -175,9 +190,21 @@ func gidsEqual(a []glyphInfo, glyphs []Glyph) bool {
 			firstX = glyphs[i].X
 		}
 		// Cache glyph X offsets relative to the first glyph.
-		if a[i].ID != glyphs[i].ID || a[i].X != (glyphs[i].X-firstX) {
+		if a[i].ID != glyphs[i].ID || a[i].X != (glyphs[i].X-firstX) || a[i].Y != glyphs[i].Offset.Y || a[i].Upright != (glyphs[i].Flags&FlagUpright != 0) {
 			return false
 		}
 	}
 	return true
 }
+
+// spanKey encodes spans for use in a layoutKey.
+func spanKey(spans []paragraphSpan) string {
+	if len(spans) == 0 {
+		return ""
+	}
+	var b []byte
+	for _, sp := range spans {
+		b = fmt.Appendf(b, "%d:%d:%q:%d:%d:%d:%d;", sp.index, sp.Runes, sp.Font.Typeface, sp.Font.Style, sp.Font.Weight, sp.PxPerEm, sp.BaselineShift)
+	}
+	return string(b)
+}
@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package text
+
+import (
+	"math"
+
+	"golang.org/x/image/math/fixed"
+
+	"gioui.org/io/system"
+)
+
+// Measurement describes the dimensions of laid out text.
+type Measurement struct {
+	// Width and Height are the size in pixels of the logical bounds of the
+	// text, as computed for the text displayed by widget.Label.
+	Width, Height int
+	// Baseline is the distance in pixels from the top of the text to the
+	// baseline of its first line.
+	Baseline int
+	// Truncated is the number of runes represented by the truncator, if
+	// the text is truncated.
+	Truncated int
+	// Lines describes each line of the text.
+	Lines []LineMetrics
+}
+
+// LineMetrics describes the dimensions of a line of laid out text.
+type LineMetrics struct {
+	// Runes is the range of the runes displayed on the line, not including
+	// the runes represented by a truncator.
+	Runes Range
+	// X is the offset of the start of the line due to its alignment.
+	X fixed.Int26_6
+	// Width is the width of the line, including the space added to justify
+	// it.
+	Width fixed.Int26_6
+	// Hang is the width of the white space at the logical end of the line,
+	// which is included in Width.
+	Hang fixed.Int26_6
+	// Ascent and Descent are the distances from the baseline to the top and
+	// bottom of the line.
+	Ascent, Descent fixed.Int26_6
+	// Baseline is the distance in pixels from the top of the text to the
+	// baseline of the line.
+	Baseline int
+	// LineHeight is the distance between the baseline of the line and the
+	// baseline of the line before it, or of the last line of the preceding
+	// paragraph. Lines are stacked at distances rounded to whole pixels.
+	LineHeight fixed.Int26_6
+}
+
+// Measure lays out str like LayoutString, and returns its dimensions without
+// affecting the glyphs returned by NextGlyph. Laid out text is cached, such
+// that laying out the measured text again is cheap.
+func (l *Shaper) Measure(params Parameters, str string) Measurement {
+	l.init()
+	l.layoutDocument(&l.scratch, &l.measured, params, nil, str)
+	return l.measured.measure()
+}
+
+// IntrinsicWidths returns the min-content and max-content widths of str laid
+// out with params, that is its width when wrapped at every opportunity and
+// its width without wrapping. Lines are wrapped between words, or between
+// graphemes if the WrapPolicy of params is WrapGraphemes. White space hanging
+// at the end of lines is not included in the widths. The MaxWidth, MinWidth,
+// MaxLines and Alignment of params are ignored.
+func (l *Shaper) IntrinsicWidths(params Parameters, str string) (minContent, maxContent int) {
+	params.MinWidth = 0
+	params.MaxLines = 0
+	params.Alignment = Start
+	params.MaxWidth = math.MaxInt
+	maxContent = contentWidth(l.Measure(params, str))
+	if params.WrapPolicy != WrapGraphemes {
+		params.WrapPolicy = WrapWords
+	}
+	params.MaxWidth = 1
+	minContent = contentWidth(l.Measure(params, str))
+	return minContent, maxContent
+}
+
+// contentWidth returns the width of the widest line of m, not including
+// the white space hanging at its end.
+func contentWidth(m Measurement) int {
+	var width fixed.Int26_6
+	for _, line := range m.Lines {
+		if w := line.Width - line.Hang; w > width {
+			width = w
+		}
+	}
+	return width.Ceil()
+}
+
+// measure returns the dimensions of the document.
+func (d *document) measure() Measurement {
+	var m Measurement
+	if len(d.lines) == 0 {
+		return m
+	}
+	first, last := d.lines[0], d.lines[len(d.lines)-1]
+	top := first.yOffset - first.ascent.Ceil()
+	m.Baseline = first.yOffset - top
+	m.Height = last.yOffset + last.descent.Ceil() - top
+	var minX, maxX fixed.Int26_6
+	runes := 0
+	for i, line := range d.lines {
+		x := d.alignment.Align(line.direction, line.width, d.alignWidth)
+		extra, mode, _ := line.justification(d.alignment, d.alignWidth)
+		if mode != 0 && line.direction.Progression() == system.TowardOrigin {
+			x = -line.hang
+		}
+		lm := LineMetrics{
+			Runes:      Range{Offset: runes},
+			X:          x,
+			Width:      line.width + extra,
+			Hang:       line.hang,
+			Ascent:     line.ascent,
+			Descent:    line.descent,
+			Baseline:   line.yOffset - top,
+			LineHeight: line.lineHeight,
+		}
+		for _, run := range line.runs {
+			if run.truncator {
+				m.Truncated = run.Runes.Count + d.unreadRuneCount
+				continue
+			}
+			lm.Runes.Count += run.Runes.Count
+		}
+		runes += lm.Runes.Count
+		if i == 0 || x < minX {
+			minX = x
+		}
+		if end := x + lm.Width; i == 0 || end > maxX {
+			maxX = end
+		}
+		m.Lines = append(m.Lines, lm)
+	}
+	m.Width = maxX.Ceil() - minX.Floor()
+	return m
+}
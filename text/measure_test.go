@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package text
+
+import (
+	"image"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+
+	"gioui.org/font/opentype"
+)
+
+func TestMeasure(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: ltrFace}}))
+	const txt = "The quick brown fox\njumps over the lazy dog."
+	for _, align := range []Alignment{Start, Middle, End, Justify} {
+		params := Parameters{
+			PxPerEm:   fixed.I(16),
+			MaxWidth:  120,
+			MinWidth:  120,
+			Locale:    english,
+			Alignment: align,
+		}
+		shaper.LayoutString(params, txt)
+		var bounds image.Rectangle
+		baseline, lines := 0, 0
+		for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+			r := image.Rect(g.X.Floor(), int(g.Y)-g.Ascent.Ceil(), (g.X + g.Advance).Ceil(), int(g.Y)+g.Descent.Ceil())
+			if bounds.Empty() {
+				bounds, baseline = r, int(g.Y)
+			}
+			bounds = bounds.Union(r)
+			if g.Flags&FlagLineBreak != 0 {
+				lines++
+			}
+		}
+		m := shaper.Measure(params, txt)
+		if got, want := image.Pt(m.Width, m.Height), bounds.Size(); got != want {
+			t.Errorf("%v: got size %v, want %v", align, got, want)
+		}
+		if got, want := m.Baseline, baseline-bounds.Min.Y; got != want {
+			t.Errorf("%v: got baseline %d, want %d", align, got, want)
+		}
+		if len(m.Lines) != lines {
+			t.Fatalf("%v: got %d lines, want %d", align, len(m.Lines), lines)
+		}
+		runes := 0
+		for _, l := range m.Lines {
+			if l.Runes.Offset != runes {
+				t.Errorf("%v: line starts at rune %d, want %d", align, l.Runes.Offset, runes)
+			}
+			runes += l.Runes.Count
+		}
+		if want := len([]rune(txt)); runes != want {
+			t.Errorf("%v: lines have %d runes, want %d", align, runes, want)
+		}
+	}
+}
+
+func TestMeasureKeepsIteration(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: ltrFace}}))
+	params := Parameters{
+		PxPerEm:  fixed.I(16),
+		MaxWidth: 1000,
+		Locale:   english,
+	}
+	shaper.LayoutString(params, "abc")
+	var want []Glyph
+	for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+		want = append(want, g)
+	}
+	shaper.LayoutString(params, "abc")
+	first, _ := shaper.NextGlyph()
+	shaper.Measure(params, "other text")
+	got := []Glyph{first}
+	for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+		got = append(got, g)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d glyphs after measuring, want %d", len(got), len(want))
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("glyph %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestIntrinsicWidths(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: ltrFace}}))
+	params := Parameters{
+		PxPerEm:  fixed.I(16),
+		MaxWidth: 10,
+		MaxLines: 1,
+		Locale:   english,
+	}
+	minContent, maxContent := shaper.IntrinsicWidths(params, "aa bbbbbb cc")
+	params.MaxWidth, params.MaxLines = 1000, 0
+	if want := shaper.Measure(params, "bbbbbb").Width; minContent != want {
+		t.Errorf("got min-content width %d, want %d", minContent, want)
+	}
+	if want := shaper.Measure(params, "aa bbbbbb cc").Width; maxContent != want {
+		t.Errorf("got max-content width %d, want %d", maxContent, want)
+	}
+	params.WrapPolicy = WrapGraphemes
+	if minContent, _ := shaper.IntrinsicWidths(params, "aa bbbbbb cc"); minContent >= shaper.Measure(params, "bb").Width {
+		t.Errorf("got min-content width %d, want less than two graphemes", minContent)
+	}
+}
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package text
+
+// Paragraph is text laid out by [Shaper.Paragraph]. A Paragraph is
+// immutable and its methods are safe for concurrent use, such that text can
+// be laid out in advance by other goroutines and displayed many times.
+type Paragraph struct {
+	doc document
+}
+
+// GlyphIterator iterates over the glyphs of a Paragraph.
+type GlyphIterator struct {
+	iter glyphIter
+}
+
+// Paragraph lays out str like LayoutString, and returns the laid out text.
+// Unlike LayoutString, Paragraph does not affect the glyphs returned by
+// NextGlyph and may be called from any goroutine. Concurrent layouts share
+// the caches of the Shaper, such that text laid out in advance is cheap to
+// lay out again for display.
+func (l *Shaper) Paragraph(params Parameters, str string) *Paragraph {
+	return l.ParagraphSpans(params, nil, str)
+}
+
+// ParagraphSpans is like Paragraph, but styles the text with a sequence of
+// spans as described by [Shaper.LayoutSpans].
+func (l *Shaper) ParagraphSpans(params Parameters, spans []Span, str string) *Paragraph {
+	l.init()
+	p := new(Paragraph)
+	scratch := layoutScratch{spans: spans}
+	l.layoutDocument(&scratch, &p.doc, params, nil, str)
+	return p
+}
+
+// Glyphs returns an iterator over the glyphs of the paragraph, in the order
+// returned by [Shaper.NextGlyph].
+func (p *Paragraph) Glyphs() GlyphIterator {
+	return GlyphIterator{iter: glyphIter{doc: &p.doc}}
+}
+
+// Measure returns the dimensions of the paragraph and its lines.
+func (p *Paragraph) Measure() Measurement {
+	return p.doc.measure()
+}
+
+// Runes returns the number of runes in the paragraph, including the runes
+// represented by a truncator.
+func (p *Paragraph) Runes() int {
+	n := p.doc.unreadRuneCount
+	for _, line := range p.doc.lines {
+		for _, run := range line.runs {
+			n += run.Runes.Count
+		}
+	}
+	return n
+}
+
+// Next returns the next glyph of the paragraph. If there are no more
+// glyphs, ok will be false.
+func (it *GlyphIterator) Next() (_ Glyph, ok bool) {
+	return it.iter.next()
+}
@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package text
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+
+	"gioui.org/font/opentype"
+)
+
+func TestParagraphConcurrent(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: ltrFace}}))
+	params := Parameters{
+		PxPerEm:  fixed.I(16),
+		MaxWidth: 1000,
+		Locale:   english,
+	}
+	texts := make([]string, 8)
+	for i := range texts {
+		texts[i] = strings.Repeat(fmt.Sprintf("paragraph %d of the document\n", i), i+1)
+	}
+	paragraphs := make([]*Paragraph, len(texts))
+	var wg sync.WaitGroup
+	for i := range texts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			paragraphs[i] = shaper.Paragraph(params, texts[i])
+		}(i)
+	}
+	wg.Wait()
+	for i, p := range paragraphs {
+		shaper.LayoutString(params, texts[i])
+		var want []Glyph
+		for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+			want = append(want, g)
+		}
+		// Iterate more than once.
+		for j := 0; j < 2; j++ {
+			it := p.Glyphs()
+			var got []Glyph
+			for g, ok := it.Next(); ok; g, ok = it.Next() {
+				got = append(got, g)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("paragraph %d: got %d glyphs, want %d", i, len(got), len(want))
+			}
+			for k := range got {
+				if got[k] != want[k] {
+					t.Errorf("paragraph %d: glyph %d: got %v, want %v", i, k, got[k], want[k])
+				}
+			}
+		}
+		if got, want := p.Runes(), len([]rune(texts[i])); got != want {
+			t.Errorf("paragraph %d: got %d runes, want %d", i, got, want)
+		}
+		if got, want := len(p.Measure().Lines), i+1; got != want {
+			t.Errorf("paragraph %d: got %d lines, want %d", i, got, want)
+		}
+	}
+}
+
+func TestParagraphKeepsIteration(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: ltrFace}}))
+	params := Parameters{
+		PxPerEm:  fixed.I(16),
+		MaxWidth: 1000,
+		Locale:   english,
+	}
+	shaper.LayoutString(params, "ab")
+	first, _ := shaper.NextGlyph()
+	shaper.Paragraph(params, "other text")
+	second, ok := shaper.NextGlyph()
+	if !ok || second.X <= first.X {
+		t.Errorf("laying out a paragraph disturbed the iteration of the shaper")
+	}
+	if _, ok := shaper.NextGlyph(); ok {
+		t.Errorf("got more than 2 glyphs")
+	}
+}
+
+func TestParagraphParallel(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	blocked, release := make(chan struct{}), make(chan struct{})
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: ltrFace}}), WithMissingGlyphs(func([]rune) {
+		close(blocked)
+		<-release
+	}))
+	params := Parameters{
+		PxPerEm:  fixed.I(16),
+		MaxWidth: 1000,
+		Locale:   english,
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// The face has no glyph for the rune, blocking the layout in
+		// WithMissingGlyphs.
+		shaper.Paragraph(params, "missing \u4e16")
+	}()
+	<-blocked
+	// Other layouts proceed while shaping is in progress.
+	laidOut := make(chan *Paragraph)
+	go func() {
+		laidOut <- shaper.Paragraph(params, "other text")
+	}()
+	select {
+	case p := <-laidOut:
+		if p.Runes() != len("other text") {
+			t.Errorf("got %d runes, want %d", p.Runes(), len("other text"))
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("layout blocked by a concurrent layout")
+	}
+	close(release)
+	<-done
+}
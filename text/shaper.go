@@ -6,6 +6,7 @@ import (
 	"bufio"
 	"io"
 	"strings"
+	"sync"
 	"unicode/utf8"
 
 	giofont "gioui.org/font"
@@ -35,6 +36,12 @@ const (
 	// breaking any word across lines on UAX#29 grapheme cluster boundaries to maximize the number of
 	// grapheme clusters on each line.
 	WrapGraphemes
+	// WrapHyphenate is like WrapHeuristically, but also breaks words at
+	// their hyphenation points, displaying a hyphen at the end of the line.
+	// Words are hyphenated with the Hyphenator configured by WithHyphenation
+	// for the language of the text, and at soft hyphens (U+00AD) in the
+	// text. Only left-to-right text is hyphenated.
+	WrapHyphenate
 )
 
 // Parameters are static text shaping attributes applied to the entire shaped text.
@@ -60,8 +67,40 @@ type Parameters struct {
 	// for the shaped text.
 	MinWidth, MaxWidth int
 	// Locale provides primary direction and language information for the shaped text.
+	// Text with a vertical direction such as [system.TTB] is laid out as
+	// if it were horizontal, with MaxWidth limiting the length of its
+	// columns. It must be rotated 90 degrees clockwise for display, such
+	// that the first column is on the right; see [FlagUpright].
 	Locale system.Locale
 
+	// Features is a list of OpenType features to enable or disable when shaping,
+	// separated by commas or spaces, such as "tnum, zero, -liga, ss01". A
+	// feature is enabled by its four letter tag, optionally prefixed by '+',
+	// disabled by a '-' prefix, and set to a particular value with
+	// "tag=value". Features not in the list use the defaults of the font.
+	Features string
+
+	// Variations is a list of values for the variation axes of variable fonts,
+	// separated by commas or spaces, such as "wdth=75, opsz=12, slnt=-10".
+	// Values are in the design units of the axis and are clamped to its range.
+	// Unless set by Variations, the "wght" axis follows the weight of the font.
+	// Axes not in the list, and not supported by a font, are ignored.
+	//
+	// Values are rounded to 1/64 of the range between the default and the
+	// extremes of each axis, and every distinct set of values for a font
+	// creates an instance of it in the Shaper. The Shaper keeps a limited
+	// number of instances, and evicting instances clears its caches, so
+	// continuously animating variations is costly. Glyphs laid out with an
+	// evicted instance, such as those of a Paragraph, are no longer drawn and
+	// must be laid out again.
+	Variations string
+
+	// TabStops are the positions that text following tab characters is
+	// aligned to in left-to-right lines. Tab stops are applied after the
+	// text is wrapped into lines, so lines with tabs may exceed MaxWidth.
+	// The zero value disables tab stops.
+	TabStops TabStops
+
 	// LineHeightScale is a scaling factor applied to the LineHeight of a paragraph. If zero, a default
 	// value of 1.2 will be used.
 	LineHeightScale float32
@@ -78,6 +117,21 @@ type Parameters struct {
 	forceTruncate bool
 }
 
+// Span describes the style of a run of text laid out by [Shaper.LayoutSpans].
+type Span struct {
+	// Runes is the number of runes of text covered by the span.
+	Runes int
+	// Font describes the preferred typeface of the span.
+	Font giofont.Font
+	// PxPerEm is the pixels-per-em to shape the span with. If zero,
+	// the PxPerEm of the Parameters is used.
+	PxPerEm fixed.Int26_6
+	// BaselineShift moves the glyphs of the span above the baseline if
+	// positive, or below it if negative, such as for superscripts and
+	// subscripts.
+	BaselineShift fixed.Int26_6
+}
+
 type FontFace = giofont.FontFace
 
 // Glyph describes a shaped font glyph. Many fields are distances relative
@@ -124,6 +178,9 @@ type Glyph struct {
 	Runes uint16
 	// Flags encode special properties of this glyph.
 	Flags Flags
+	// Span is the index of the span containing this glyph, for text laid
+	// out with LayoutSpans. It is zero for other text.
+	Span int
 }
 
 type Flags uint16
@@ -155,6 +212,13 @@ const (
 	// FlagTruncator and FlagClusterBreak will have a Runes field accounting for all
 	// runes truncated.
 	FlagTruncator
+	// FlagUpright is set for glyphs of vertical text that are displayed
+	// upright. The text is laid out as if it were horizontal and must be
+	// rotated 90 degrees clockwise for display, as described by
+	// [system.TTB]. The outlines of upright glyphs are rotated 90 degrees
+	// counter-clockwise to cancel out the rotation of the text, and their
+	// Offset and Bounds are in the coordinates of the text.
+	FlagUpright
 )
 
 func (f Flags) String() string {
@@ -194,46 +258,83 @@ func (f Flags) String() string {
 	} else {
 		b.WriteString("_")
 	}
+	if f&FlagUpright != 0 {
+		b.WriteString("U")
+	} else {
+		b.WriteString("_")
+	}
 	return b.String()
 }
 
 type GlyphID uint64
 
-// Shaper converts strings of text into glyphs that can be displayed. The same
-// Shaper should not be used in different goroutines.
-//
-// The Shaper controls text layout and has a cache, implemented as a map, and
-// so laying out text in two different goroutines can easily result in
-// concurrent access to said map, resulting in a panic.
+// Shaper converts strings of text into glyphs that can be displayed.
 //
-// Practically speaking, this means you should use different Themes (with
-// different Shapers) for different top-level windows.
+// The Layout methods, NextGlyph and Measure share the state of the text
+// being iterated, and must not be called from different goroutines. The
+// other methods are safe for concurrent use, and share the caches of the
+// Shaper: use Paragraph to lay out text in other goroutines.
 type Shaper struct {
 	config struct {
 		disableSystemFonts bool
 		collection         []FontFace
+		hyphenators        map[string]*Hyphenator
+		fallbacks          []Fallback
+		missingGlyphs      func(missing []rune)
 	}
-	initialized      bool
-	shaper           shaperImpl
+	initOnce sync.Once
+
+	// faces are the faces shared by the shaper implementations.
+	faces *faceRegistry
+	// mu guards the idle shaper implementations and the caches, which are
+	// shared by concurrent layouts.
+	mu sync.Mutex
+	// idle are the shaper implementations not in use by a layout. Every
+	// concurrent layout shapes text with its own implementation.
+	idle             []*shaperImpl
 	pathCache        pathCache
 	bitmapShapeCache bitmapShapeCache
+	bitmapGlyphCache bitmapCache
 	layoutCache      layoutCache
+	// evictions is the number of evicted font instances when the caches
+	// were last validated.
+	evictions uint64
+
+	scratch layoutScratch
+	txt     document
+	// measured is the document laid out by Measure.
+	measured document
+	iter     glyphIter
+}
 
+// layoutScratch is the memory used while laying out a document.
+type layoutScratch struct {
 	reader    *bufio.Reader
 	paragraph []byte
 
-	// Iterator state.
-	brokeParagraph   bool
-	pararagraphStart Glyph
-	txt              document
-	line             int
-	run              int
-	glyph            int
+	// spans are the spans of the text being laid out by LayoutSpans.
+	spans []Span
+	// spanIdx and spanOff are the index of the span and the number of its
+	// runes covered by the paragraphs laid out so far.
+	spanIdx, spanOff int
+	paraSpans        []paragraphSpan
+}
+
+// glyphIter iterates over the glyphs of a document.
+type glyphIter struct {
+	doc            *document
+	brokeParagraph bool
+	paragraphStart Glyph
+	line           int
+	run            int
+	glyph          int
 	// advance is the width of glyphs from the current run that have already been displayed.
 	advance fixed.Int26_6
+	// stretched is the number of glyphs from the current run that have
+	// already been displayed and stretched to justify the line.
+	stretched int
 	// done tracks whether iteration is over.
 	done bool
-	err  error
 }
 
 // ShaperOptions configure text shapers.
@@ -253,6 +354,51 @@ func WithCollection(collection []FontFace) ShaperOption {
 	}
 }
 
+// WithHyphenation configures the shaper to hyphenate text in a language
+// when wrapping with WrapHyphenate. The language is a BCP 47 tag such as
+// "de" or "en-US", and applies to text whose locale language has the tag
+// as a prefix, unless a more specific tag is configured.
+func WithHyphenation(language string, h *Hyphenator) ShaperOption {
+	return func(s *Shaper) {
+		if s.config.hyphenators == nil {
+			s.config.hyphenators = make(map[string]*Hyphenator)
+		}
+		s.config.hyphenators[normalizeLanguage(language)] = h
+	}
+}
+
+// WithFallbacks configures the typefaces preferred for runes that the
+// typeface of the text has no glyphs for, by the language of the text and
+// the script of the runes. For example, Japanese and simplified Chinese text
+// may prefer different faces for Han characters:
+//
+//	WithFallbacks(
+//		Fallback{Language: "ja", Script: "Hani", Typeface: "Noto Sans JP"},
+//		Fallback{Language: "zh-Hans", Script: "Hani", Typeface: "Noto Sans SC"},
+//	)
+//
+// The families of every fallback matching a rune are tried in the order of
+// the fallbacks, after the families of the text's typeface and before the
+// fonts of the system.
+func WithFallbacks(fallbacks ...Fallback) ShaperOption {
+	return func(s *Shaper) {
+		s.config.fallbacks = append(s.config.fallbacks, fallbacks...)
+	}
+}
+
+// WithMissingGlyphs configures a function called with the runes of laid out
+// text that are displayed with the missing glyph of a font, or with no font
+// at all, because no font has glyphs for them. The runes are also logged
+// if text debugging is enabled. Because laid out text is cached, f is called
+// only when text is shaped, and the missing slice is only valid during the
+// call. f is called by the goroutine laying out the text, and may be called
+// concurrently by layouts in other goroutines.
+func WithMissingGlyphs(f func(missing []rune)) ShaperOption {
+	return func(s *Shaper) {
+		s.config.missingGlyphs = f
+	}
+}
+
 // NewShaper constructs a shaper with the provided options.
 //
 // NewShaper must be called after [app.NewWindow], unless the [NoSystemFonts]
@@ -268,12 +414,39 @@ func NewShaper(options ...ShaperOption) *Shaper {
 }
 
 func (l *Shaper) init() {
-	if l.initialized {
-		return
+	l.initOnce.Do(func() {
+		l.faces = newFaceRegistry(!l.config.disableSystemFonts, l.config.collection)
+	})
+}
+
+// validateCaches clears the caches if font instances were evicted since the
+// last call, because the glyphs of evicted instances are no longer drawn,
+// and their face indices may eventually be reused. It returns the number of
+// evictions. The caller must hold l.mu.
+func (l *Shaper) validateCaches() uint64 {
+	if n := l.faces.evicted(); n != l.evictions {
+		l.evictions = n
+		l.pathCache = pathCache{}
+		l.bitmapShapeCache = bitmapShapeCache{}
+		l.bitmapGlyphCache = bitmapCache{}
+		l.layoutCache = layoutCache{}
 	}
-	l.initialized = true
-	l.reader = bufio.NewReader(nil)
-	l.shaper = *newShaperImpl(!l.config.disableSystemFonts, l.config.collection)
+	return l.evictions
+}
+
+// acquireShaper returns an idle shaper implementation, or a new one if all
+// are in use. The caller must hold l.mu.
+func (l *Shaper) acquireShaper() *shaperImpl {
+	if n := len(l.idle); n > 0 {
+		s := l.idle[n-1]
+		l.idle = l.idle[:n-1]
+		return s
+	}
+	s := l.faces.newShaperImpl()
+	s.hyphenators = l.config.hyphenators
+	s.setFallbacks(l.config.fallbacks)
+	s.missingGlyphs = l.config.missingGlyphs
+	return s
 }
 
 // Layout text from an io.Reader according to a set of options. Results can be retrieved by
@@ -289,46 +462,66 @@ func (l *Shaper) LayoutString(params Parameters, str string) {
 	l.layoutText(params, nil, str)
 }
 
-func (l *Shaper) reset(align Alignment) {
-	l.line, l.run, l.glyph, l.advance = 0, 0, 0, 0
-	l.done = false
-	l.txt.reset()
-	l.txt.alignment = align
+// LayoutSpans is like LayoutString, but styles the text with a sequence of
+// spans. The Font and PxPerEm of params are replaced by the style of each
+// span, and the Span field of every glyph is set to the index of the span
+// containing it. The final span extends to the end of str, and text without
+// spans is laid out as by LayoutString.
+//
+// The glyphs of a truncator are shaped with the Font and PxPerEm of params.
+func (l *Shaper) LayoutSpans(params Parameters, spans []Span, str string) {
+	l.init()
+	l.scratch.spans = spans
+	l.scratch.spanIdx, l.scratch.spanOff = 0, 0
+	l.layoutText(params, nil, str)
+	l.scratch.spans = nil
 }
 
-// layoutText lays out a large text document by breaking it into paragraphs and laying
-// out each of them separately. This allows the shaping results to be cached independently
-// by paragraph. Only one of txt and str should be provided.
+// layoutText lays out text for iteration by NextGlyph.
 func (l *Shaper) layoutText(params Parameters, txt io.Reader, str string) {
-	l.reset(params.Alignment)
+	l.iter = glyphIter{doc: &l.txt}
+	l.layoutDocument(&l.scratch, &l.txt, params, txt, str)
+}
+
+// layoutDocument lays out a large text document into doc by breaking it into paragraphs and laying
+// out each of them separately. This allows the shaping results to be cached independently
+// by paragraph. Only one of txt and str should be provided. The memory of s is used while
+// laying out the document.
+func (l *Shaper) layoutDocument(s *layoutScratch, doc *document, params Parameters, txt io.Reader, str string) {
+	doc.reset()
+	doc.alignment = params.Alignment
 	if txt == nil && len(str) == 0 {
-		l.txt.append(l.layoutParagraph(params, "", nil))
+		doc.append(l.layoutParagraph(params, s.paragraphSpans(0), "", nil))
 		return
 	}
-	l.reader.Reset(txt)
+	if s.reader == nil {
+		s.reader = bufio.NewReader(txt)
+	} else {
+		s.reader.Reset(txt)
+	}
 	truncating := params.MaxLines > 0
 	var done bool
 	var endByte int
 	for !done {
-		l.paragraph = l.paragraph[:0]
+		s.paragraph = s.paragraph[:0]
 		if txt != nil {
 			for {
-				b, err := l.reader.ReadByte()
+				b, err := s.reader.ReadByte()
 				if err != nil {
 					// EOF or any other error ends processing here.
 					done = true
 					break
 				}
-				l.paragraph = append(l.paragraph, b)
+				s.paragraph = append(s.paragraph, b)
 				if b == '\n' {
 					break
 				}
 			}
 			if !done {
-				_, re := l.reader.ReadByte()
+				_, re := s.reader.ReadByte()
 				done = re != nil
 				if !done {
-					_ = l.reader.UnreadByte()
+					_ = s.reader.UnreadByte()
 				}
 			}
 		} else {
@@ -341,9 +534,17 @@ func (l *Shaper) layoutText(params Parameters, txt io.Reader, str string) {
 				done = endByte == len(str)
 			}
 		}
-		if len(str[:endByte]) > 0 || (len(l.paragraph) > 0 || len(l.txt.lines) == 0) {
+		if len(str[:endByte]) > 0 || (len(s.paragraph) > 0 || len(doc.lines) == 0) {
 			params.forceTruncate = truncating && !done
-			lines := l.layoutParagraph(params, str[:endByte], l.paragraph)
+			var spans []paragraphSpan
+			if len(s.spans) > 0 {
+				n := utf8.RuneCount(s.paragraph)
+				if txt == nil {
+					n = utf8.RuneCountInString(str[:endByte])
+				}
+				spans = s.paragraphSpans(n)
+			}
+			lines := l.layoutParagraph(params, spans, str[:endByte], s.paragraph)
 			if truncating {
 				params.MaxLines -= len(lines.lines)
 				if params.MaxLines == 0 {
@@ -355,17 +556,17 @@ func (l *Shaper) layoutText(params Parameters, txt io.Reader, str string) {
 						unreadRunes = utf8.RuneCountInString(str[endByte:])
 					} else {
 						for {
-							_, _, e := l.reader.ReadRune()
+							_, _, e := s.reader.ReadRune()
 							if e != nil {
 								break
 							}
 							unreadRunes++
 						}
 					}
-					l.txt.unreadRuneCount = unreadRunes
+					doc.unreadRuneCount = unreadRunes
 				}
 			}
-			l.txt.append(lines)
+			doc.append(lines)
 		}
 		if done {
 			return
@@ -374,10 +575,40 @@ func (l *Shaper) layoutText(params Parameters, txt io.Reader, str string) {
 	}
 }
 
+// paragraphSpans returns the spans of the next paragraph of n runes, clipped
+// to the paragraph. It returns nil if the text has no spans.
+func (s *layoutScratch) paragraphSpans(n int) []paragraphSpan {
+	if len(s.spans) == 0 {
+		return nil
+	}
+	s.paraSpans = s.paraSpans[:0]
+	for {
+		if n == 0 && len(s.paraSpans) > 0 {
+			return s.paraSpans
+		}
+		sp := s.spans[s.spanIdx]
+		avail := max(sp.Runes-s.spanOff, 0)
+		if s.spanIdx == len(s.spans)-1 || avail > n {
+			// The span covers the rest of the paragraph.
+			sp.Runes = n
+			s.spanOff += n
+			s.paraSpans = append(s.paraSpans, paragraphSpan{Span: sp, index: s.spanIdx})
+			return s.paraSpans
+		}
+		if avail > 0 {
+			sp.Runes = avail
+			s.paraSpans = append(s.paraSpans, paragraphSpan{Span: sp, index: s.spanIdx})
+			n -= avail
+		}
+		s.spanIdx++
+		s.spanOff = 0
+	}
+}
+
 // layoutParagraph shapes and wraps a paragraph using the provided parameters.
 // It accepts the paragraph data in either string or rune format, preferring the
 // string in order to hit the shaper cache more quickly.
-func (l *Shaper) layoutParagraph(params Parameters, asStr string, asBytes []byte) document {
+func (l *Shaper) layoutParagraph(params Parameters, spans []paragraphSpan, asStr string, asBytes []byte) document {
 	if l == nil {
 		return document{}
 	}
@@ -398,12 +629,29 @@ func (l *Shaper) layoutParagraph(params Parameters, asStr string, asBytes []byte
 		str:             asStr,
 		lineHeight:      params.LineHeight,
 		lineHeightScale: params.LineHeightScale,
+		features:        params.Features,
+		variations:      params.Variations,
+		tabStops:        params.TabStops,
+		spans:           spanKey(spans),
 	}
-	if l, ok := l.layoutCache.Get(lk); ok {
-		return l
+	l.mu.Lock()
+	evictions := l.validateCaches()
+	if doc, ok := l.layoutCache.Get(lk); ok {
+		l.mu.Unlock()
+		return doc
+	}
+	s := l.acquireShaper()
+	l.mu.Unlock()
+	// Shape without holding the lock, such that layouts in other
+	// goroutines proceed concurrently.
+	lines := s.layoutRunes(params, spans, []rune(asStr))
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.idle = append(l.idle, s)
+	// Don't cache glyphs whose instances may have been evicted meanwhile.
+	if l.validateCaches() == evictions {
+		l.layoutCache.Put(lk, lines)
 	}
-	lines := l.shaper.LayoutRunes(params, []rune(asStr))
-	l.layoutCache.Put(lk, lines)
 	return lines
 }
 
@@ -411,28 +659,36 @@ func (l *Shaper) layoutParagraph(params Parameters, asStr string, asBytes []byte
 // any. If there are no more glyphs, ok will be false.
 func (l *Shaper) NextGlyph() (_ Glyph, ok bool) {
 	l.init()
-	if l.done {
+	return l.iter.next()
+}
+
+// next returns the next glyph of the document, if any.
+func (l *glyphIter) next() (_ Glyph, ok bool) {
+	if l.doc == nil || l.done {
 		return Glyph{}, false
 	}
 	for {
-		if l.line == len(l.txt.lines) {
+		if l.line == len(l.doc.lines) {
 			if l.brokeParagraph {
 				l.brokeParagraph = false
-				return l.pararagraphStart, true
-			}
-			if l.err == nil {
-				l.err = io.EOF
+				return l.paragraphStart, true
 			}
 			return Glyph{}, false
 		}
-		line := l.txt.lines[l.line]
+		line := l.doc.lines[l.line]
 		if l.run == len(line.runs) {
 			l.line++
 			l.run = 0
 			continue
 		}
 		run := line.runs[l.run]
-		align := l.txt.alignment.Align(line.direction, line.width, l.txt.alignWidth)
+		align := l.doc.alignment.Align(line.direction, line.width, l.doc.alignWidth)
+		extra, mode, stretched := line.justification(l.doc.alignment, l.doc.alignWidth)
+		if mode != 0 && line.direction.Progression() == system.TowardOrigin {
+			// Align the content, not the whitespace hanging to its left, with
+			// the start.
+			align = -line.hang
+		}
 		if l.line == 0 && l.run == 0 && len(run.Glyphs) == 0 {
 			// The very first run is empty, which will only happen when the
 			// entire text is a shaped empty string. Return a single synthetic
@@ -445,12 +701,14 @@ func (l *Shaper) NextGlyph() (_ Glyph, ok bool) {
 				Flags:   FlagLineBreak | FlagClusterBreak | FlagRunBreak,
 				Ascent:  line.ascent,
 				Descent: line.descent,
+				Span:    run.span,
 			}, true
 		}
 		if l.glyph == len(run.Glyphs) {
 			l.run++
 			l.glyph = 0
 			l.advance = 0
+			l.stretched = 0
 			continue
 		}
 		glyphIdx := l.glyph
@@ -483,10 +741,36 @@ func (l *Shaper) NextGlyph() (_ Glyph, ok bool) {
 				Y: g.yOffset,
 			},
 			Bounds: g.bounds,
+			Span:   run.span,
+		}
+		if mode != 0 {
+			// Offset the glyph by the stretching of the glyphs visually
+			// before it.
+			before, inRun := run.wordsBefore, run.words
+			if mode == justifyChar {
+				before, inRun = run.charsBefore, run.chars
+			}
+			if g.justify&mode != 0 {
+				l.stretched++
+			}
+			if rtl {
+				before += inRun - l.stretched
+			} else if g.justify&mode != 0 {
+				before += l.stretched - 1
+			} else {
+				before += l.stretched
+			}
+			glyph.X += justifyOffset(extra, before, stretched)
+			if g.justify&mode != 0 {
+				glyph.Advance += justifyOffset(extra, before+1, stretched) - justifyOffset(extra, before, stretched)
+			}
 		}
 		if run.truncator {
 			glyph.Flags |= FlagTruncator
 		}
+		if run.upright {
+			glyph.Flags |= FlagUpright
+		}
 		l.glyph++
 		if !rtl {
 			l.advance += g.xAdvance
@@ -500,7 +784,7 @@ func (l *Shaper) NextGlyph() (_ Glyph, ok bool) {
 		if endOfLine {
 			glyph.Flags |= FlagLineBreak
 		}
-		endOfText := endOfLine && l.line == len(l.txt.lines)-1
+		endOfText := endOfLine && l.line == len(l.doc.lines)-1
 		nextGlyph := l.glyph
 		if rtl {
 			nextGlyph = len(run.Glyphs) - 1 - nextGlyph
@@ -513,7 +797,7 @@ func (l *Shaper) NextGlyph() (_ Glyph, ok bool) {
 		if endOfCluster {
 			glyph.Flags |= FlagClusterBreak
 			if run.truncator {
-				glyph.Runes += uint16(l.txt.unreadRuneCount)
+				glyph.Runes += uint16(l.doc.unreadRuneCount)
 			}
 		} else {
 			glyph.Runes = 0
@@ -529,17 +813,18 @@ func (l *Shaper) NextGlyph() (_ Glyph, ok bool) {
 			glyph.Flags |= FlagParagraphBreak
 			l.brokeParagraph = true
 			if endOfText {
-				l.pararagraphStart = Glyph{
+				l.paragraphStart = Glyph{
 					Ascent:  glyph.Ascent,
 					Descent: glyph.Descent,
 					Flags:   FlagParagraphStart | FlagLineBreak | FlagRunBreak | FlagClusterBreak,
+					Span:    glyph.Span,
 				}
 				// If a glyph is both a paragraph break and the final glyph, it's a newline
 				// at the end of the text. We must inform widgets like the text editor
 				// of a valid cursor position they can use for "after" such a newline,
 				// taking text alignment into account.
-				l.pararagraphStart.X = l.txt.alignment.Align(line.direction, 0, l.txt.alignWidth)
-				l.pararagraphStart.Y = glyph.Y + int32((glyph.Ascent + glyph.Descent).Ceil())
+				l.paragraphStart.X = l.doc.alignment.Align(line.direction, 0, l.doc.alignWidth)
+				l.paragraphStart.Y = glyph.Y + int32((glyph.Ascent + glyph.Descent).Ceil())
 			}
 		}
 		return glyph, true
@@ -577,35 +862,64 @@ func splitGlyphID(g GlyphID) (fixed.Int26_6, int, font.GID) {
 	return ppem, faceIdx, gid
 }
 
+// GlyphFont returns the font of the face of glyphs with the given ID, such
+// as the face chosen by font fallback for the runs of text that the font of
+// the text has no glyphs for. It returns false if the ID is not from the
+// shaper.
+func (l *Shaper) GlyphFont(id GlyphID) (giofont.Font, bool) {
+	l.init()
+	_, faceIdx, _ := splitGlyphID(id)
+	return l.faces.meta(faceIdx)
+}
+
 // Shape converts the provided glyphs into a path. The path will enclose the forms
 // of all vector glyphs.
 // All glyphs are expected to be from a single line of text (their Y offsets are ignored).
 func (l *Shaper) Shape(gs []Glyph) clip.PathSpec {
 	l.init()
+	l.mu.Lock()
+	evictions := l.validateCaches()
 	key := l.pathCache.hashGlyphs(gs)
 	shape, ok := l.pathCache.Get(key, gs)
+	l.mu.Unlock()
 	if ok {
 		return shape
 	}
 	pathOps := new(op.Ops)
-	shape = l.shaper.Shape(pathOps, gs)
-	l.pathCache.Put(key, gs, shape)
+	shape = l.faces.Shape(pathOps, gs)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.validateCaches() == evictions {
+		l.pathCache.Put(key, gs, shape)
+	}
 	return shape
 }
 
+// DecorationLine returns the vertical position of the top of the decoration
+// line d relative to the dot of g, and the thickness of the line, from the
+// metrics of the font of g. Positive positions are below the baseline. d
+// must be a single decoration.
+func (l *Shaper) DecorationLine(g Glyph, d Decoration) (y, thickness fixed.Int26_6) {
+	l.init()
+	return l.faces.decorationLine(g, d)
+}
+
 // Bitmaps extracts bitmap glyphs from the provided slice and creates an op.CallOp to present
 // them. The returned op.CallOp will align correctly with the return value of Shape() for the
 // same gs slice.
 // All glyphs are expected to be from a single line of text (their Y offsets are ignored).
 func (l *Shaper) Bitmaps(gs []Glyph) op.CallOp {
 	l.init()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.validateCaches()
 	key := l.bitmapShapeCache.hashGlyphs(gs)
 	call, ok := l.bitmapShapeCache.Get(key, gs)
 	if ok {
 		return call
 	}
 	callOps := new(op.Ops)
-	call = l.shaper.Bitmaps(callOps, gs)
+	call = l.faces.Bitmaps(callOps, &l.bitmapGlyphCache, gs)
 	l.bitmapShapeCache.Put(key, gs, call)
 	return call
 }
@@ -581,3 +581,237 @@ func TestShapeStringRuneAccounting(t *testing.T) {
 		})
 	}
 }
+
+// TestLayoutSpans checks that glyphs laid out with spans carry the index
+// and style of their span, across paragraphs.
+func TestLayoutSpans(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	collection := []FontFace{{Face: ltrFace}}
+	shaper := NewShaper(NoSystemFonts(), WithCollection(collection))
+	spans := []Span{
+		{Runes: 2},
+		{Runes: 3, PxPerEm: fixed.I(20), BaselineShift: fixed.I(4)},
+		{Runes: 1},
+	}
+	// The second span crosses the paragraph break, and the final span
+	// extends to the end of the text.
+	shaper.LayoutSpans(Parameters{
+		PxPerEm:  fixed.I(10),
+		MaxWidth: 1000,
+		Locale:   english,
+	}, spans, "ab\ncdef")
+	// The synthetic newline glyph belongs to the run preceding it.
+	wantSpans := []int{0, 0, 0, 1, 1, 2, 2}
+	var got []int
+	for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+		got = append(got, g.Span)
+		ppem, _, _ := splitGlyphID(g.ID)
+		wantPPEM, wantShift := fixed.I(10), fixed.I(0)
+		if g.Span == 1 {
+			wantPPEM, wantShift = fixed.I(20), fixed.I(4)
+		}
+		if g.Flags&FlagParagraphBreak == 0 && ppem != wantPPEM {
+			t.Errorf("glyph %d of span %d has ppem %v, want %v", len(got)-1, g.Span, ppem, wantPPEM)
+		}
+		if g.Offset.Y != wantShift {
+			t.Errorf("glyph %d of span %d has y offset %v, want %v", len(got)-1, g.Span, g.Offset.Y, wantShift)
+		}
+	}
+	if !slices.Equal(got, wantSpans) {
+		t.Errorf("got glyph spans %v, want %v", got, wantSpans)
+	}
+	// Changing the style of a span must not reuse the cached layout.
+	spans[1].BaselineShift = 0
+	shaper.LayoutSpans(Parameters{
+		PxPerEm:  fixed.I(10),
+		MaxWidth: 1000,
+		Locale:   english,
+	}, spans, "ab\ncdef")
+	for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+		if g.Offset.Y != 0 {
+			t.Errorf("glyph of span %d has y offset %v after removing shift", g.Span, g.Offset.Y)
+		}
+	}
+}
+
+// TestDecorationLine checks that decoration lines are positioned from the
+// font metrics.
+func TestDecorationLine(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: ltrFace}}))
+	shaper.LayoutString(Parameters{
+		PxPerEm:  fixed.I(20),
+		MaxWidth: 1000,
+		Locale:   english,
+	}, "a")
+	g, _ := shaper.NextGlyph()
+	under, underSize := shaper.DecorationLine(g, Underline)
+	strike, strikeSize := shaper.DecorationLine(g, Strikethrough)
+	over, _ := shaper.DecorationLine(g, Overline)
+	if under <= 0 || under > g.Descent {
+		t.Errorf("underline at %v, want below the baseline within descent %v", under, g.Descent)
+	}
+	if strike >= 0 || -strike > g.Ascent {
+		t.Errorf("strikethrough at %v, want above the baseline within ascent %v", strike, g.Ascent)
+	}
+	if over != -g.Ascent {
+		t.Errorf("overline at %v, want %v", over, -g.Ascent)
+	}
+	if underSize <= 0 || strikeSize <= 0 {
+		t.Errorf("got thicknesses %v, %v, want positive", underSize, strikeSize)
+	}
+}
+
+func TestJustify(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	rtlFace, _ := opentype.Parse(nsareg.TTF)
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: ltrFace}, {Face: rtlFace}}))
+	const width = 150
+	for _, tc := range []struct {
+		name   string
+		align  Alignment
+		locale system.Locale
+		txt    string
+	}{
+		{"words", Justify, english, "The quick brown fox jumps over the lazy dog"},
+		{"characters", JustifyCharacters, english, "The quick brown fox jumps over the lazy dog"},
+		{"rtl", Justify, arabic, "الحب سماء لا تمطر غير الأحلام الحب سماء لا تمطر"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			shaper.LayoutString(Parameters{
+				Alignment: tc.align,
+				PxPerEm:   fixed.I(16),
+				MaxWidth:  width,
+				MinWidth:  width,
+				Locale:    tc.locale,
+			}, tc.txt)
+			var lines [][]Glyph
+			var line []Glyph
+			for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+				line = append(line, g)
+				if g.Flags&FlagLineBreak != 0 {
+					lines = append(lines, line)
+					line = nil
+				}
+			}
+			if len(lines) < 2 {
+				t.Fatalf("got %d lines, want several", len(lines))
+			}
+			for i, l := range lines {
+				slices.SortFunc(l, func(a, b Glyph) int { return int(a.X - b.X) })
+				// Lines end with a hanging space, on the left in RTL lines.
+				first, last := l[0], l[len(l)-1]
+				start, end := first.X, last.X
+				if tc.locale.Direction.Progression() == system.TowardOrigin {
+					start, end = first.X+first.Advance, last.X+last.Advance
+				}
+				for j := 1; j < len(l); j++ {
+					if prev := l[j-1]; prev.X+prev.Advance != l[j].X {
+						t.Errorf("line %d: glyph at %v doesn't follow glyph at %v with advance %v", i, l[j].X, prev.X, prev.Advance)
+					}
+				}
+				if i == len(lines)-1 {
+					if start == 0 && end == fixed.I(width) {
+						t.Errorf("final line spans %v-%v, want unjustified", start, end)
+					}
+					continue
+				}
+				if start != 0 || end != fixed.I(width) {
+					t.Errorf("line %d spans %v-%v, want 0-%v", i, start, end, width)
+				}
+			}
+		})
+	}
+}
+
+func TestTabStops(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: ltrFace}}))
+	tabs := NewTabStops(fixed.I(100),
+		TabStop{Position: fixed.I(50), Alignment: TabEnd},
+		TabStop{Position: fixed.I(200), Alignment: TabDecimal},
+		TabStop{Position: fixed.I(20)},
+	)
+	if got := tabs.Stops(); len(got) != 3 || got[0].Position != fixed.I(20) {
+		t.Errorf("got unsorted stops %v", got)
+	}
+	shaper.LayoutString(Parameters{
+		PxPerEm:  fixed.I(16),
+		MaxWidth: 1000,
+		Locale:   english,
+		TabStops: tabs,
+	}, "a\tb\tcd\t12.5\te")
+	var glyphs []Glyph
+	for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+		glyphs = append(glyphs, g)
+	}
+	if len(glyphs) != 13 {
+		t.Fatalf("got %d glyphs, want 13", len(glyphs))
+	}
+	if got, want := glyphs[2].X, fixed.I(20); got != want {
+		t.Errorf("start aligned text at %v, want %v", got, want)
+	}
+	if got, want := glyphs[5].X+glyphs[5].Advance, fixed.I(50); got != want {
+		t.Errorf("end aligned text ends at %v, want %v", got, want)
+	}
+	if got, want := glyphs[9].X, fixed.I(200); got != want {
+		t.Errorf("decimal point at %v, want %v", got, want)
+	}
+	if got, want := glyphs[12].X, fixed.I(300); got != want {
+		t.Errorf("text after the last stop at %v, want %v", got, want)
+	}
+	// Tabs display as spaces.
+	space, _ := ltrFace.Face().NominalGlyph(' ')
+	if _, _, gid := splitGlyphID(glyphs[1].ID); gid != space {
+		t.Errorf("tab has glyph %d, want space %d", gid, space)
+	}
+}
+
+func TestVerticalText(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	shaper := NewShaper(NoSystemFonts(), WithCollection([]FontFace{{Face: ltrFace}}))
+	params := Parameters{
+		PxPerEm:  fixed.I(16),
+		MaxWidth: 1000,
+		Locale:   system.Locale{Language: "ja", Direction: system.TTB},
+	}
+	shaper.LayoutString(params, "ab漢字")
+	var glyphs []Glyph
+	for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+		glyphs = append(glyphs, g)
+	}
+	if len(glyphs) != 4 {
+		t.Fatalf("got %d glyphs, want 4", len(glyphs))
+	}
+	for i, g := range glyphs {
+		if upright := g.Flags&FlagUpright != 0; upright != (i >= 2) {
+			t.Errorf("glyph %d: upright %v, want %v", i, upright, i >= 2)
+		}
+		if g.Advance <= 0 {
+			t.Errorf("glyph %d: non-positive advance %v", i, g.Advance)
+		}
+		if i > 0 && g.X != glyphs[i-1].X+glyphs[i-1].Advance {
+			t.Errorf("glyph %d: at %v, want %v", i, g.X, glyphs[i-1].X+glyphs[i-1].Advance)
+		}
+		// Glyphs are centered around the baseline.
+		if g.Bounds.Min.Y >= 0 || g.Bounds.Max.Y <= 0 {
+			t.Errorf("glyph %d: bounds %v not centered on the baseline", i, g.Bounds)
+		}
+	}
+	if b := glyphs[2].Bounds; b.Min.X < 0 || b.Max.X > glyphs[2].Advance {
+		t.Errorf("upright glyph bounds %v outside its advance %v", b, glyphs[2].Advance)
+	}
+
+	// Columns wrap at MaxWidth.
+	params.MaxWidth = glyphs[2].Advance.Ceil() * 2
+	shaper.LayoutString(params, "漢字漢字")
+	var ys []int32
+	for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+		if g.Flags&FlagLineBreak != 0 {
+			ys = append(ys, g.Y)
+		}
+	}
+	if len(ys) != 2 || ys[1] <= ys[0] {
+		t.Errorf("got lines at %v, want 2 increasing lines", ys)
+	}
+}
@@ -3,7 +3,10 @@
 package text
 
 import (
+	"encoding/binary"
 	"fmt"
+	"sort"
+	"strings"
 
 	"gioui.org/io/system"
 	"golang.org/x/image/math/fixed"
@@ -15,6 +18,14 @@ const (
 	Start Alignment = iota
 	End
 	Middle
+	// Justify aligns both edges of every line but the final line of a
+	// paragraph by stretching the spaces between words. The final line is
+	// aligned to the Start.
+	Justify
+	// JustifyCharacters is like Justify, but stretches the space between
+	// every pair of characters, for scripts that don't separate words by
+	// spaces.
+	JustifyCharacters
 )
 
 func (a Alignment) String() string {
@@ -25,16 +36,154 @@ func (a Alignment) String() string {
 		return "End"
 	case Middle:
 		return "Middle"
+	case Justify:
+		return "Justify"
+	case JustifyCharacters:
+		return "JustifyCharacters"
 	default:
 		panic("invalid Alignment")
 	}
 }
 
+// justified reports whether a stretches lines to justify them.
+func (a Alignment) justified() bool {
+	return a == Justify || a == JustifyCharacters
+}
+
+// TabAlignment describes how the text following a tab character aligns
+// with its tab stop.
+type TabAlignment uint8
+
+const (
+	// TabStart aligns the start of the text with the stop.
+	TabStart TabAlignment = iota
+	// TabEnd aligns the end of the text with the stop. The text extends up
+	// to the next tab character or the end of the line.
+	TabEnd
+	// TabDecimal aligns the first decimal point ('.') of the text with the
+	// stop. Text without a decimal point is aligned like TabEnd.
+	TabDecimal
+)
+
+func (a TabAlignment) String() string {
+	switch a {
+	case TabStart:
+		return "TabStart"
+	case TabEnd:
+		return "TabEnd"
+	case TabDecimal:
+		return "TabDecimal"
+	default:
+		panic("invalid TabAlignment")
+	}
+}
+
+// TabStop is a position in a line that text following a tab character
+// is aligned to.
+type TabStop struct {
+	// Position of the stop, relative to the start of the line.
+	Position  fixed.Int26_6
+	Alignment TabAlignment
+}
+
+// TabStops is a set of tab stops in a comparable form suitable for
+// Parameters. The zero value disables tab stops, and tab characters are
+// shaped like any other character.
+type TabStops struct {
+	interval fixed.Int26_6
+	// stops contains the encoded stops, sorted by position.
+	stops string
+}
+
+// NewTabStops returns the TabStops with the given stops. Tab characters
+// past the last stop advance to the next multiple of interval, or are
+// left as is if interval is zero.
+func NewTabStops(interval fixed.Int26_6, stops ...TabStop) TabStops {
+	stops = append([]TabStop(nil), stops...)
+	sort.Slice(stops, func(i, j int) bool {
+		return stops[i].Position < stops[j].Position
+	})
+	b := make([]byte, 0, len(stops)*5)
+	for _, s := range stops {
+		b = binary.LittleEndian.AppendUint32(b, uint32(s.Position))
+		b = append(b, byte(s.Alignment))
+	}
+	return TabStops{interval: interval, stops: string(b)}
+}
+
+// Interval returns the distance between the implicit stops following the
+// last stop.
+func (t TabStops) Interval() fixed.Int26_6 {
+	return t.interval
+}
+
+// Stops returns the explicit stops, sorted by position.
+func (t TabStops) Stops() []TabStop {
+	var stops []TabStop
+	for s := t.stops; len(s) >= 5; s = s[5:] {
+		stops = append(stops, t.stop(s))
+	}
+	return stops
+}
+
+// next returns the first stop after x, and whether there is one.
+func (t TabStops) next(x fixed.Int26_6) (TabStop, bool) {
+	for s := t.stops; len(s) >= 5; s = s[5:] {
+		if st := t.stop(s); st.Position > x {
+			return st, true
+		}
+	}
+	if t.interval <= 0 {
+		return TabStop{}, false
+	}
+	return TabStop{Position: (x/t.interval + 1) * t.interval}, true
+}
+
+func (t TabStops) stop(s string) TabStop {
+	pos := binary.LittleEndian.Uint32([]byte(s[:4]))
+	return TabStop{Position: fixed.Int26_6(pos), Alignment: TabAlignment(s[4])}
+}
+
+// enabled reports whether t has any stops.
+func (t TabStops) enabled() bool {
+	return t.interval > 0 || len(t.stops) > 0
+}
+
+// Decoration is a set of lines drawn along text.
+type Decoration uint8
+
+const (
+	// Underline draws a line below the baseline.
+	Underline Decoration = 1 << iota
+	// Strikethrough draws a line through the text.
+	Strikethrough
+	// Overline draws a line above the text.
+	Overline
+)
+
+func (d Decoration) String() string {
+	var names []string
+	if d&Underline != 0 {
+		names = append(names, "Underline")
+	}
+	if d&Strikethrough != 0 {
+		names = append(names, "Strikethrough")
+	}
+	if d&Overline != 0 {
+		names = append(names, "Overline")
+	}
+	return strings.Join(names, "|")
+}
+
 // Align returns the x offset that should be applied to text with width so that it
 // appears correctly aligned within a space of size maxWidth and with the primary
-// text direction dir.
+// text direction dir. Justified text is aligned like Start; the stretching of
+// justified lines is applied by the Shaper.
 func (a Alignment) Align(dir system.TextDirection, width fixed.Int26_6, maxWidth int) fixed.Int26_6 {
 	mw := fixed.I(maxWidth)
+	if a.justified() {
+		a = Start
+	}
 	if dir.Progression() == system.TowardOrigin {
 		switch a {
 		case Start:
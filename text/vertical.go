@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package text
+
+import (
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/shaping"
+	"github.com/go-text/typesetting/unicodedata"
+	"golang.org/x/image/math/fixed"
+)
+
+// Vertical text is laid out in the same coordinate space as horizontal
+// text: lines flow along the X axis and are stacked along the Y axis. Runs
+// of glyphs that are upright in vertical text are shaped top-to-bottom and
+// rotated counter-clockwise when drawn, such that they appear upright once
+// the entire layout is rotated clockwise for display. Other runs are shaped
+// horizontally and appear rotated sideways.
+
+// splitByOrientation divides the inputs of vertical text on the boundaries
+// of upright and sideways runes, as defined by Unicode Standard Annex #50.
+// Upright inputs have a top-to-bottom direction, and sideways inputs keep
+// their horizontal direction. It will use buf as the backing memory for the
+// returned slice if buf is non-nil.
+func splitByOrientation(inputs []shaping.Input, buf []shaping.Input) []shaping.Input {
+	splitInputs := buf
+	if splitInputs == nil {
+		splitInputs = make([]shaping.Input, 0, len(inputs))
+	}
+	for _, input := range inputs {
+		if input.RunStart == input.RunEnd {
+			splitInputs = append(splitInputs, input)
+			continue
+		}
+		orientation := unicodedata.LookupVerticalOrientation(input.Script)
+		currentInput := input
+		sideways := orientation.Orientation(input.Text[input.RunStart])
+		for i := input.RunStart + 1; i < input.RunEnd; i++ {
+			if s := orientation.Orientation(input.Text[i]); s != sideways {
+				currentInput.RunEnd = i
+				splitInputs = append(splitInputs, orientInput(currentInput, sideways))
+				currentInput = input
+				currentInput.RunStart = i
+				sideways = s
+			}
+		}
+		currentInput.RunEnd = input.RunEnd
+		splitInputs = append(splitInputs, orientInput(currentInput, sideways))
+	}
+	return splitInputs
+}
+
+// orientInput returns input with a top-to-bottom direction unless it is
+// sideways.
+func orientInput(input shaping.Input, sideways bool) shaping.Input {
+	if !sideways {
+		input.Direction = di.DirectionTTB
+		input.Direction.SetSideways(false)
+	}
+	return input
+}
+
+// uprightOutput converts the output of shaping upright text to the
+// conventions of horizontal text: advances are made positive, and the line
+// bounds are centered around the baseline, which is the center line of the
+// vertical text.
+func uprightOutput(out *shaping.Output) {
+	for i := range out.Glyphs {
+		out.Glyphs[i].YAdvance = -out.Glyphs[i].YAdvance
+	}
+	out.Advance = -out.Advance
+	out.LineBounds = shaping.Bounds{
+		Ascent:  out.Size / 2,
+		Descent: -out.Size / 2,
+	}
+}
+
+// centerSideways moves the glyphs of a horizontally shaped output such that
+// their line bounds are centered around the baseline, which is the center
+// line of the vertical text.
+func centerSideways(out *shaping.Output) {
+	center := (out.LineBounds.Ascent + out.LineBounds.Descent) / 2
+	for i := range out.Glyphs {
+		out.Glyphs[i].YOffset -= center
+		out.Glyphs[i].YBearing -= center
+	}
+	out.LineBounds.Ascent -= center
+	out.LineBounds.Descent -= center
+	out.GlyphBounds.Ascent -= center
+	out.GlyphBounds.Descent -= center
+}
+
+// toUprightGlyph converts a glyph shaped top-to-bottom into the
+// representation of toGioGlyphs. The offsets of the glyph are swapped, such
+// that the glyph is positioned by the same rules as horizontal glyphs, and
+// its bounds include its offsets.
+func toUprightGlyph(g shaping.Glyph, ppem fixed.Int26_6, faceIdx int) glyph {
+	// The glyph is rotated counter-clockwise: its Y axis maps to the
+	// negative X axis, and its X axis to the negative Y axis.
+	minX := -g.YOffset - g.YBearing
+	maxY := -g.XOffset - g.XBearing
+	return glyph{
+		id:           newGlyphID(ppem, faceIdx, g.GlyphID),
+		clusterIndex: g.ClusterIndex,
+		runeCount:    g.RuneCount,
+		glyphCount:   g.GlyphCount,
+		xAdvance:     g.YAdvance,
+		xOffset:      g.YOffset,
+		yOffset:      g.XOffset,
+		bounds: fixed.Rectangle26_6{
+			Min: fixed.Point26_6{X: minX, Y: maxY - g.Width},
+			Max: fixed.Point26_6{X: minX - g.Height, Y: maxY},
+		},
+	}
+}
@@ -0,0 +1,275 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+	"unicode"
+
+	"golang.org/x/exp/slices"
+)
+
+// AnnotationKind is the kind of an Annotation, such as for choosing the
+// color of its underline.
+type AnnotationKind uint8
+
+const (
+	AnnotationError AnnotationKind = iota
+	AnnotationWarning
+	AnnotationInfo
+	AnnotationSpelling
+)
+
+// Annotation marks a range of runes of text, such as a misspelled word, to
+// be underlined with a wavy line.
+type Annotation struct {
+	Start, End int
+	Kind       AnnotationKind
+	// Message describes the annotation, such as in a tooltip.
+	Message string
+}
+
+// Checker annotates text, such as a spell checker.
+type Checker interface {
+	// Check returns the annotations of txt. After an edit, an Editor checks
+	// only the paragraphs changed by the edit, so the annotations of a
+	// paragraph must not depend on the text around it.
+	Check(txt string) []Annotation
+}
+
+// Dictionary is a Checker annotating the words missing from a list of
+// words as misspelled.
+type Dictionary struct {
+	words map[string]bool
+}
+
+// ReadDictionary reads a list of words for a Dictionary, one word per line.
+// Empty lines and lines starting with '#' are ignored.
+func ReadDictionary(r io.Reader) (*Dictionary, error) {
+	d := &Dictionary{words: make(map[string]bool)}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		w := strings.TrimSpace(s.Text())
+		if w == "" || strings.HasPrefix(w, "#") {
+			continue
+		}
+		d.words[w] = true
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Contains reports whether w is in the dictionary. Words are also found
+// in lower case, such as at the start of sentences.
+func (d *Dictionary) Contains(w string) bool {
+	return d.words[w] || d.words[strings.ToLower(w)]
+}
+
+// Check annotates the words of txt missing from the dictionary. Words are
+// runs of letters, including apostrophes between letters.
+func (d *Dictionary) Check(txt string) []Annotation {
+	var annotations []Annotation
+	runes := []rune(txt)
+	for i := 0; i < len(runes); {
+		if !unicode.IsLetter(runes[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(runes) && (unicode.IsLetter(runes[i]) ||
+			runes[i] == '\'' && i+1 < len(runes) && unicode.IsLetter(runes[i+1])) {
+			i++
+		}
+		if w := string(runes[start:i]); !d.Contains(w) {
+			annotations = append(annotations, Annotation{
+				Start:   start,
+				End:     i,
+				Kind:    AnnotationSpelling,
+				Message: fmt.Sprintf("unknown word %q", w),
+			})
+		}
+	}
+	return annotations
+}
+
+// SetAnnotations replaces the annotations of the text, sorted by their
+// start. Edits of the text move, shrink and remove the annotations to keep
+// them attached to the annotated text.
+func (e *textView) SetAnnotations(annotations []Annotation) {
+	e.annotations = sortAnnotations(append(e.annotations[:0], annotations...))
+}
+
+// Annotations returns the annotations of the text and the annotations of a
+// Checker, sorted by their start.
+func (e *textView) Annotations() []Annotation {
+	var all []Annotation
+	all = append(all, e.annotations...)
+	all = append(all, e.checked...)
+	return sortAnnotations(all)
+}
+
+// VisibleAnnotations appends the annotations overlapping the visible lines
+// of the text to as and returns it.
+func (e *textView) VisibleAnnotations(as []Annotation) []Annotation {
+	e.makeValid()
+	start, end := e.visibleRunes()
+	for _, list := range [...][]Annotation{e.annotations, e.checked} {
+		for _, a := range list {
+			if a.Start >= end {
+				break
+			}
+			if a.End > start {
+				as = append(as, a)
+			}
+		}
+	}
+	return as
+}
+
+// sortAnnotations sorts as by start, keeping the order of annotations with
+// the same start, and returns it.
+func sortAnnotations(as []Annotation) []Annotation {
+	slices.SortStableFunc(as, func(a, b Annotation) int {
+		return a.Start - b.Start
+	})
+	return as
+}
+
+// adjustAnnotations adjusts the annotations to the replacement of the runes
+// [start, end) of the text with the runes [start, newEnd), and marks the
+// replacement for checking.
+func (e *textView) adjustAnnotations(start, end, newEnd int) {
+	e.annotations = adjustAnnotations(e.annotations, start, end, newEnd)
+	e.checked = adjustAnnotations(e.checked, start, end, newEnd)
+	e.unchecked.add(start, end, newEnd)
+}
+
+// adjustAnnotations is like textView.adjustAnnotations for the annotations
+// as.
+func adjustAnnotations(as []Annotation, start, end, newEnd int) []Annotation {
+	adjusted := as[:0]
+	for _, a := range as {
+		if a.Start == start && start == end {
+			// Text inserted at the start is not annotated.
+			a.Start = newEnd
+		} else {
+			a.Start = adjustPos(a.Start, start, end, newEnd)
+		}
+		a.End = adjustPos(a.End, start, end, newEnd)
+		if a.Start < a.End {
+			adjusted = append(adjusted, a)
+		}
+	}
+	return adjusted
+}
+
+// AnnotationAt returns the annotation whose visible regions contain pos.
+func (e *textView) AnnotationAt(pos image.Point) (Annotation, bool) {
+	e.makeValid()
+	start, end := e.visibleRunes()
+	for _, list := range [...][]Annotation{e.annotations, e.checked} {
+		for _, a := range list {
+			if a.End <= start || a.Start >= end {
+				continue
+			}
+			e.regions = e.Regions(a.Start, a.End, e.regions)
+			for _, r := range e.regions {
+				if pos.In(r.Bounds) {
+					return a, true
+				}
+			}
+		}
+	}
+	return Annotation{}, false
+}
+
+// checkParagraphs annotates the paragraphs changed since they were checked
+// with c, replacing their previous annotations by c.
+func (e *textView) checkParagraphs(c Checker) {
+	u := &e.unchecked
+	if !u.pending {
+		return
+	}
+	u.pending = false
+	start, end, startOff, endOff := e.paragraphRange(u.start, u.end)
+	buf := make([]byte, endOff-startOff)
+	n, _ := e.ReadAt(buf, startOff)
+	annotations := c.Check(string(buf[:n]))
+	for i := range annotations {
+		annotations[i].Start += start
+		annotations[i].End += start
+	}
+	kept := e.checked[:0]
+	for _, a := range e.checked {
+		if a.End <= start || a.Start >= end {
+			kept = append(kept, a)
+		}
+	}
+	e.checked = sortAnnotations(append(kept, annotations...))
+}
+
+// SetAnnotations replaces the annotations of the text, such as the
+// diagnostics of a compiler. The annotations are sorted by their start.
+// Edits of the text move, shrink and remove the annotations to keep them
+// attached to the annotated text. The annotations of the Checker are kept
+// apart, and are not replaced.
+func (e *Editor) SetAnnotations(annotations []Annotation) {
+	e.initBuffer()
+	e.text.SetAnnotations(annotations)
+}
+
+// Annotations returns the annotations set by SetAnnotations and the
+// annotations of the Checker, adjusted for the edits since they were set.
+func (e *Editor) Annotations() []Annotation {
+	e.initBuffer()
+	return e.text.Annotations()
+}
+
+// VisibleAnnotations appends the annotations overlapping the visible lines
+// of the text to as and returns it, such as for drawing only the visible
+// annotations.
+func (e *Editor) VisibleAnnotations(as []Annotation) []Annotation {
+	e.initBuffer()
+	return e.text.VisibleAnnotations(as)
+}
+
+// AnnotationAt returns the annotation under the position pos, relative to
+// the editor.
+func (e *Editor) AnnotationAt(pos image.Point) (Annotation, bool) {
+	e.initBuffer()
+	return e.text.AnnotationAt(pos)
+}
+
+// HoveredAnnotation returns the annotation under the pointer, and the
+// position of the pointer relative to the editor, such as for displaying
+// the message of the annotation in a tooltip.
+func (e *Editor) HoveredAnnotation() (Annotation, image.Point, bool) {
+	e.initBuffer()
+	if !e.hover.inside {
+		return Annotation{}, image.Point{}, false
+	}
+	a, ok := e.text.AnnotationAt(e.hover.pos)
+	return a, e.hover.pos, ok
+}
+
+// checkText annotates the paragraphs of the text changed since they were
+// last checked with the Checker. The annotations of the Checker are removed
+// if it is replaced or unset, and the whole text is checked again by a new
+// Checker.
+func (e *Editor) checkText() {
+	if e.Checker != e.checker {
+		e.checker = e.Checker
+		e.text.checked = e.text.checked[:0]
+		e.text.unchecked = changedRange{end: e.text.Len(), pending: true}
+	}
+	if e.Checker == nil {
+		return
+	}
+	e.text.checkParagraphs(e.Checker)
+}
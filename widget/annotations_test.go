@@ -0,0 +1,183 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"os"
+	"reflect"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/font"
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+)
+
+func readDictionary(t *testing.T) *Dictionary {
+	t.Helper()
+	f, err := os.Open("testdata/words.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := ReadDictionary(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestDictionary(t *testing.T) {
+	d := readDictionary(t)
+	got := d.Check("The quikc brown fox don't jumsp, Go!")
+	want := []Annotation{
+		{Start: 4, End: 9, Kind: AnnotationSpelling, Message: `unknown word "quikc"`},
+		{Start: 26, End: 31, Kind: AnnotationSpelling, Message: `unknown word "jumsp"`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got annotations %v, want %v", got, want)
+	}
+}
+
+func TestEditorAnnotations(t *testing.T) {
+	r := new(input.Router)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(500, 500)),
+		Locale:      english,
+		Source:      r.Source(),
+	}
+	shaper := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	e := &Editor{Checker: readDictionary(t)}
+	e.SetText("teh fox")
+	layoutEditor := func() {
+		gtx.Ops.Reset()
+		e.Layout(gtx, shaper, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+		r.Frame(gtx.Ops)
+	}
+	layoutEditor()
+	want := []Annotation{{Start: 0, End: 3, Kind: AnnotationSpelling, Message: `unknown word "teh"`}}
+	if got := e.Annotations(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got annotations %v, want %v", got, want)
+	}
+	// Annotations follow the edits of the text until it is checked again.
+	e.SetCaret(0, 0)
+	e.Insert("the ")
+	want[0].Start, want[0].End = 4, 7
+	if got := e.Annotations(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got annotations %v after an edit, want %v", got, want)
+	}
+	layoutEditor()
+	if got := e.Annotations(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got annotations %v after checking, want %v", got, want)
+	}
+	regions := e.Regions(4, 7, nil)
+	if len(regions) != 1 {
+		t.Fatalf("got regions %v", regions)
+	}
+	center := regions[0].Bounds.Min.Add(regions[0].Bounds.Max).Div(2)
+	if a, ok := e.AnnotationAt(center); !ok || a != want[0] {
+		t.Errorf("got annotation %v, %v at %v", a, ok, center)
+	}
+	if _, _, ok := e.HoveredAnnotation(); ok {
+		t.Error("annotation hovered without a pointer")
+	}
+	r.Queue(pointer.Event{
+		Kind:     pointer.Move,
+		Source:   pointer.Mouse,
+		Position: f32.Pt(float32(center.X), float32(center.Y)),
+	})
+	layoutEditor()
+	if a, pos, ok := e.HoveredAnnotation(); !ok || a != want[0] || pos != center {
+		t.Errorf("got hovered annotation %v at %v, %v", a, pos, ok)
+	}
+	// Annotations set by the application are kept apart from the
+	// annotations of the checker.
+	e.SetAnnotations([]Annotation{{Start: 4, End: 7, Kind: AnnotationWarning}, {Start: 0, End: 3}})
+	e.SetCaret(2, 6)
+	e.Insert("")
+	spelling := want[0]
+	spelling.Start, spelling.End = 2, 3
+	want = []Annotation{{Start: 0, End: 2}, {Start: 2, End: 3, Kind: AnnotationWarning}, spelling}
+	if got := e.Annotations(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got annotations %v after deleting, want %v", got, want)
+	}
+	layoutEditor()
+	want = []Annotation{
+		{Start: 0, End: 2},
+		{Start: 0, End: 3, Kind: AnnotationSpelling, Message: `unknown word "thh"`},
+		{Start: 2, End: 3, Kind: AnnotationWarning},
+	}
+	if got := e.Annotations(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got annotations %v after checking, want %v", got, want)
+	}
+	if got := e.VisibleAnnotations(nil); len(got) != len(want) {
+		t.Errorf("got visible annotations %v, want %v", got, want)
+	}
+	e.Checker = nil
+	layoutEditor()
+	want = []Annotation{{Start: 0, End: 2}, {Start: 2, End: 3, Kind: AnnotationWarning}}
+	if got := e.Annotations(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got annotations %v without a checker, want %v", got, want)
+	}
+	// Setting a checker again checks the whole text.
+	e.Checker = readDictionary(t)
+	layoutEditor()
+	want = []Annotation{
+		{Start: 0, End: 2},
+		{Start: 0, End: 3, Kind: AnnotationSpelling, Message: `unknown word "thh"`},
+		{Start: 2, End: 3, Kind: AnnotationWarning},
+	}
+	if got := e.Annotations(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got annotations %v after setting the checker again, want %v", got, want)
+	}
+}
+
+// paragraphChecker records the text it checks.
+type paragraphChecker struct {
+	Checker
+	checked []string
+}
+
+func (c *paragraphChecker) Check(txt string) []Annotation {
+	c.checked = append(c.checked, txt)
+	return c.Checker.Check(txt)
+}
+
+func TestEditorCheckParagraphs(t *testing.T) {
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(500, 500)),
+		Locale:      english,
+	}
+	shaper := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	c := &paragraphChecker{Checker: readDictionary(t)}
+	e := &Editor{Checker: c}
+	e.SetText("teh fox\nthe dgo\nthe fox")
+	layoutEditor := func() {
+		gtx.Ops.Reset()
+		e.Layout(gtx, shaper, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	}
+	layoutEditor()
+	// Only the edited paragraph is checked again.
+	c.checked = nil
+	e.SetCaret(len("teh fox\nthe d"), len("teh fox\nthe dg"))
+	e.Insert("o")
+	layoutEditor()
+	if want := []string{"the doo"}; !reflect.DeepEqual(c.checked, want) {
+		t.Errorf("checked %q after an edit, want %q", c.checked, want)
+	}
+	want := []Annotation{
+		{Start: 0, End: 3, Kind: AnnotationSpelling, Message: `unknown word "teh"`},
+		{Start: 12, End: 15, Kind: AnnotationSpelling, Message: `unknown word "doo"`},
+	}
+	if got := e.Annotations(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got annotations %v, want %v", got, want)
+	}
+}
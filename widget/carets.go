@@ -0,0 +1,226 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"bytes"
+	"image"
+	"unicode/utf8"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/image/math/fixed"
+)
+
+// Caret is the position of a caret in runes, and of the other end of the
+// text selected by it. Start is the position of the caret, and may be
+// after End.
+type Caret struct {
+	Start, End int
+}
+
+// CaretCount returns the number of carets, including the primary caret.
+func (e *textView) CaretCount() int {
+	return len(e.carets) + 1
+}
+
+// Carets returns the carets, starting with the primary caret.
+func (e *textView) Carets() []Caret {
+	carets := []Caret{{Start: e.caret.start, End: e.caret.end}}
+	for _, c := range e.carets {
+		carets = append(carets, Caret{Start: c.start, End: c.end})
+	}
+	return carets
+}
+
+// AddCaret adds a caret at start, selecting the text to end, and makes it
+// the primary caret. Carets whose selections overlap are merged.
+func (e *textView) AddCaret(start, end int) {
+	e.pushCaret()
+	e.SetCaret(start, end)
+	e.caret.xoff = 0
+	e.mergeCarets()
+}
+
+// selectionsText appends the text selected by the carets to buf in the
+// order of the text, separated by newlines, and returns it. Carets without a
+// selection are skipped.
+func (e *textView) selectionsText(buf []byte) []byte {
+	carets := e.Carets()
+	slices.SortFunc(carets, func(a, b Caret) int {
+		return min(a.Start, a.End) - min(b.Start, b.End)
+	})
+	buf = buf[:0]
+	first := true
+	for _, c := range carets {
+		if c.Start == c.End {
+			continue
+		}
+		if !first {
+			buf = append(buf, '\n')
+		}
+		first = false
+		start, end := e.runeOffset(min(c.Start, c.End)), e.runeOffset(max(c.Start, c.End))
+		n := len(buf)
+		buf = append(buf, make([]byte, end-start)...)
+		m, _ := e.rr.ReadAt(buf[n:], int64(start))
+		buf = buf[:n+m]
+	}
+	return buf
+}
+
+// pushCaret adds a secondary caret at the position of the primary caret.
+func (e *textView) pushCaret() {
+	e.carets = append(e.carets, e.caret)
+}
+
+// ClearCarets removes the secondary carets.
+func (e *textView) ClearCarets() {
+	e.carets = e.carets[:0]
+}
+
+// adjustCarets adjusts the carets to the replacement of the runes
+// [start, end) of the text with the runes [start, newEnd).
+func (e *textView) adjustCarets(start, end, newEnd int) {
+	e.caret.start = adjustPos(e.caret.start, start, end, newEnd)
+	e.caret.end = adjustPos(e.caret.end, start, end, newEnd)
+	for i := range e.carets {
+		c := &e.carets[i]
+		c.start = adjustPos(c.start, start, end, newEnd)
+		c.end = adjustPos(c.end, start, end, newEnd)
+	}
+}
+
+// forEachCaret calls f for every caret, with the caret in place of the
+// primary caret, and merges the resulting carets. The primary caret is
+// visited last.
+func (e *textView) forEachCaret(f func()) {
+	for i := range e.carets {
+		e.caret, e.carets[i] = e.carets[i], e.caret
+		f()
+		e.caret, e.carets[i] = e.carets[i], e.caret
+	}
+	f()
+	e.mergeCarets()
+}
+
+// mergeCarets merges carets whose selections overlap, and carets at the same
+// position.
+func (e *textView) mergeCarets() {
+	if len(e.carets) == 0 {
+		return
+	}
+	type entry struct {
+		textCaret
+		lo, hi  int
+		primary bool
+	}
+	entries := make([]entry, 0, len(e.carets)+1)
+	for i := -1; i < len(e.carets); i++ {
+		c := e.caret
+		if i >= 0 {
+			c = e.carets[i]
+		}
+		entries = append(entries, entry{
+			textCaret: c,
+			lo:        min(c.start, c.end),
+			hi:        max(c.start, c.end),
+			primary:   i == -1,
+		})
+	}
+	slices.SortStableFunc(entries, func(a, b entry) int {
+		return a.lo - b.lo
+	})
+	merged := entries[:1]
+	for _, c := range entries[1:] {
+		last := &merged[len(merged)-1]
+		if c.lo >= last.hi && c.lo != last.lo {
+			merged = append(merged, c)
+			continue
+		}
+		if c.primary {
+			last.textCaret, last.primary = c.textCaret, true
+		}
+		last.hi = max(last.hi, c.hi)
+		// Keep the caret at the end of the merged selection it was at.
+		if last.start < last.end {
+			last.start, last.end = last.lo, last.hi
+		} else {
+			last.start, last.end = last.hi, last.lo
+		}
+	}
+	e.carets = e.carets[:0]
+	for _, c := range merged {
+		if c.primary {
+			e.caret = c.textCaret
+		} else {
+			e.carets = append(e.carets, c.textCaret)
+		}
+	}
+}
+
+// SelectBox replaces the carets with a selection on every line between the
+// document coordinates a and b, spanning the columns between them. The
+// carets are on the side of b, and the primary caret is on the line of b.
+func (e *textView) SelectBox(a, b image.Point) {
+	from := e.closestToXY(fixed.I(a.X), a.Y).lineCol.line
+	to := e.closestToXY(fixed.I(b.X), b.Y).lineCol.line
+	step := 1
+	if to < from {
+		step = -1
+	}
+	e.carets = e.carets[:0]
+	for line := from; ; line += step {
+		y := e.closestToLineCol(line, 0).y
+		c := textCaret{
+			start: e.closestToXYGraphemes(fixed.I(b.X), y).runes,
+			end:   e.closestToXYGraphemes(fixed.I(a.X), y).runes,
+		}
+		if line == to {
+			e.caret = c
+			break
+		}
+		e.carets = append(e.carets, c)
+	}
+}
+
+// SelectNextOccurrence selects the word at the primary caret if it has no
+// selection. Otherwise, it adds a primary caret selecting the next
+// occurrence of the text selected by the primary caret, after the last
+// selection of any caret and wrapping around to the start of the text. It
+// reports whether the carets changed.
+func (e *textView) SelectNextOccurrence() bool {
+	if e.caret.start == e.caret.end {
+		e.MoveWord(-1, selectionClear)
+		e.MoveWord(1, selectionExtend)
+		return e.caret.start != e.caret.end
+	}
+	sel := e.SelectedText(nil)
+	txt := e.Text(nil)
+	last := max(e.caret.start, e.caret.end)
+	for _, c := range e.carets {
+		last = max(last, max(c.start, c.end))
+	}
+	off := e.runeOffset(last)
+	i := bytes.Index(txt[off:], sel)
+	if i == -1 {
+		off = 0
+		i = bytes.Index(txt, sel)
+	}
+	if i == -1 {
+		return false
+	}
+	start := e.byteRunes(int64(off + i))
+	end := start + utf8.RuneCount(sel)
+	for i := -1; i < len(e.carets); i++ {
+		c := e.caret
+		if i >= 0 {
+			c = e.carets[i]
+		}
+		if min(c.start, c.end) == start {
+			// Every occurrence is selected.
+			return false
+		}
+	}
+	e.AddCaret(end, start)
+	return true
+}
@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"reflect"
+	"testing"
+
+	"gioui.org/font"
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+)
+
+func assertCarets(t *testing.T, e *Editor, contents string, carets ...Caret) {
+	t.Helper()
+	if got := e.Text(); got != contents {
+		t.Errorf("got contents %q, want %q", got, contents)
+	}
+	if got := e.Carets(); !reflect.DeepEqual(got, carets) {
+		t.Errorf("got carets %v, want %v", got, carets)
+	}
+}
+
+func TestEditorCarets(t *testing.T) {
+	e := new(Editor)
+	e.SetText("one two one three one")
+	e.SetCaret(3, 3)
+	e.AddCaret(7, 7)
+	e.AddCaret(21, 21)
+	assertCarets(t, e, "one two one three one", Caret{21, 21}, Caret{3, 3}, Caret{7, 7})
+	e.Insert("!")
+	assertCarets(t, e, "one! two! one three one!", Caret{24, 24}, Caret{4, 4}, Caret{9, 9})
+	e.Delete(-2)
+	assertCarets(t, e, "on tw one three on", Caret{18, 18}, Caret{2, 2}, Caret{5, 5})
+	// The edits at every caret are undone and redone together.
+	e.Undo()
+	assertCarets(t, e, "one! two! one three one!", Caret{4, 2}, Caret{9, 7}, Caret{24, 22})
+	e.Undo()
+	assertCarets(t, e, "one two one three one", Caret{3, 3}, Caret{7, 7}, Caret{21, 21})
+	e.Redo()
+	assertCarets(t, e, "one! two! one three one!", Caret{24, 23}, Caret{4, 3}, Caret{9, 8})
+	// Carets moving to the same position are merged.
+	e.ClearSelection()
+	e.MoveCaret(-9, -9)
+	assertCarets(t, e, "one! two! one three one!", Caret{15, 15}, Caret{0, 0})
+	e.SetCaret(1, 1)
+	assertCarets(t, e, "one! two! one three one!", Caret{1, 1})
+}
+
+func TestEditorCutCarets(t *testing.T) {
+	r := new(input.Router)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(500, 500)),
+		Locale:      english,
+		Source:      r.Source(),
+	}
+	shaper := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	e := new(Editor)
+	gtx.Execute(key.FocusCmd{Tag: e})
+	e.SetText("one two one three one")
+	e.SetCaret(3, 0)
+	e.AddCaret(7, 7)
+	e.AddCaret(11, 8)
+	for _, evts := range [][]key.Event{nil, {{Name: "X", Modifiers: key.ModShortcut, State: key.Press}}} {
+		for _, ev := range evts {
+			r.Queue(ev)
+		}
+		gtx.Ops.Reset()
+		e.Layout(gtx, shaper, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+		r.Frame(gtx.Ops)
+	}
+	// Every selection is cut, and carets without a selection are kept.
+	assertCarets(t, e, " two  three one", Caret{5, 5}, Caret{0, 0}, Caret{4, 4})
+	if _, content, ok := r.WriteClipboard(); !ok || string(content) != "one\none" {
+		t.Errorf("got clipboard %q, %v, want %q", content, ok, "one\none")
+	}
+}
+
+func TestEditorSelectNextOccurrence(t *testing.T) {
+	e := new(Editor)
+	e.SetText("one two one three one")
+	e.SetCaret(13, 13)
+	e.initBuffer()
+	if !e.text.SelectNextOccurrence() {
+		t.Fatal("no word selected")
+	}
+	assertCarets(t, e, "one two one three one", Caret{17, 12})
+	if e.text.SelectNextOccurrence() {
+		t.Fatal("selected missing occurrence")
+	}
+	e.SetCaret(11, 8)
+	for i := 0; i < 2; i++ {
+		if !e.text.SelectNextOccurrence() {
+			t.Fatalf("occurrence %d not selected", i)
+		}
+	}
+	if e.text.SelectNextOccurrence() {
+		t.Error("selected an occurrence twice")
+	}
+	assertCarets(t, e, "one two one three one", Caret{3, 0}, Caret{11, 8}, Caret{21, 18})
+	e.Insert("1")
+	assertCarets(t, e, "1 two 1 three 1", Caret{1, 1}, Caret{7, 7}, Caret{15, 15})
+}
+
+func TestEditorSelectBox(t *testing.T) {
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(200, 200)),
+		Locale:      english,
+	}
+	shaper := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	e := new(Editor)
+	e.SetText("abcd\nabcd\nab\nabcd")
+	e.Layout(gtx, shaper, font.Font{Typeface: "Go Mono"}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	coords := func(r int) image.Point {
+		e.SetCaret(r, r)
+		return e.CaretCoords().Round()
+	}
+	e.text.SelectBox(coords(1), coords(16))
+	assertCarets(t, e, "abcd\nabcd\nab\nabcd", Caret{16, 14}, Caret{3, 1}, Caret{8, 6}, Caret{12, 11})
+	e.Insert("-")
+	assertCarets(t, e, "a-d\na-d\na-\na-d", Caret{13, 13}, Caret{2, 2}, Caret{6, 6}, Caret{10, 10})
+}
@@ -0,0 +1,161 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"strings"
+
+	"gioui.org/io/key"
+)
+
+// Completion is a suggestion for completing the text at the caret of an
+// Editor.
+type Completion struct {
+	// Label is the text displayed in the list of suggestions. If empty, Text
+	// is displayed.
+	Label string
+	// Text replaces the runes [Start, End) of the editor text when the
+	// completion is accepted.
+	Text       string
+	Start, End int
+}
+
+// CompletionProvider suggests completions for the text of an Editor.
+type CompletionProvider interface {
+	// Completions returns the completions for the text of e at the rune
+	// offset caret, in the order they are listed. The first completion is
+	// selected, and shown inline after the caret.
+	Completions(e *Editor, caret int) []Completion
+}
+
+// CompletionEvent is generated when a completion is accepted.
+type CompletionEvent struct {
+	Completion Completion
+}
+
+func (s CompletionEvent) isEditorEvent() {}
+
+// Completions returns the open list of completions, and the index of the
+// selected completion. The list is open until the caret moves other than by
+// typing, the Escape key is pressed, or a completion is accepted.
+func (e *Editor) Completions() (items []Completion, selected int) {
+	e.initBuffer()
+	return e.completion.items, e.completion.selected
+}
+
+// SetCompletions opens the list of completions for the text at the caret,
+// such as completions computed asynchronously, and selects the first
+// completion. An empty list closes the list.
+func (e *Editor) SetCompletions(items []Completion) {
+	e.initBuffer()
+	c := &e.completion
+	c.items = append(c.items[:0], items...)
+	c.selected = 0
+	c.caret, _ = e.text.Selection()
+}
+
+// SelectCompletion selects the completion at index i of the list returned by
+// Completions.
+func (e *Editor) SelectCompletion(i int) {
+	e.initBuffer()
+	if i >= 0 && i < len(e.completion.items) {
+		e.completion.selected = i
+	}
+}
+
+// CloseCompletions closes the list of completions.
+func (e *Editor) CloseCompletions() {
+	e.completion.items = e.completion.items[:0]
+}
+
+// AcceptCompletion replaces the text of the selected completion, closes the
+// list of completions, and reports whether a completion was selected.
+func (e *Editor) AcceptCompletion() bool {
+	_, ok := e.acceptCompletion()
+	return ok
+}
+
+func (e *Editor) acceptCompletion() (EditorEvent, bool) {
+	e.initBuffer()
+	c := &e.completion
+	if len(c.items) == 0 {
+		return nil, false
+	}
+	item := c.items[c.selected]
+	e.CloseCompletions()
+	e.BeginGroup()
+	n := e.replace(item.Start, item.End, item.Text, true)
+	// Reset xoff.
+	e.text.MoveCaret(0, 0)
+	e.setCaret(item.Start+n, item.Start+n)
+	e.EndGroup()
+	e.pending = append(e.pending, CompletionEvent{Completion: item})
+	return ChangeEvent{}, true
+}
+
+// InlineCompletion returns the text of the selected completion following
+// the text typed before the caret, for displaying after the caret. It
+// returns the empty string if the typed text is not a prefix of the
+// completion.
+func (e *Editor) InlineCompletion() string {
+	e.initBuffer()
+	c := &e.completion
+	if len(c.items) == 0 {
+		return ""
+	}
+	item := c.items[c.selected]
+	start, end := e.text.Selection()
+	if start != end || start < item.Start || start > item.End {
+		return ""
+	}
+	typed := make([]byte, e.text.ByteOffset(start)-e.text.ByteOffset(item.Start))
+	e.text.ReadAt(typed, e.text.ByteOffset(item.Start))
+	if !strings.HasPrefix(item.Text, string(typed)) {
+		return ""
+	}
+	return item.Text[len(typed):]
+}
+
+// queryCompletions requests completions from the Completer after the text
+// has been edited by the user. Otherwise, it closes the list of completions
+// if the caret has moved.
+func (e *Editor) queryCompletions() {
+	c := &e.completion
+	caret, _ := e.text.Selection()
+	if c.query {
+		c.query = false
+		if e.Completer != nil {
+			e.SetCompletions(e.Completer.Completions(e, caret))
+		}
+		// Keep the list open while typing.
+		c.caret = caret
+		return
+	}
+	if len(c.items) > 0 && caret != c.caret {
+		e.CloseCompletions()
+	}
+}
+
+// completionKey handles the keys for choosing a completion while the list
+// of completions is open, and reports whether k was handled. If k accepted a
+// completion, it also returns the resulting event.
+func (e *Editor) completionKey(k key.Event) (ev EditorEvent, ok, handled bool) {
+	c := &e.completion
+	if len(c.items) == 0 || k.Modifiers != 0 {
+		return nil, false, false
+	}
+	switch k.Name {
+	case key.NameUpArrow:
+		c.selected = (c.selected + len(c.items) - 1) % len(c.items)
+	case key.NameDownArrow:
+		c.selected = (c.selected + 1) % len(c.items)
+	case key.NameTab, key.NameReturn, key.NameEnter:
+		ev, ok = e.acceptCompletion()
+		return ev, ok, true
+	case key.NameEscape:
+		e.CloseCompletions()
+	default:
+		return nil, false, false
+	}
+	return nil, false, true
+}
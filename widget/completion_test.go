@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"gioui.org/font"
+	"gioui.org/font/gofont"
+	"gioui.org/io/event"
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+)
+
+// wordCompleter completes the word before the caret with words starting
+// with it.
+type wordCompleter []string
+
+func (w wordCompleter) Completions(e *Editor, caret int) []Completion {
+	txt := []rune(e.Text())[:caret]
+	start := caret
+	for start > 0 && txt[start-1] != ' ' {
+		start--
+	}
+	prefix := string(txt[start:])
+	var items []Completion
+	for _, word := range w {
+		if prefix != "" && strings.HasPrefix(word, prefix) {
+			items = append(items, Completion{Text: word, Start: start, End: caret})
+		}
+	}
+	return items
+}
+
+func TestEditorCompletions(t *testing.T) {
+	r := new(input.Router)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(500, 500)),
+		Locale:      english,
+		Source:      r.Source(),
+	}
+	shaper := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	e := &Editor{Completer: wordCompleter{"golang", "gopher", "gorilla"}}
+	gtx.Execute(key.FocusCmd{Tag: e})
+	var events []EditorEvent
+	frame := func(evts ...event.Event) {
+		r.Queue(evts...)
+		gtx.Ops.Reset()
+		for {
+			ev, ok := e.Update(gtx)
+			if !ok {
+				break
+			}
+			events = append(events, ev)
+		}
+		e.Layout(gtx, shaper, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+		r.Frame(gtx.Ops)
+	}
+	typ := func(at int, s string) {
+		end := at + len(s)
+		frame(key.EditEvent{Range: key.Range{Start: at, End: at}, Text: s}, key.SelectionEvent{Start: end, End: end})
+	}
+	frame()
+	typ(0, "a go")
+	if items, selected := e.Completions(); len(items) != 3 || selected != 0 {
+		t.Fatalf("got completions %v, selected %d", items, selected)
+	}
+	if got := e.InlineCompletion(); got != "lang" {
+		t.Errorf("got inline completion %q, want %q", got, "lang")
+	}
+	typ(4, "p")
+	if items, _ := e.Completions(); len(items) != 1 {
+		t.Fatalf("got completions %v after typing", items)
+	}
+	frame(key.Event{Name: key.NameDeleteBackward, State: key.Press})
+	frame(key.Event{Name: key.NameDownArrow, State: key.Press})
+	if _, selected := e.Completions(); selected != 1 {
+		t.Errorf("got selection %d, want 1", selected)
+	}
+	events = events[:0]
+	frame(key.Event{Name: key.NameTab, State: key.Press})
+	assertContents(t, e, "a gopher", 8, 8)
+	if items, _ := e.Completions(); len(items) != 0 {
+		t.Errorf("completions open after accepting a completion")
+	}
+	accepted := false
+	for _, ev := range events {
+		if ev, ok := ev.(CompletionEvent); ok {
+			accepted = ev.Completion.Text == "gopher"
+		}
+	}
+	if !accepted {
+		t.Errorf("no completion event in %v", events)
+	}
+	// Escape and moving the caret close the list.
+	typ(8, " go")
+	frame(key.Event{Name: key.NameEscape, State: key.Press})
+	if items, _ := e.Completions(); len(items) != 0 {
+		t.Errorf("completions open after Escape")
+	}
+	typ(11, "r")
+	frame(key.Event{Name: key.NameLeftArrow, State: key.Press})
+	if items, _ := e.Completions(); len(items) != 0 {
+		t.Errorf("completions open after moving the caret")
+	}
+	e.SetCaret(12, 12)
+	frame(key.Event{Name: key.NameReturn, State: key.Press})
+	assertContents(t, e, "a gopher gor\n", 13, 13)
+}
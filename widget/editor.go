@@ -57,17 +57,51 @@ type Editor struct {
 	// Newline characters are not masked. When non-zero, the unmasked contents
 	// are accessed by Len, Text, and SetText.
 	Mask rune
+	// Features is a list of OpenType features to apply when shaping, in the
+	// format of text.Parameters.Features. Code editors may disable
+	// ligatures with "-liga, -calt".
+	Features string
+	// Variations sets the variation axes of variable fonts, in the format
+	// of text.Parameters.Variations.
+	Variations string
+	// TabStops are the positions that text following tab characters is
+	// aligned to, as described by text.Parameters.TabStops.
+	TabStops []TabStop
+	// TabWidth is the distance between the tab stops following TabStops.
+	// If zero and TabStops is empty, tab characters are not aligned.
+	TabWidth unit.Sp
+	// AutoIndent starts the lines inserted by the Return key with the
+	// indentation of the line they are inserted in.
+	AutoIndent bool
+	// TabSpaces, if positive, makes the Tab key insert spaces up to the next
+	// multiple of TabSpaces columns instead of moving the focus.
+	TabSpaces int
 	// InputHint specifies the type of on-screen keyboard to be displayed.
 	InputHint key.InputHint
 	// MaxLen limits the editor content to a maximum length. Zero means no limit.
 	MaxLen int
+	// MaxHistory limits the number of steps of the undo history. The oldest
+	// steps are discarded when the limit is exceeded. Zero means no limit.
+	MaxHistory int
 	// Filter is the list of characters allowed in the Editor. If Filter is empty,
 	// all characters are allowed.
 	Filter string
 	// WrapPolicy configures how displayed text will be broken into lines.
 	WrapPolicy text.WrapPolicy
-
-	buffer *editBuffer
+	// Format, if set, formats the text after every edit, such as by typing,
+	// deleting or pasting, as part of the same step of the undo history.
+	Format InputFormat
+	// Validate, if set, reports whether the text is valid, for Err.
+	Validate func(text string) error
+	// Checker, if set, annotates the paragraphs of the text after they
+	// change, such as a spell checker. Replacing the Checker checks the
+	// whole text again. The Checker must be comparable.
+	Checker Checker
+	// Completer, if set, is queried for completions of the text at the caret
+	// after the text is typed or deleted by the user.
+	Completer CompletionProvider
+
+	buffer TextSource
 	// scratch is a byte buffer that is reused to efficiently read portions of text
 	// from the textView.
 	scratch    []byte
@@ -84,6 +118,17 @@ type Editor struct {
 	scroller    gesture.Scroll
 	scrollCaret bool
 	showCaret   bool
+	// hover tracks the position of the pointer over the editor.
+	hover struct {
+		inside bool
+		pos    image.Point
+	}
+	// box tracks the rectangular selection of an Alt+drag.
+	box struct {
+		selecting bool
+		// anchor is the document position of the start of the drag.
+		anchor image.Point
+	}
 
 	clicker gesture.Click
 
@@ -93,6 +138,32 @@ type Editor struct {
 	// is only not len(history) immediately after undo operations occur. It is framed as the "next" value
 	// to make the zero value consistent.
 	nextHistoryIdx int
+	// group tracks the grouping of modifications into a single step of the
+	// undo history.
+	group struct {
+		// depth is the number of unfinished calls to BeginGroup.
+		depth int
+		// recorded is set if a modification of the group was recorded.
+		recorded bool
+	}
+	// caretEdits counts the edits at multiple carets, and caretEdit is the
+	// edit in progress, or zero.
+	caretEdits, caretEdit int
+
+	// checker is the Checker that annotated the text.
+	checker Checker
+
+	// formatPending is set if the text is to be formatted by Format.
+	formatPending bool
+	// completion tracks the open list of completions.
+	completion struct {
+		items    []Completion
+		selected int
+		// caret is the position of the caret the completions are for.
+		caret int
+		// query is set if the Completer is to be queried.
+		query bool
+	}
 
 	pending []EditorEvent
 }
@@ -249,6 +320,16 @@ func (e *Editor) processPointer(gtx layout.Context) (EditorEvent, bool) {
 		e.text.ScrollRel(0, sdist)
 		soff = e.text.ScrollOff().Y
 	}
+	for {
+		evt, ok := gtx.Event(pointer.Filter{Target: e, Kinds: pointer.Move | pointer.Enter | pointer.Leave})
+		if !ok {
+			break
+		}
+		if evt, ok := evt.(pointer.Event); ok {
+			e.hover.inside = evt.Kind != pointer.Leave
+			e.hover.pos = evt.Position.Round()
+		}
+	}
 	for {
 		evt, ok := e.clicker.Update(gtx.Source)
 		if !ok {
@@ -283,6 +364,9 @@ func (e *Editor) processPointerEvent(gtx layout.Context, ev event.Event) (Editor
 		case evt.Kind == gesture.KindPress && evt.Source == pointer.Mouse,
 			evt.Kind == gesture.KindClick && evt.Source != pointer.Mouse:
 			prevCaretPos, _ := e.text.Selection()
+			if evt.Modifiers == key.ModAlt {
+				e.text.pushCaret()
+			}
 			e.blinkStart = gtx.Now
 			e.text.MoveCoord(image.Point{
 				X: int(math.Round(float64(evt.Position.X))),
@@ -296,14 +380,22 @@ func (e *Editor) processPointerEvent(gtx layout.Context, ev event.Event) (Editor
 				e.scrollCaret = true
 			}
 
-			if evt.Modifiers == key.ModShift {
+			e.box.selecting = evt.Modifiers == key.ModAlt && evt.Source == pointer.Mouse
+			e.box.anchor = evt.Position.Add(e.text.ScrollOff())
+			switch evt.Modifiers {
+			case key.ModShift:
 				start, end := e.text.Selection()
 				// If they clicked closer to the end, then change the end to
 				// where the caret used to be (effectively swapping start & end).
 				if abs(end-start) < abs(start-prevCaretPos) {
 					e.text.SetCaret(start, prevCaretPos)
 				}
-			} else {
+			case key.ModAlt:
+				// Add a caret, keeping the caret that was moved.
+				e.text.ClearSelection()
+				e.text.mergeCarets()
+			default:
+				e.text.ClearCarets()
 				e.text.ClearSelection()
 			}
 			e.dragging = true
@@ -329,10 +421,17 @@ func (e *Editor) processPointerEvent(gtx layout.Context, ev event.Event) (Editor
 		case evt.Kind == pointer.Drag && evt.Source == pointer.Mouse:
 			if e.dragging {
 				e.blinkStart = gtx.Now
-				e.text.MoveCoord(image.Point{
+				pos := image.Point{
 					X: int(math.Round(float64(evt.Position.X))),
 					Y: int(math.Round(float64(evt.Position.Y))),
-				})
+				}
+				if e.box.selecting {
+					if pos := pos.Add(e.text.ScrollOff()); pos != e.box.anchor {
+						e.text.SelectBox(e.box.anchor, pos)
+					}
+				} else {
+					e.text.MoveCoord(pos)
+				}
 				e.scrollCaret = true
 
 				if release {
@@ -357,8 +456,10 @@ func (e *Editor) processKey(gtx layout.Context) (EditorEvent, bool) {
 		return ChangeEvent{}, true
 	}
 	caret, _ := e.text.Selection()
-	atBeginning := caret == 0
-	atEnd := caret == e.text.Len()
+	multi := e.text.CaretCount() > 1
+	atBeginning := caret == 0 && !multi
+	atEnd := caret == e.text.Len() && !multi
+	completing := len(e.completion.items) > 0
 	if gtx.Locale.Direction.Progression() != system.FromOrigin {
 		atEnd, atBeginning = atBeginning, atEnd
 	}
@@ -373,6 +474,9 @@ func (e *Editor) processKey(gtx layout.Context) (EditorEvent, bool) {
 		key.Filter{Focus: e, Name: "V", Required: key.ModShortcut},
 		key.Filter{Focus: e, Name: "X", Required: key.ModShortcut},
 		key.Filter{Focus: e, Name: "A", Required: key.ModShortcut},
+		key.Filter{Focus: e, Name: "D", Required: key.ModShortcut},
+		condFilter(multi || completing, key.Filter{Focus: e, Name: key.NameEscape}),
+		condFilter(e.TabSpaces > 0 && !e.ReadOnly || completing, key.Filter{Focus: e, Name: key.NameTab}),
 
 		key.Filter{Focus: e, Name: key.NameDeleteBackward, Optional: key.ModShortcutAlt | key.ModShift},
 		key.Filter{Focus: e, Name: key.NameDeleteForward, Optional: key.ModShortcutAlt | key.ModShift},
@@ -382,17 +486,29 @@ func (e *Editor) processKey(gtx layout.Context) (EditorEvent, bool) {
 		key.Filter{Focus: e, Name: key.NamePageDown, Optional: key.ModShift},
 		key.Filter{Focus: e, Name: key.NamePageUp, Optional: key.ModShift},
 		condFilter(!atBeginning, key.Filter{Focus: e, Name: key.NameLeftArrow, Optional: key.ModShortcutAlt | key.ModShift}),
-		condFilter(!atBeginning, key.Filter{Focus: e, Name: key.NameUpArrow, Optional: key.ModShortcutAlt | key.ModShift}),
+		condFilter(!atBeginning || completing, key.Filter{Focus: e, Name: key.NameUpArrow, Optional: key.ModShortcutAlt | key.ModShift}),
 		condFilter(!atEnd, key.Filter{Focus: e, Name: key.NameRightArrow, Optional: key.ModShortcutAlt | key.ModShift}),
-		condFilter(!atEnd, key.Filter{Focus: e, Name: key.NameDownArrow, Optional: key.ModShortcutAlt | key.ModShift}),
+		condFilter(!atEnd || completing, key.Filter{Focus: e, Name: key.NameDownArrow, Optional: key.ModShortcutAlt | key.ModShift}),
 	}
 	// adjust keeps track of runes dropped because of MaxLen.
 	var adjust int
+	// An input method edit is formatted after the selection following it,
+	// which is in the positions of the unformatted text.
+	grouped := false
+	endGroup := func() {
+		if grouped {
+			grouped = false
+			e.EndGroup()
+		}
+	}
 	for {
 		ke, ok := gtx.Event(filters...)
 		if !ok {
 			break
 		}
+		if _, ok := ke.(key.SelectionEvent); !ok {
+			endGroup()
+		}
 		e.blinkStart = gtx.Now
 		switch ke := ke.(type) {
 		case key.FocusEvent:
@@ -405,6 +521,14 @@ func (e *Editor) processKey(gtx layout.Context) (EditorEvent, bool) {
 			if !gtx.Focused(e) || ke.State != key.Press {
 				break
 			}
+			// The list of completions takes precedence over the editor
+			// commands.
+			if ev, ok, handled := e.completionKey(ke); handled {
+				if ok {
+					return ev, ok
+				}
+				break
+			}
 			if !e.ReadOnly && e.Submit && (ke.Name == key.NameReturn || ke.Name == key.NameEnter) {
 				if !ke.Modifiers.Contain(key.ModShift) {
 					e.scratch = e.text.Text(e.scratch)
@@ -427,6 +551,7 @@ func (e *Editor) processKey(gtx layout.Context) (EditorEvent, bool) {
 			}
 			e.scrollCaret = true
 			e.scroller.Stop()
+			e.completion.query = true
 			s := ke.Text
 			moves := 0
 			submit := false
@@ -440,11 +565,22 @@ func (e *Editor) processKey(gtx layout.Context) (EditorEvent, bool) {
 			case e.SingleLine:
 				s = strings.ReplaceAll(s, "\n", " ")
 			}
-			moves += e.replace(ke.Range.Start, ke.Range.End, s, true)
+			if e.Format != nil {
+				e.BeginGroup()
+				grouped = true
+			}
+			if start, end := e.text.Selection(); e.text.CaretCount() > 1 && ke.Range == (key.Range{Start: min(start, end), End: max(start, end)}) {
+				// Replace the selection of every caret.
+				e.Insert(s)
+				moves += utf8.RuneCountInString(s)
+			} else {
+				moves += e.replace(ke.Range.Start, ke.Range.End, s, true)
+			}
 			adjust += utf8.RuneCountInString(ke.Text) - moves
 			// Reset caret xoff.
 			e.text.MoveCaret(0, 0)
 			if submit {
+				endGroup()
 				e.scratch = e.text.Text(e.scratch)
 				submitEvent := SubmitEvent{
 					Text: string(e.scratch),
@@ -474,6 +610,7 @@ func (e *Editor) processKey(gtx layout.Context) (EditorEvent, bool) {
 			e.text.SetCaret(ke.Start, ke.End)
 		}
 	}
+	endGroup()
 	if e.text.Changed() {
 		return ChangeEvent{}, true
 	}
@@ -498,20 +635,32 @@ func (e *Editor) command(gtx layout.Context, k key.Event) (EditorEvent, bool) {
 			if !e.ReadOnly {
 				gtx.Execute(clipboard.ReadCmd{Tag: e})
 			}
-		// Copy or Cut selection -- ignored if nothing selected.
+		// Copy or Cut selection -- ignored if nothing selected. The
+		// selections of multiple carets are copied on separate lines.
 		case "C", "X":
-			e.scratch = e.text.SelectedText(e.scratch)
+			e.scratch = e.text.selectionsText(e.scratch)
 			if text := string(e.scratch); text != "" {
 				gtx.Execute(clipboard.WriteCmd{Type: "application/text", Data: io.NopCloser(strings.NewReader(text))})
 				if k.Name == "X" && !e.ReadOnly {
-					if e.Delete(1) != 0 {
+					// Cut only the carets with a selection.
+					cut := e.editCarets(func() int {
+						if start, end := e.text.Selection(); start == end {
+							return 0
+						}
+						return e.delete(1)
+					})
+					if cut != 0 {
 						return ChangeEvent{}, true
 					}
 				}
 			}
 		// Select all
 		case "A":
+			e.text.ClearCarets()
 			e.text.SetCaret(0, e.text.Len())
+		// Select the next occurrence of the selection.
+		case "D":
+			e.text.SelectNextOccurrence()
 		case "Z":
 			if !e.ReadOnly {
 				if k.Modifiers.Contain(key.ModShift) {
@@ -525,23 +674,31 @@ func (e *Editor) command(gtx layout.Context, k key.Event) (EditorEvent, bool) {
 				}
 			}
 		case key.NameHome:
-			e.text.MoveTextStart(selAct)
+			e.text.forEachCaret(func() { e.text.MoveTextStart(selAct) })
 		case key.NameEnd:
-			e.text.MoveTextEnd(selAct)
+			e.text.forEachCaret(func() { e.text.MoveTextEnd(selAct) })
 		}
 		return nil, false
 	}
-	switch k.Name {
+	switch verticalKey(gtx, k.Name) {
 	case key.NameReturn, key.NameEnter:
 		if !e.ReadOnly {
-			if e.Insert("\n") != 0 {
+			if e.editCarets(e.insertNewline) != 0 {
+				return ChangeEvent{}, true
+			}
+		}
+	case key.NameTab:
+		if !e.ReadOnly && e.TabSpaces > 0 {
+			if e.editCarets(e.insertTab) != 0 {
 				return ChangeEvent{}, true
 			}
 		}
 	case key.NameDeleteBackward:
 		if !e.ReadOnly {
+			// Update the open list of completions.
+			e.completion.query = len(e.completion.items) > 0
 			if moveByWord {
-				if e.deleteWord(-1) != 0 {
+				if e.editCarets(func() int { return e.deleteWord(-1) }) != 0 {
 					return ChangeEvent{}, true
 				}
 			} else {
@@ -553,7 +710,7 @@ func (e *Editor) command(gtx layout.Context, k key.Event) (EditorEvent, bool) {
 	case key.NameDeleteForward:
 		if !e.ReadOnly {
 			if moveByWord {
-				if e.deleteWord(1) != 0 {
+				if e.editCarets(func() int { return e.deleteWord(1) }) != 0 {
 					return ChangeEvent{}, true
 				}
 			} else {
@@ -563,35 +720,47 @@ func (e *Editor) command(gtx layout.Context, k key.Event) (EditorEvent, bool) {
 			}
 		}
 	case key.NameUpArrow:
-		e.text.MoveLines(-1, selAct)
+		e.text.forEachCaret(func() { e.text.MoveLines(-1, selAct) })
 	case key.NameDownArrow:
-		e.text.MoveLines(+1, selAct)
+		e.text.forEachCaret(func() { e.text.MoveLines(+1, selAct) })
 	case key.NameLeftArrow:
-		if moveByWord {
-			e.text.MoveWord(-1*direction, selAct)
-		} else {
-			if selAct == selectionClear {
-				e.text.ClearSelection()
+		e.text.forEachCaret(func() {
+			if moveByWord {
+				e.text.MoveWord(-1*direction, selAct)
+			} else {
+				if selAct == selectionClear {
+					e.text.ClearSelection()
+				}
+				e.text.MoveCaret(-1*direction, -1*direction*int(selAct))
+				if selAct == selectionClear {
+					e.skipLiterals(-1 * direction)
+				}
 			}
-			e.text.MoveCaret(-1*direction, -1*direction*int(selAct))
-		}
+		})
 	case key.NameRightArrow:
-		if moveByWord {
-			e.text.MoveWord(1*direction, selAct)
-		} else {
-			if selAct == selectionClear {
-				e.text.ClearSelection()
+		e.text.forEachCaret(func() {
+			if moveByWord {
+				e.text.MoveWord(1*direction, selAct)
+			} else {
+				if selAct == selectionClear {
+					e.text.ClearSelection()
+				}
+				e.text.MoveCaret(1*direction, int(selAct)*direction)
+				if selAct == selectionClear {
+					e.skipLiterals(1 * direction)
+				}
 			}
-			e.text.MoveCaret(1*direction, int(selAct)*direction)
-		}
+		})
 	case key.NamePageUp:
-		e.text.MovePages(-1, selAct)
+		e.text.forEachCaret(func() { e.text.MovePages(-1, selAct) })
 	case key.NamePageDown:
-		e.text.MovePages(+1, selAct)
+		e.text.forEachCaret(func() { e.text.MovePages(+1, selAct) })
 	case key.NameHome:
-		e.text.MoveLineStart(selAct)
+		e.text.forEachCaret(func() { e.text.MoveLineStart(selAct) })
 	case key.NameEnd:
-		e.text.MoveLineEnd(selAct)
+		e.text.forEachCaret(func() { e.text.MoveLineEnd(selAct) })
+	case key.NameEscape:
+		e.text.ClearCarets()
 	}
 	return nil, false
 }
@@ -601,15 +770,22 @@ func (e *Editor) command(gtx layout.Context, k key.Event) (EditorEvent, bool) {
 // and has its fields synced with the editor.
 func (e *Editor) initBuffer() {
 	if e.buffer == nil {
-		e.buffer = new(editBuffer)
+		e.buffer = new(pieceTable)
 		e.text.SetSource(e.buffer)
 	}
+	for _, c := range e.text.applyChanges() {
+		e.mapHistory(c)
+	}
 	e.text.Alignment = e.Alignment
 	e.text.LineHeight = e.LineHeight
 	e.text.LineHeightScale = e.LineHeightScale
 	e.text.SingleLine = e.SingleLine
 	e.text.Mask = e.Mask
 	e.text.WrapPolicy = e.WrapPolicy
+	e.text.Features = e.Features
+	e.text.Variations = e.Variations
+	e.text.TabStops = e.TabStops
+	e.text.TabWidth = e.TabWidth
 }
 
 // Update the state of the editor in response to input events. Update consumes editor
@@ -619,6 +795,8 @@ func (e *Editor) initBuffer() {
 func (e *Editor) Update(gtx layout.Context) (EditorEvent, bool) {
 	e.initBuffer()
 	event, ok := e.processEvents(gtx)
+	e.checkText()
+	e.queryCompletions()
 	// Notify IME of selection if it changed.
 	newSel := e.ime.selection
 	start, end := e.text.Selection()
@@ -643,7 +821,8 @@ func (e *Editor) Update(gtx layout.Context) (EditorEvent, bool) {
 
 // Layout lays out the editor using the provided textMaterial as the paint material
 // for the text glyphs+caret and the selectMaterial as the paint material for the
-// selection rectangle.
+// selection rectangle. If the locale of gtx has a vertical text direction, the
+// text is laid out in columns.
 func (e *Editor) Layout(gtx layout.Context, lt *text.Shaper, font font.Font, size unit.Sp, textMaterial, selectMaterial op.CallOp) layout.Dimensions {
 	for {
 		_, ok := e.Update(gtx)
@@ -652,6 +831,12 @@ func (e *Editor) Layout(gtx layout.Context, lt *text.Shaper, font font.Font, siz
 		}
 	}
 
+	if verticalText(gtx) {
+		return layoutVertical(gtx, func(gtx layout.Context) layout.Dimensions {
+			e.text.Layout(gtx, lt, font, size)
+			return e.layout(gtx, textMaterial, selectMaterial)
+		})
+	}
 	e.text.Layout(gtx, lt, font, size)
 	return e.layout(gtx, textMaterial, selectMaterial)
 }
@@ -732,6 +917,8 @@ func (e *Editor) layout(gtx layout.Context, textMaterial, selectMaterial op.Call
 	}
 	semantic.Editor.Add(gtx.Ops)
 	if e.Len() > 0 {
+		e.paintBackgrounds(gtx)
+		e.paintMatches(gtx)
 		e.paintSelection(gtx, selectMaterial)
 		e.paintText(gtx, textMaterial)
 	}
@@ -741,6 +928,12 @@ func (e *Editor) layout(gtx layout.Context, textMaterial, selectMaterial op.Call
 	return visibleDims
 }
 
+// paintBackgrounds paints the backgrounds of the styled ranges of the text.
+func (e *Editor) paintBackgrounds(gtx layout.Context) {
+	e.initBuffer()
+	e.text.PaintBackgrounds(gtx)
+}
+
 // paintSelection paints the contrasting background for selected text using the provided
 // material to set the painting material for the selection.
 func (e *Editor) paintSelection(gtx layout.Context, material op.CallOp) {
@@ -781,14 +974,50 @@ func (e *Editor) Text() string {
 	return string(e.scratch)
 }
 
+// SetStyles replaces the styled ranges of the text, such as the tokens of a
+// syntax highlighter. The ranges are sorted by their start, and clipped such
+// that they don't overlap. Edits of the text move, shrink and remove the
+// ranges to keep them attached to the styled text, and text inserted at the
+// start of a range or within it is included in the range.
+func (e *Editor) SetStyles(styles []TextStyle) {
+	e.initBuffer()
+	e.text.SetStyles(styles)
+}
+
+// Styles returns the styled ranges of the text, adjusted for the edits since
+// the last call to SetStyles.
+func (e *Editor) Styles() []TextStyle {
+	e.initBuffer()
+	return e.text.Styles()
+}
+
+// SetSource replaces the storage of the editor contents with src, such as
+// the document model of the application. The editor reads the contents from
+// src and edits them with its ReplaceRunes method. If src is a
+// ChangeReporter, the editor follows the changes it reports. SetSource clears
+// the undo history, styled ranges and annotations, and moves the caret to the
+// beginning of the text.
+func (e *Editor) SetSource(src TextSource) {
+	if r, ok := src.(ChangeReporter); ok {
+		// Changes made before src was set are already part of its contents.
+		r.Changes()
+	}
+	e.buffer = src
+	e.text.SetSource(src)
+	e.clearHistory()
+	e.SetCaret(0, 0)
+}
+
 func (e *Editor) SetText(s string) {
 	e.initBuffer()
 	if e.SingleLine {
 		s = strings.ReplaceAll(s, "\n", " ")
 	}
+	e.BeginGroup()
 	e.replace(0, e.text.Len(), s, true)
 	// Reset xoff and move the caret to the beginning.
 	e.SetCaret(0, 0)
+	e.EndGroup()
 }
 
 // CaretPos returns the line & column numbers of the caret.
@@ -808,9 +1037,17 @@ func (e *Editor) CaretCoords() f32.Point {
 // direction to delete: positive is forward, negative is backward.
 //
 // If there is a selection, it is deleted and counts as a single grapheme
-// cluster.
+// cluster. If there are multiple carets, runes are deleted at every caret in
+// a single step of the undo history.
 func (e *Editor) Delete(graphemeClusters int) (deletedRunes int) {
 	e.initBuffer()
+	return e.editCarets(func() int {
+		return e.delete(graphemeClusters)
+	})
+}
+
+// delete is like Delete for the primary caret only.
+func (e *Editor) delete(graphemeClusters int) (deletedRunes int) {
 	if graphemeClusters == 0 {
 		return 0
 	}
@@ -827,15 +1064,25 @@ func (e *Editor) Delete(graphemeClusters int) (deletedRunes int) {
 	e.replace(start, end, "", true)
 	// Reset xoff.
 	e.text.MoveCaret(0, 0)
-	e.ClearSelection()
+	e.text.ClearSelection()
 	return end - start
 }
 
+// Insert replaces the selection with s, or inserts s at the caret if there is
+// no selection. If there are multiple carets, s is inserted at every caret in
+// a single step of the undo history.
 func (e *Editor) Insert(s string) (insertedRunes int) {
 	e.initBuffer()
 	if e.SingleLine {
 		s = strings.ReplaceAll(s, "\n", " ")
 	}
+	return e.editCarets(func() int {
+		return e.insert(s)
+	})
+}
+
+// insert is like Insert for the primary caret only.
+func (e *Editor) insert(s string) (insertedRunes int) {
 	start, end := e.text.Selection()
 	moves := e.replace(start, end, s, true)
 	if end < start {
@@ -843,11 +1090,28 @@ func (e *Editor) Insert(s string) (insertedRunes int) {
 	}
 	// Reset xoff.
 	e.text.MoveCaret(0, 0)
-	e.SetCaret(start+moves, start+moves)
-	e.scrollCaret = true
+	e.setCaret(start+moves, start+moves)
 	return moves
 }
 
+// editCarets calls edit for every caret, with the caret in place of the
+// primary caret. The modifications are recorded as a single step of the undo
+// history. It returns the sum of the results of edit.
+func (e *Editor) editCarets(edit func() int) int {
+	n := 0
+	e.BeginGroup()
+	if e.text.CaretCount() > 1 {
+		e.caretEdits++
+		e.caretEdit = e.caretEdits
+	}
+	e.text.forEachCaret(func() {
+		n += edit()
+	})
+	e.caretEdit = 0
+	e.EndGroup()
+	return n
+}
+
 // modification represents a change to the contents of the editor buffer.
 // It contains the necessary information to both apply the change and
 // reverse it, and is useful for implementing undo/redo.
@@ -861,6 +1125,13 @@ type modification struct {
 	// ReverseContent is the data inserted at StartRune to
 	// apply this operation. It overwrites len([]rune(ApplyContent)) runes.
 	ReverseContent string
+	// Linked is set if the modification is part of the same edit as the
+	// modification before it, such as the edits at multiple carets.
+	Linked bool
+	// CaretEdit identifies the edit at multiple carets that the
+	// modification is part of, or is zero. Undoing and redoing the edit
+	// restores a caret for every modification.
+	CaretEdit int
 }
 
 // undo applies the modification at e.history[e.historyIdx] and decrements
@@ -870,12 +1141,24 @@ func (e *Editor) undo() (EditorEvent, bool) {
 	if len(e.history) < 1 || e.nextHistoryIdx == 0 {
 		return nil, false
 	}
-	mod := e.history[e.nextHistoryIdx-1]
-	replaceEnd := mod.StartRune + utf8.RuneCountInString(mod.ApplyContent)
-	e.replace(mod.StartRune, replaceEnd, mod.ReverseContent, false)
-	caretEnd := mod.StartRune + utf8.RuneCountInString(mod.ReverseContent)
-	e.SetCaret(caretEnd, mod.StartRune)
-	e.nextHistoryIdx--
+	e.text.ClearCarets()
+	prev := 0
+	for first := true; ; first = false {
+		mod := e.history[e.nextHistoryIdx-1]
+		replaceEnd := mod.StartRune + utf8.RuneCountInString(mod.ApplyContent)
+		e.replace(mod.StartRune, replaceEnd, mod.ReverseContent, false)
+		caretEnd := mod.StartRune + utf8.RuneCountInString(mod.ReverseContent)
+		if !first && mod.CaretEdit != 0 && mod.CaretEdit == prev {
+			e.text.AddCaret(caretEnd, mod.StartRune)
+		} else {
+			e.setCaret(caretEnd, mod.StartRune)
+		}
+		prev = mod.CaretEdit
+		e.nextHistoryIdx--
+		if !mod.Linked {
+			break
+		}
+	}
 	return ChangeEvent{}, true
 }
 
@@ -886,12 +1169,21 @@ func (e *Editor) redo() (EditorEvent, bool) {
 	if len(e.history) < 1 || e.nextHistoryIdx == len(e.history) {
 		return nil, false
 	}
-	mod := e.history[e.nextHistoryIdx]
-	end := mod.StartRune + utf8.RuneCountInString(mod.ReverseContent)
-	e.replace(mod.StartRune, end, mod.ApplyContent, false)
-	caretEnd := mod.StartRune + utf8.RuneCountInString(mod.ApplyContent)
-	e.SetCaret(caretEnd, mod.StartRune)
-	e.nextHistoryIdx++
+	e.text.ClearCarets()
+	prev := 0
+	for first := true; first || e.nextHistoryIdx < len(e.history) && e.history[e.nextHistoryIdx].Linked; first = false {
+		mod := e.history[e.nextHistoryIdx]
+		end := mod.StartRune + utf8.RuneCountInString(mod.ReverseContent)
+		e.replace(mod.StartRune, end, mod.ApplyContent, false)
+		caretEnd := mod.StartRune + utf8.RuneCountInString(mod.ApplyContent)
+		if !first && mod.CaretEdit != 0 && mod.CaretEdit == prev {
+			e.text.AddCaret(caretEnd, mod.StartRune)
+		} else {
+			e.setCaret(caretEnd, mod.StartRune)
+		}
+		prev = mod.CaretEdit
+		e.nextHistoryIdx++
+	}
 	return ChangeEvent{}, true
 }
 
@@ -940,8 +1232,13 @@ func (e *Editor) replace(start, end int, s string, addHistory bool) int {
 			StartRune:      start,
 			ApplyContent:   s,
 			ReverseContent: string(deleted),
+			Linked:         e.group.depth > 0 && e.group.recorded,
+			CaretEdit:      e.caretEdit,
 		})
 		e.nextHistoryIdx++
+		e.group.recorded = e.group.depth > 0
+		e.trimHistory()
+		e.formatPending = true
 	}
 
 	sc = e.text.Replace(start, end, s)
@@ -968,7 +1265,9 @@ func (e *Editor) replace(start, end int, s string, addHistory bool) int {
 // characters are multiple code points long.
 func (e *Editor) MoveCaret(startDelta, endDelta int) {
 	e.initBuffer()
-	e.text.MoveCaret(startDelta, endDelta)
+	e.text.forEachCaret(func() {
+		e.text.MoveCaret(startDelta, endDelta)
+	})
 }
 
 // deleteWord deletes the next word(s) in the specified direction.
@@ -983,7 +1282,7 @@ func (e *Editor) deleteWord(distance int) (deletedRunes int) {
 
 	start, end := e.text.Selection()
 	if start != end {
-		deletedRunes = e.Delete(1)
+		deletedRunes = e.delete(1)
 		distance -= sign(distance)
 	}
 	if distance == 0 {
@@ -1027,7 +1326,7 @@ func (e *Editor) deleteWord(distance int) (deletedRunes int) {
 			runes += 1
 		}
 	}
-	deletedRunes += e.Delete(runes * direction)
+	deletedRunes += e.delete(runes * direction)
 	return deletedRunes
 }
 
@@ -1046,14 +1345,45 @@ func (e *Editor) Selection() (start, end int) {
 }
 
 // SetCaret moves the caret to start, and sets the selection end to end. start
-// and end are in runes, and represent offsets into the editor text.
+// and end are in runes, and represent offsets into the editor text. Other
+// carets are removed.
 func (e *Editor) SetCaret(start, end int) {
 	e.initBuffer()
+	e.text.ClearCarets()
+	e.setCaret(start, end)
+}
+
+// setCaret is like SetCaret, but keeps the other carets.
+func (e *Editor) setCaret(start, end int) {
 	e.text.SetCaret(start, end)
 	e.scrollCaret = true
 	e.scroller.Stop()
 }
 
+// AddCaret adds a caret at start, selecting the text to end, and makes it
+// the primary caret. The primary caret is the caret reported by Selection
+// and scrolled into view. Edits apply to every caret, and carets whose
+// selections overlap are merged.
+func (e *Editor) AddCaret(start, end int) {
+	e.initBuffer()
+	e.text.AddCaret(start, end)
+	e.scrollCaret = true
+	e.scroller.Stop()
+}
+
+// Carets returns the position of every caret, starting with the primary
+// caret.
+func (e *Editor) Carets() []Caret {
+	e.initBuffer()
+	return e.text.Carets()
+}
+
+// ClearCarets removes every caret but the primary caret.
+func (e *Editor) ClearCarets() {
+	e.initBuffer()
+	e.text.ClearCarets()
+}
+
 // SelectedText returns the currently selected text (if any) from the editor.
 func (e *Editor) SelectedText() string {
 	e.initBuffer()
@@ -1061,11 +1391,11 @@ func (e *Editor) SelectedText() string {
 	return string(e.scratch)
 }
 
-// ClearSelection clears the selection, by setting the selection end equal to
-// the selection start.
+// ClearSelection clears the selection of every caret, by setting the
+// selection end equal to the selection start.
 func (e *Editor) ClearSelection() {
 	e.initBuffer()
-	e.text.ClearSelection()
+	e.text.forEachCaret(e.text.ClearSelection)
 }
 
 // WriteTo implements io.WriterTo.
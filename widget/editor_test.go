@@ -9,6 +9,7 @@ import (
 	"io"
 	"math/rand"
 	"reflect"
+	"strings"
 	"testing"
 	"testing/quick"
 	"time"
@@ -1283,3 +1284,193 @@ func textBaseline(e *Editor, lineNum int) float32 {
 	start := e.text.closestToLineCol(lineNum, 0)
 	return float32(start.y)
 }
+
+// TestEditorTabStops ensures that the caret follows text aligned to tab stops.
+func TestEditorTabStops(t *testing.T) {
+	e := &Editor{
+		TabStops: []TabStop{{Position: 40}, {Position: 90, Alignment: text.TabEnd}},
+	}
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(200, 100)),
+		Locale:      english,
+	}
+	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	e.SetText("a\tb\tcd")
+	e.Layout(gtx, cache, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	e.SetCaret(2, 2)
+	if got := e.CaretCoords().X; got != 40 {
+		t.Errorf("caret after first tab at %v, want 40", got)
+	}
+	e.SetCaret(6, 6)
+	if got := e.CaretCoords().X; got != 90 {
+		t.Errorf("caret at end of tab stop at %v, want 90", got)
+	}
+	// Changing a stop in place lays out the text again.
+	e.TabStops[0].Position = 50
+	e.Layout(gtx, cache, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	e.SetCaret(2, 2)
+	if got := e.CaretCoords().X; got != 50 {
+		t.Errorf("caret after moved tab stop at %v, want 50", got)
+	}
+}
+
+// TestEditorHyphenation ensures that the caret positions of hyphenated text
+// map to the runes of the text.
+func TestEditorHyphenation(t *testing.T) {
+	h, err := text.NewHyphenator("hy3ph he2n hena4 hen5at 1na n2at 1tio 2io o2n", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()), text.WithHyphenation("en", h))
+	e := &Editor{WrapPolicy: text.WrapHyphenate}
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(90, 500)),
+		Locale:      english,
+	}
+	e.SetText("a hyphenation hyphenation")
+	e.Layout(gtx, cache, font.Font{}, unit.Sp(20), op.CallOp{}, op.CallOp{})
+	for _, tc := range []struct {
+		runes, line, col int
+	}{
+		{runes: 5, line: 1, col: 1},
+		{runes: 12, line: 2, col: 4},
+		{runes: 25, line: 4, col: 5},
+	} {
+		e.SetCaret(tc.runes, tc.runes)
+		if line, col := e.CaretPos(); line != tc.line || col != tc.col {
+			t.Errorf("caret at rune %d is at (%d, %d), want (%d, %d)", tc.runes, line, col, tc.line, tc.col)
+		}
+	}
+}
+
+// sharedSource is a TextSource changed by another user.
+type sharedSource struct {
+	pieceTable
+	changes []TextChange
+}
+
+func (s *sharedSource) Changes() []TextChange {
+	c := s.changes
+	s.changes = nil
+	return c
+}
+
+// remoteReplace replaces the bytes [start, end) with str, as if by another
+// user of the source.
+func (s *sharedSource) remoteReplace(start, end int64, str string) {
+	buf := make([]byte, end)
+	s.ReadAt(buf, 0)
+	s.replace(start, end, str)
+	s.changes = append(s.changes, TextChange{
+		Start:     start,
+		End:       end,
+		StartRune: utf8.RuneCount(buf[:start]),
+		Runes:     utf8.RuneCount(buf[start:]),
+		Size:      int64(len(str)),
+		NewRunes:  utf8.RuneCountInString(str),
+	})
+}
+
+// TestEditorSourceHistory ensures that the undo history follows the changes
+// of the source.
+func TestEditorSourceHistory(t *testing.T) {
+	src := new(sharedSource)
+	e := new(Editor)
+	e.SetSource(src)
+	e.SetText("hello world")
+	e.SetCaret(e.Len(), e.Len())
+	e.Insert("!")
+	e.SetCaret(0, 0)
+	e.Insert("Oh, ")
+	// Insert between the two edits, and undo them both.
+	src.remoteReplace(int64(len("Oh, hello")), int64(len("Oh, hello")), " big")
+	assertContents(t, e, "Oh, hello big world!", 4, 4)
+	if !e.Undo() {
+		t.Fatal("history discarded by a remote change")
+	}
+	if got, want := e.Text(), "hello big world!"; got != want {
+		t.Errorf("undo got %q, want %q", got, want)
+	}
+	e.Undo()
+	if got, want := e.Text(), "hello big world"; got != want {
+		t.Errorf("undo got %q, want %q", got, want)
+	}
+	// Redo follows the changes too.
+	src.remoteReplace(0, 0, "Well, ")
+	e.Redo()
+	e.Redo()
+	if got, want := e.Text(), "Well, Oh, hello big world!"; got != want {
+		t.Errorf("redo got %q, want %q", got, want)
+	}
+	// A change overlapping an edit discards it and the edits before it.
+	src.remoteReplace(int64(len("Well, Oh, hello big world")), int64(len("Well, Oh, hello big world!")), "?")
+	e.Undo()
+	if got, want := e.Text(), "Well, hello big world?"; got != want {
+		t.Errorf("undo of a discarded edit got %q, want %q", got, want)
+	}
+	if e.Undo() {
+		t.Error("edits before a remote change overlapping an edit not discarded")
+	}
+}
+
+// TestEditorSource ensures that the editor follows the changes of its source.
+func TestEditorSource(t *testing.T) {
+	src := new(sharedSource)
+	src.remoteReplace(0, 0, "stale")
+	e := new(Editor)
+	e.SetSource(src)
+	e.SetText("안П你 hello world")
+	if got := src.Size(); got != int64(len("안П你 hello world")) {
+		t.Errorf("source has %d bytes, want %d", got, len("안П你 hello world"))
+	}
+	e.SetCaret(12, 10)
+	// Insert before the selection.
+	src.remoteReplace(int64(len("안П你 ")), int64(len("안П你 ")), "big ")
+	assertContents(t, e, "안П你 big hello world", 16, 14)
+	if e.nextHistoryIdx != 0 || len(e.history) != 0 {
+		t.Error("history overlapping a remote change not discarded")
+	}
+	// Replace the text around the selection end.
+	src.remoteReplace(int64(len("안П你 big ")), int64(len("안П你 big hello w")), "П")
+	assertContents(t, e, "안П你 big Пorld", 10, 9)
+	// Edits after the selection don't move it.
+	src.remoteReplace(int64(len("안П你 big Пo")), int64(len("안П你 big Пorld")), "")
+	assertContents(t, e, "안П你 big Пo", 10, 9)
+	if _, ok := e.Update(layout.Context{Ops: new(op.Ops)}); !ok {
+		t.Error("no change event for remote changes")
+	}
+	// Changes are applied in order, including a change before an earlier
+	// one.
+	src.remoteReplace(int64(len("안П你 big ")), int64(len("안П你 big ")), "П")
+	src.remoteReplace(0, int64(len("안П你 ")), "")
+	assertContents(t, e, "big ППo", 7, 6)
+
+	// Large text is laid out on demand. Ensure remote changes lay out the
+	// changed paragraphs again.
+	var large strings.Builder
+	for large.Len() <= virtualSize {
+		fmt.Fprintf(&large, "line %d\n", large.Len())
+	}
+	e.SetText(large.String())
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(200, 200)),
+		Locale:      english,
+	}
+	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	e.Layout(gtx, cache, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	if !e.text.virt.enabled {
+		t.Fatal("large text not laid out on demand")
+	}
+	e.SetCaret(e.Len(), e.Len())
+	src.remoteReplace(0, int64(len("line 0")), "first\nsecond")
+	want := "first\nsecond" + large.String()[len("line 0"):]
+	assertContents(t, e, want, utf8.RuneCountInString(want), utf8.RuneCountInString(want))
+	e.Layout(gtx, cache, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	e.SetCaret(len("first\nsec"), len("first\nsec"))
+	if line, col := e.CaretPos(); line != 1 || col != 3 {
+		t.Errorf("caret after remote change at (%d, %d), want (1, 3)", line, col)
+	}
+}
@@ -0,0 +1,260 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// InputFormat is a structured format of the text of an Editor, such as a
+// number or a mask of fixed characters.
+type InputFormat interface {
+	// Format returns txt in the format, and the rune offset caret in txt
+	// moved to the formatted text.
+	Format(txt string, caret int) (string, int)
+	// Literal reports whether the rune at index i of the formatted text txt
+	// is a fixed character of the format, which the caret moves over.
+	Literal(txt string, i int) bool
+	// Validate returns an error if txt is formatted but not a complete or
+	// valid input, such as a number out of range.
+	Validate(txt string) error
+}
+
+// NumberFormat is an InputFormat of decimal numbers.
+type NumberFormat struct {
+	// Min and Max are the range of valid numbers, if Min is less than Max.
+	// Negative numbers can only be typed if Min is negative or the range is
+	// empty.
+	Min, Max float64
+	// Decimals is the number of decimal places allowed.
+	Decimals int
+}
+
+// MaskFormat is an InputFormat of text filling the slots of a pattern of
+// fixed characters, such as "(999) 999-9999" for phone numbers.
+type MaskFormat struct {
+	// Pattern is the mask of the text. The runes '9', 'a' and '*' are slots
+	// for a digit, a letter and a letter or digit respectively. Other runes
+	// are fixed characters, inserted as the slots after them are filled.
+	Pattern string
+}
+
+var (
+	errInvalidNumber = errors.New("invalid number")
+	errIncomplete    = errors.New("incomplete input")
+)
+
+func (f NumberFormat) Format(txt string, caret int) (string, int) {
+	negative := f.Min < 0 || f.Min >= f.Max
+	var b strings.Builder
+	n, newCaret := 0, 0
+	point, decimals := false, 0
+	for i, r := range []rune(txt) {
+		if i == caret {
+			newCaret = n
+		}
+		switch {
+		case r == '-' && n == 0 && negative:
+		case r == '.' && !point && f.Decimals > 0:
+			point = true
+		case r >= '0' && r <= '9' && (!point || decimals < f.Decimals):
+			if point {
+				decimals++
+			}
+		default:
+			continue
+		}
+		b.WriteRune(r)
+		n++
+	}
+	if caret >= len([]rune(txt)) {
+		newCaret = n
+	}
+	return b.String(), newCaret
+}
+
+func (f NumberFormat) Literal(txt string, i int) bool {
+	return false
+}
+
+func (f NumberFormat) Validate(txt string) error {
+	if txt == "" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(txt, 64)
+	if err != nil {
+		return errInvalidNumber
+	}
+	if f.Min < f.Max && (v < f.Min || v > f.Max) {
+		return fmt.Errorf("number out of range [%v, %v]", f.Min, f.Max)
+	}
+	return nil
+}
+
+// slot reports whether the pattern rune p is a slot, and whether r fills
+// it.
+func (f MaskFormat) slot(p, r rune) (slot, fits bool) {
+	switch p {
+	case '9':
+		return true, unicode.IsDigit(r)
+	case 'a':
+		return true, unicode.IsLetter(r)
+	case '*':
+		return true, unicode.IsDigit(r) || unicode.IsLetter(r)
+	}
+	return false, false
+}
+
+func (f MaskFormat) Format(txt string, caret int) (string, int) {
+	pattern := []rune(f.Pattern)
+	// Collect the runes filling slots, skipping the fixed characters in
+	// place.
+	var filled []rune
+	before := 0
+	p := 0
+	for i, r := range []rune(txt) {
+		for p < len(pattern) {
+			if slot, _ := f.slot(pattern[p], r); slot || pattern[p] == r {
+				break
+			}
+			p++
+		}
+		if p == len(pattern) {
+			break
+		}
+		slot, fits := f.slot(pattern[p], r)
+		if slot && !fits {
+			continue
+		}
+		p++
+		if slot {
+			filled = append(filled, r)
+			if i < caret {
+				before++
+			}
+		}
+	}
+	var b strings.Builder
+	n, newCaret := 0, -1
+	for _, pr := range pattern {
+		if len(filled) == 0 {
+			break
+		}
+		if slot, _ := f.slot(pr, 0); !slot {
+			b.WriteRune(pr)
+			n++
+			continue
+		}
+		b.WriteRune(filled[0])
+		filled = filled[1:]
+		n++
+		if before > 0 {
+			// Place the caret after the slot filled by the rune before it.
+			before--
+			newCaret = n
+		} else if newCaret == -1 {
+			newCaret = n - 1
+		}
+	}
+	return b.String(), max(newCaret, 0)
+}
+
+func (f MaskFormat) Literal(txt string, i int) bool {
+	pattern := []rune(f.Pattern)
+	if i < 0 || i >= len(pattern) {
+		return false
+	}
+	slot, _ := f.slot(pattern[i], 0)
+	return !slot
+}
+
+func (f MaskFormat) Validate(txt string) error {
+	if txt != "" && len([]rune(txt)) < len([]rune(f.Pattern)) {
+		return errIncomplete
+	}
+	return nil
+}
+
+// Err returns the error of validating the text with Format and Validate,
+// or nil if the text is valid.
+func (e *Editor) Err() error {
+	if e.Format == nil && e.Validate == nil {
+		return nil
+	}
+	txt := e.Text()
+	if e.Format != nil {
+		if err := e.Format.Validate(txt); err != nil {
+			return err
+		}
+	}
+	if e.Validate != nil {
+		return e.Validate(txt)
+	}
+	return nil
+}
+
+// applyFormat formats the text after the edits of the outermost group, as
+// part of the same step of the undo history. It is called by EndGroup before
+// the group ends.
+func (e *Editor) applyFormat() {
+	if !e.formatPending {
+		return
+	}
+	e.formatPending = false
+	if e.Format == nil {
+		return
+	}
+	e.scratch = e.text.Text(e.scratch)
+	txt := string(e.scratch)
+	caret, _ := e.text.Selection()
+	formatted, newCaret := e.Format.Format(txt, caret)
+	if formatted == txt && newCaret == caret {
+		return
+	}
+	if formatted != txt {
+		// Replace only the runes that differ.
+		from, to := []rune(txt), []rune(formatted)
+		start := 0
+		for start < len(from) && start < len(to) && from[start] == to[start] {
+			start++
+		}
+		end, newEnd := len(from), len(to)
+		for end > start && newEnd > start && from[end-1] == to[newEnd-1] {
+			end--
+			newEnd--
+		}
+		e.replace(start, end, string(to[start:newEnd]), true)
+		e.formatPending = false
+	}
+	e.text.ClearCarets()
+	e.text.SetCaret(newCaret, newCaret)
+}
+
+// skipLiterals continues a move of the caret without selection in the
+// direction dir over the fixed characters of the format, stopping next to
+// the slot that follows them.
+func (e *Editor) skipLiterals(dir int) {
+	start, end := e.text.Selection()
+	if e.Format == nil || start != end {
+		return
+	}
+	e.scratch = e.text.Text(e.scratch)
+	txt := string(e.scratch)
+	n := len([]rune(txt))
+	caret := start
+	switch {
+	case dir > 0:
+		for caret < n && e.Format.Literal(txt, caret) {
+			caret++
+		}
+	case dir < 0:
+		for caret > 0 && e.Format.Literal(txt, caret-1) {
+			caret--
+		}
+	}
+	e.text.SetCaret(caret, caret)
+}
@@ -0,0 +1,173 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"errors"
+	"image"
+	"testing"
+
+	"gioui.org/font"
+	"gioui.org/font/gofont"
+	"gioui.org/io/event"
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+)
+
+func TestInputFormats(t *testing.T) {
+	tests := []struct {
+		format    InputFormat
+		txt       string
+		caret     int
+		want      string
+		wantCaret int
+		wantErr   bool
+	}{
+		{format: NumberFormat{Decimals: 2}, txt: "-1a2.345", caret: 3, want: "-12.34", wantCaret: 2},
+		{format: NumberFormat{Min: 0, Max: 10}, txt: "-12.5", caret: 5, want: "125", wantCaret: 3, wantErr: true},
+		{format: NumberFormat{Min: -1, Max: 1, Decimals: 1}, txt: "-0.5", caret: 0, want: "-0.5", wantCaret: 0},
+		{format: NumberFormat{}, txt: "-", caret: 1, want: "-", wantCaret: 1, wantErr: true},
+		{format: MaskFormat{Pattern: "(999) 999-9999"}, txt: "5551234", caret: 4, want: "(555) 123-4", wantCaret: 7, wantErr: true},
+		{format: MaskFormat{Pattern: "(999) 999-9999"}, txt: "(555) 123-4x567", caret: 15, want: "(555) 123-4567", wantCaret: 14},
+		{format: MaskFormat{Pattern: "(999) 999-9999"}, txt: "(555)1", caret: 5, want: "(555) 1", wantCaret: 4, wantErr: true},
+		{format: MaskFormat{Pattern: "99/99/9999"}, txt: "1", caret: 0, want: "1", wantCaret: 0, wantErr: true},
+		{format: MaskFormat{Pattern: "99/99/9999"}, txt: "3112", caret: 0, want: "31/12", wantCaret: 0, wantErr: true},
+		{format: MaskFormat{Pattern: "+1 999"}, txt: "+1 2", caret: 4, want: "+1 2", wantCaret: 4, wantErr: true},
+		{format: MaskFormat{Pattern: "+1 999"}, txt: "+1 234", caret: 0, want: "+1 234", wantCaret: 3},
+	}
+	for _, test := range tests {
+		got, caret := test.format.Format(test.txt, test.caret)
+		if got != test.want || caret != test.wantCaret {
+			t.Errorf("%+v.Format(%q, %d) = %q, %d, want %q, %d", test.format, test.txt, test.caret, got, caret, test.want, test.wantCaret)
+		}
+		if err := test.format.Validate(got); (err != nil) != test.wantErr {
+			t.Errorf("%+v.Validate(%q) = %v", test.format, got, err)
+		}
+	}
+}
+
+func TestEditorFormat(t *testing.T) {
+	r := new(input.Router)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(500, 500)),
+		Locale:      english,
+		Source:      r.Source(),
+	}
+	shaper := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	e := &Editor{Format: MaskFormat{Pattern: "99/99/9999"}}
+	gtx.Execute(key.FocusCmd{Tag: e})
+	frame := func(evts ...event.Event) {
+		r.Queue(evts...)
+		gtx.Ops.Reset()
+		e.Layout(gtx, shaper, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+		r.Frame(gtx.Ops)
+	}
+	typ := func(s string) {
+		start, end := e.Selection()
+		frame(key.EditEvent{Range: key.Range{Start: start, End: end}, Text: s}, key.SelectionEvent{Start: start + len(s), End: start + len(s)})
+	}
+	frame()
+	for _, s := range []string{"3", "1", "1", "2"} {
+		typ(s)
+	}
+	assertContents(t, e, "31/12", 5, 5)
+	if err := e.Err(); !errors.Is(err, errIncomplete) {
+		t.Errorf("got error %v for incomplete input", err)
+	}
+	left := key.Event{Name: key.NameLeftArrow, State: key.Press}
+	right := key.Event{Name: key.NameRightArrow, State: key.Press}
+	frame(left)
+	assertContents(t, e, "31/12", 4, 4)
+	// The caret moves over fixed characters.
+	frame(left)
+	assertContents(t, e, "31/12", 2, 2)
+	frame(left)
+	assertContents(t, e, "31/12", 1, 1)
+	frame(right)
+	assertContents(t, e, "31/12", 3, 3)
+	frame(right)
+	assertContents(t, e, "31/12", 4, 4)
+	frame(key.Event{Name: key.NameDeleteBackward, State: key.Press})
+	assertContents(t, e, "31/2", 2, 2)
+	frame(key.Event{Name: key.NameDeleteBackward, State: key.Press})
+	assertContents(t, e, "32", 1, 1)
+	// The formatting is undone with the edit.
+	e.Undo()
+	assertContents(t, e, "31/2", 2, 1)
+	e.SetCaret(4, 4)
+	typ("02024")
+	assertContents(t, e, "31/20/2024", 10, 10)
+	e.Validate = func(txt string) error {
+		if txt[3:5] == "20" {
+			return errors.New("invalid month")
+		}
+		return nil
+	}
+	if err := e.Err(); err == nil || err.Error() != "invalid month" {
+		t.Errorf("got error %v, want invalid month", err)
+	}
+}
+
+func TestEditorFormatArrows(t *testing.T) {
+	r := new(input.Router)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(500, 500)),
+		Locale:      english,
+		Source:      r.Source(),
+	}
+	shaper := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	e := &Editor{Format: MaskFormat{Pattern: "(999) 999"}}
+	gtx.Execute(key.FocusCmd{Tag: e})
+	e.SetText("(555) 1")
+	frame := func(evts ...event.Event) {
+		r.Queue(evts...)
+		gtx.Ops.Reset()
+		e.Layout(gtx, shaper, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+		r.Frame(gtx.Ops)
+	}
+	left := key.Event{Name: key.NameLeftArrow, State: key.Press}
+	right := key.Event{Name: key.NameRightArrow, State: key.Press}
+	frame()
+	// The caret stops at the slots next to the fixed characters.
+	e.SetCaret(4, 4)
+	frame(right)
+	assertContents(t, e, "(555) 1", 6, 6)
+	frame(left)
+	assertContents(t, e, "(555) 1", 4, 4)
+	frame(left)
+	assertContents(t, e, "(555) 1", 3, 3)
+	e.SetCaret(1, 1)
+	frame(left)
+	assertContents(t, e, "(555) 1", 0, 0)
+	frame(right)
+	assertContents(t, e, "(555) 1", 1, 1)
+}
+
+func TestEditorFormatInsert(t *testing.T) {
+	e := &Editor{Format: MaskFormat{Pattern: "99/99/9999"}}
+	// Edits are formatted without a frame.
+	e.SetText("3112")
+	assertContents(t, e, "31/12", 0, 0)
+	e.SetCaret(5, 5)
+	e.Insert("2")
+	assertContents(t, e, "31/12/2", 7, 7)
+	if err := e.Err(); !errors.Is(err, errIncomplete) {
+		t.Errorf("got error %v for incomplete input", err)
+	}
+	e.Insert("024")
+	assertContents(t, e, "31/12/2024", 10, 10)
+	if err := e.Err(); err != nil {
+		t.Errorf("got error %v for complete input", err)
+	}
+	// The formatting is undone with the insertion.
+	e.Undo()
+	assertContents(t, e, "31/12/2", 7, 7)
+	e.Undo()
+	assertContents(t, e, "31/12", 5, 5)
+}
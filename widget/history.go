@@ -0,0 +1,181 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"unicode/utf8"
+
+	"golang.org/x/exp/slices"
+)
+
+// UndoHistory is a snapshot of the undo history of an Editor, such as for
+// restoring the history of a document when switching back to it.
+type UndoHistory struct {
+	mods []modification
+	next int
+}
+
+// Undo reverts the most recent step of the undo history. It reports whether
+// there was a step to undo.
+func (e *Editor) Undo() bool {
+	_, ok := e.undo()
+	return ok
+}
+
+// Redo applies the most recently undone step of the undo history again. It
+// reports whether there was a step to redo.
+func (e *Editor) Redo() bool {
+	_, ok := e.redo()
+	return ok
+}
+
+// CanUndo reports whether there is a step of the undo history to undo.
+func (e *Editor) CanUndo() bool {
+	e.initBuffer()
+	return e.nextHistoryIdx > 0
+}
+
+// CanRedo reports whether there is an undone step of the undo history to
+// redo.
+func (e *Editor) CanRedo() bool {
+	e.initBuffer()
+	return e.nextHistoryIdx < len(e.history)
+}
+
+// BeginGroup starts a group of edits that are undone and redone as a single
+// step of the undo history, until the matching call to EndGroup. Groups may
+// be nested, in which case the outermost group is the step.
+func (e *Editor) BeginGroup() {
+	if e.group.depth == 0 {
+		e.group.recorded = false
+	}
+	e.group.depth++
+}
+
+// EndGroup ends the group of edits started by the matching call to
+// BeginGroup. If the outermost group ends, its edits are formatted by the
+// Format of the editor.
+func (e *Editor) EndGroup() {
+	if e.group.depth == 1 {
+		e.applyFormat()
+	}
+	if e.group.depth > 0 {
+		e.group.depth--
+	}
+}
+
+// ClearHistory discards the undo history.
+func (e *Editor) ClearHistory() {
+	e.initBuffer()
+	e.clearHistory()
+}
+
+func (e *Editor) clearHistory() {
+	e.history = e.history[:0]
+	e.nextHistoryIdx = 0
+	e.group.recorded = false
+}
+
+// mapHistory adjusts the history to the change c of the text. The steps
+// overlapping c are discarded, along with the steps before them for undoing,
+// or after them for redoing.
+func (e *Editor) mapHistory(c TextChange) {
+	// The change is mapped through the modifications, from the text after
+	// the newest modification to undo back to the oldest.
+	start, end, newEnd := c.StartRune, c.StartRune+c.Runes, c.StartRune+c.NewRunes
+	for i := e.nextHistoryIdx - 1; i >= 0; i-- {
+		mod := &e.history[i]
+		applied, reversed := utf8.RuneCountInString(mod.ApplyContent), utf8.RuneCountInString(mod.ReverseContent)
+		switch {
+		case end <= mod.StartRune:
+			mod.StartRune += newEnd - end
+		case start >= mod.StartRune+applied:
+			diff := reversed - applied
+			start, end, newEnd = start+diff, end+diff, newEnd+diff
+		default:
+			// Discard the modification, its step, and the steps before it.
+			for i > 0 && e.history[i].Linked {
+				i--
+			}
+			// Keep the steps to redo.
+			n := i + 1
+			for n < e.nextHistoryIdx && e.history[n].Linked {
+				n++
+			}
+			e.history = e.history[:copy(e.history, e.history[n:])]
+			e.nextHistoryIdx -= n
+			e.group.recorded = false
+			i = -1
+		}
+	}
+	// Map the change forward through the modifications to redo.
+	start, end, newEnd = c.StartRune, c.StartRune+c.Runes, c.StartRune+c.NewRunes
+	for i := e.nextHistoryIdx; i < len(e.history); i++ {
+		mod := &e.history[i]
+		applied, reversed := utf8.RuneCountInString(mod.ApplyContent), utf8.RuneCountInString(mod.ReverseContent)
+		switch {
+		case end <= mod.StartRune:
+			mod.StartRune += newEnd - end
+		case start >= mod.StartRune+reversed:
+			diff := applied - reversed
+			start, end, newEnd = start+diff, end+diff, newEnd+diff
+		default:
+			// Discard the step of the modification, and the steps after it.
+			for i > e.nextHistoryIdx && e.history[i].Linked {
+				i--
+			}
+			e.history = e.history[:i]
+		}
+	}
+}
+
+// History returns a snapshot of the undo history.
+func (e *Editor) History() UndoHistory {
+	e.initBuffer()
+	return UndoHistory{
+		mods: slices.Clone(e.history),
+		next: e.nextHistoryIdx,
+	}
+}
+
+// SetHistory replaces the undo history with a snapshot returned by History.
+// The text of the editor must be the text it had when the snapshot was
+// taken.
+func (e *Editor) SetHistory(h UndoHistory) {
+	e.initBuffer()
+	e.history = slices.Clone(h.mods)
+	e.nextHistoryIdx = h.next
+	e.group.recorded = false
+	e.trimHistory()
+}
+
+// trimHistory discards the oldest steps of the history exceeding MaxHistory.
+func (e *Editor) trimHistory() {
+	if e.MaxHistory <= 0 {
+		return
+	}
+	steps := 0
+	for _, mod := range e.history {
+		if !mod.Linked {
+			steps++
+		}
+	}
+	n := 0
+	for ; steps > e.MaxHistory; steps-- {
+		// Discard the first step, and the modifications linked to it.
+		end := n + 1
+		for end < len(e.history) && e.history[end].Linked {
+			end++
+		}
+		if end > e.nextHistoryIdx {
+			// Keep the steps that can be redone.
+			break
+		}
+		n = end
+	}
+	if n == 0 {
+		return
+	}
+	e.history = e.history[:copy(e.history, e.history[n:])]
+	e.nextHistoryIdx -= n
+}
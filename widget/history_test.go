@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import "testing"
+
+func TestEditorUndoGroups(t *testing.T) {
+	e := new(Editor)
+	if e.CanUndo() || e.CanRedo() {
+		t.Fatal("empty editor can undo or redo")
+	}
+	e.Insert("hello")
+	e.BeginGroup()
+	e.Insert(",")
+	e.BeginGroup()
+	e.Insert(" world")
+	e.EndGroup()
+	e.SetCaret(0, 0)
+	e.Insert(">")
+	e.EndGroup()
+	assertContents(t, e, ">hello, world", 1, 1)
+	if !e.Undo() {
+		t.Fatal("group not undone")
+	}
+	assertContents(t, e, "hello", 5, 5)
+	if !e.CanRedo() || !e.Redo() {
+		t.Fatal("group not redone")
+	}
+	assertContents(t, e, ">hello, world", 1, 0)
+	e.Undo()
+	e.Undo()
+	assertContents(t, e, "", 0, 0)
+	if e.CanUndo() || e.Undo() {
+		t.Error("undid past the start of the history")
+	}
+}
+
+func TestEditorMaxHistory(t *testing.T) {
+	e := &Editor{MaxHistory: 2}
+	for _, s := range []string{"a", "b", "c"} {
+		e.Insert(s)
+	}
+	for e.Undo() {
+	}
+	assertContents(t, e, "a", 1, 1)
+	// Undone steps are kept.
+	e.MaxHistory = 1
+	e.SetHistory(e.History())
+	for e.Redo() {
+	}
+	assertContents(t, e, "abc", 3, 2)
+	e.SetCaret(3, 3)
+	e.Insert("d")
+	e.Undo()
+	if e.Undo() {
+		t.Error("undid a discarded step")
+	}
+	assertContents(t, e, "abc", 3, 3)
+}
+
+func TestEditorHistorySnapshot(t *testing.T) {
+	e := new(Editor)
+	e.SetText("first")
+	e.SetCaret(5, 5)
+	e.Insert(" document")
+	first := e.History()
+	e.SetText("second")
+	e.ClearHistory()
+	if e.CanUndo() {
+		t.Fatal("cleared history can undo")
+	}
+	e.SetText("first document")
+	e.SetHistory(first)
+	e.Undo()
+	assertContents(t, e, "first", 5, 5)
+	e.Undo()
+	assertContents(t, e, "", 0, 0)
+}
@@ -30,6 +30,9 @@ type glyphIndex struct {
 	// lines contains metadata about the size and position of each line of
 	// text.
 	lines []lineInfo
+	// firstLine is the line number of the first indexed line, and firstRune
+	// the rune offset of its start.
+	firstLine, firstRune int
 
 	// currentLineMin and currentLineMax track the dimensions of the line
 	// that is being indexed.
@@ -64,6 +67,18 @@ func (g *glyphIndex) reset() {
 	g.clusterAdvance = 0
 	g.truncated = false
 	g.midCluster = false
+	g.firstLine = 0
+	g.firstRune = 0
+}
+
+// resetAt prepares the index for indexing text starting at the given rune
+// and line of a larger text.
+func (g *glyphIndex) resetAt(runes, line int) {
+	g.reset()
+	g.pos.runes = runes
+	g.pos.lineCol.line = line
+	g.firstLine = line
+	g.firstRune = runes
 }
 
 // screenPos represents a character position in text line and column numbers,
@@ -343,7 +358,8 @@ type Region struct {
 	// widget.
 	Bounds image.Rectangle
 	// Baseline is the quantity of vertical pixels between the baseline and
-	// the bottom of bounds.
+	// the bottom of bounds. For vertical text, it is the quantity of
+	// horizontal pixels between the baseline and the left of bounds.
 	Baseline int
 }
 
@@ -360,7 +376,7 @@ func (g *glyphIndex) locate(viewport image.Rectangle, startRune, endRune int, re
 	caretStart, _ := g.closestToRune(startRune)
 	caretEnd, _ := g.closestToRune(endRune)
 
-	for lineIdx := caretStart.lineCol.line; lineIdx < len(g.lines); lineIdx++ {
+	for lineIdx := max(caretStart.lineCol.line, g.firstLine); lineIdx < g.firstLine+len(g.lines); lineIdx++ {
 		if lineIdx > caretEnd.lineCol.line {
 			break
 		}
@@ -371,7 +387,7 @@ func (g *glyphIndex) locate(viewport image.Rectangle, startRune, endRune int, re
 		if int(pos.y)-pos.ascent.Ceil() > viewport.Max.Y {
 			break
 		}
-		line := g.lines[lineIdx]
+		line := g.lines[lineIdx-g.firstLine]
 		if lineIdx > caretStart.lineCol.line && lineIdx < caretEnd.lineCol.line {
 			startX := line.xOff
 			endX := startX + line.width
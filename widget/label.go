@@ -18,6 +18,26 @@ import (
 	"golang.org/x/image/math/fixed"
 )
 
+// TabStop is a position that text following a tab character is aligned to.
+type TabStop struct {
+	// Position of the stop, relative to the start of the line.
+	Position  unit.Sp
+	Alignment text.TabAlignment
+}
+
+// tabStops converts stops and the interval between the stops following them
+// to text.TabStops.
+func tabStops(gtx layout.Context, interval unit.Sp, stops []TabStop) text.TabStops {
+	if interval == 0 && len(stops) == 0 {
+		return text.TabStops{}
+	}
+	ts := make([]text.TabStop, len(stops))
+	for i, s := range stops {
+		ts[i] = text.TabStop{Position: fixed.I(gtx.Sp(s.Position)), Alignment: s.Alignment}
+	}
+	return text.NewTabStops(fixed.I(gtx.Sp(interval)), ts...)
+}
+
 // Label is a widget for laying out and drawing text. Labels are always
 // non-interactive text. They cannot be selected or copied.
 type Label struct {
@@ -36,6 +56,22 @@ type Label struct {
 	// LineHeightScale applies a scaling factor to the LineHeight. If zero, a
 	// sensible default will be used.
 	LineHeightScale float32
+	// Decoration draws lines along the text, such as underlines.
+	Decoration text.Decoration
+	// SkipInk interrupts underlines where glyphs descend through them.
+	SkipInk bool
+	// Features is a list of OpenType features to apply when shaping, in the
+	// format of text.Parameters.Features.
+	Features string
+	// Variations sets the variation axes of variable fonts, in the format
+	// of text.Parameters.Variations.
+	Variations string
+	// TabStops are the positions that text following tab characters is
+	// aligned to, as described by text.Parameters.TabStops.
+	TabStops []TabStop
+	// TabWidth is the distance between the tab stops following TabStops.
+	// If zero and TabStops is empty, tab characters are not aligned.
+	TabWidth unit.Sp
 }
 
 // Layout the label with the given shaper, font, size, text, and material.
@@ -52,7 +88,24 @@ type TextInfo struct {
 }
 
 // Layout the label with the given shaper, font, size, text, and material, returning metadata about the shaped text.
+// If the locale of gtx has a vertical text direction, the text is laid out in
+// columns.
 func (l Label) LayoutDetailed(gtx layout.Context, lt *text.Shaper, font font.Font, size unit.Sp, txt string, textMaterial op.CallOp) (layout.Dimensions, TextInfo) {
+	if !verticalText(gtx) {
+		return l.layoutDetailed(gtx, lt, font, size, txt, textMaterial)
+	}
+	var info TextInfo
+	dims := layoutVertical(gtx, func(gtx layout.Context) layout.Dimensions {
+		var dims layout.Dimensions
+		dims, info = l.layoutDetailed(gtx, lt, font, size, txt, textMaterial)
+		return dims
+	})
+	return dims, info
+}
+
+// layoutDetailed is like LayoutDetailed, but lays out vertical text as if
+// it were horizontal.
+func (l Label) layoutDetailed(gtx layout.Context, lt *text.Shaper, font font.Font, size unit.Sp, txt string, textMaterial op.CallOp) (layout.Dimensions, TextInfo) {
 	cs := gtx.Constraints
 	textSize := fixed.I(gtx.Sp(size))
 	lineHeight := fixed.I(gtx.Sp(l.LineHeight))
@@ -68,13 +121,18 @@ func (l Label) LayoutDetailed(gtx layout.Context, lt *text.Shaper, font font.Fon
 		Locale:          gtx.Locale,
 		LineHeight:      lineHeight,
 		LineHeightScale: l.LineHeightScale,
+		Features:        l.Features,
+		Variations:      l.Variations,
+		TabStops:        tabStops(gtx, l.TabWidth, l.TabStops),
 	}, txt)
 	m := op.Record(gtx.Ops)
 	viewport := image.Rectangle{Max: cs.Max}
 	it := textIterator{
-		viewport: viewport,
-		maxLines: l.MaxLines,
-		material: textMaterial,
+		viewport:   viewport,
+		maxLines:   l.MaxLines,
+		material:   textMaterial,
+		decoration: l.Decoration,
+		skipInk:    l.SkipInk,
 	}
 	semantic.LabelOp(txt).Add(gtx.Ops)
 	var glyphs [32]text.Glyph
@@ -108,6 +166,10 @@ type textIterator struct {
 	// the color of the glyphs is undefined and may change unpredictably if the
 	// text contains color glyphs.
 	material op.CallOp
+	// decoration is the set of lines drawn along the glyphs.
+	decoration text.Decoration
+	// skipInk interrupts underlines around descending glyphs.
+	skipInk bool
 	// truncated tracks the count of truncated runes in the text.
 	truncated int
 	// linesSeen tracks the quantity of line endings this iterator has seen.
@@ -210,17 +272,110 @@ func (it *textIterator) paintGlyph(gtx layout.Context, shaper *text.Shaper, glyp
 		line = append(line, glyph)
 	}
 	if glyph.Flags&text.FlagLineBreak != 0 || cap(line)-len(line) == 0 || !visibleOrBefore {
-		t := op.Affine(f32.Affine2D{}.Offset(it.lineOff)).Push(gtx.Ops)
-		path := shaper.Shape(line)
-		outline := clip.Outline{Path: path}.Op().Push(gtx.Ops)
-		it.material.Add(gtx.Ops)
-		paint.PaintOp{}.Add(gtx.Ops)
-		outline.Pop()
-		if call := shaper.Bitmaps(line); call != (op.CallOp{}) {
-			call.Add(gtx.Ops)
-		}
-		t.Pop()
-		line = line[:0]
+		line = it.paintLine(gtx, shaper, line)
 	}
 	return line, visibleOrBefore
 }
+
+// paintLine paints the buffered glyphs of line with the iterator's material
+// and returns the emptied line.
+func (it *textIterator) paintLine(gtx layout.Context, shaper *text.Shaper, line []text.Glyph) []text.Glyph {
+	t := op.Affine(f32.Affine2D{}.Offset(it.lineOff)).Push(gtx.Ops)
+	path := shaper.Shape(line)
+	outline := clip.Outline{Path: path}.Op().Push(gtx.Ops)
+	it.material.Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+	outline.Pop()
+	if call := shaper.Bitmaps(line); call != (op.CallOp{}) {
+		call.Add(gtx.Ops)
+	}
+	t.Pop()
+	if it.decoration != 0 {
+		it.paintDecorations(gtx, shaper, line)
+	}
+	return line[:0]
+}
+
+// decorationSegment is a horizontal segment of a decoration line, in
+// document coordinates.
+type decorationSegment struct {
+	x0, x1       fixed.Int26_6
+	y, thickness fixed.Int26_6
+}
+
+// paintDecorations paints the decoration lines of the glyphs of line with
+// the iterator's material.
+func (it *textIterator) paintDecorations(gtx layout.Context, shaper *text.Shaper, line []text.Glyph) {
+	var buf [8]decorationSegment
+	for _, d := range [...]text.Decoration{text.Underline, text.Strikethrough, text.Overline} {
+		if it.decoration&d == 0 {
+			continue
+		}
+		for _, s := range decorationSegments(shaper, line, d, it.skipInk, buf[:0]) {
+			it.paintSegment(gtx, s)
+		}
+	}
+}
+
+// decorationSegments appends the segments of the decoration line d of the
+// glyphs of line to segs. Adjacent glyphs with equal line metrics share a
+// segment, so lines are continuous within bidi runs. If skipInk is set,
+// underlines are interrupted around glyphs that descend through them.
+func decorationSegments(shaper *text.Shaper, line []text.Glyph, d text.Decoration, skipInk bool, segs []decorationSegment) []decorationSegment {
+	add := func(s decorationSegment) {
+		if s.x0 >= s.x1 {
+			return
+		}
+		if n := len(segs); n > 0 {
+			seg := &segs[n-1]
+			if s.y == seg.y && s.thickness == seg.thickness && (s.x0 == seg.x1 || s.x1 == seg.x0) {
+				if s.x0 < seg.x0 {
+					seg.x0 = s.x0
+				}
+				if s.x1 > seg.x1 {
+					seg.x1 = s.x1
+				}
+				return
+			}
+		}
+		segs = append(segs, s)
+	}
+	for _, g := range line {
+		y, thickness := shaper.DecorationLine(g, d)
+		s := decorationSegment{x0: g.X, x1: g.X + g.Advance, y: y + fixed.I(int(g.Y)), thickness: thickness}
+		if !skipInk || d != text.Underline || g.Bounds.Max.Y <= y || g.Bounds.Min.Y >= y+thickness {
+			add(s)
+			continue
+		}
+		// Leave a gap around the ink of the glyph.
+		gap := thickness
+		if gap < fixed.I(1) {
+			gap = fixed.I(1)
+		}
+		left, right := s, s
+		left.x1 = g.X + g.Bounds.Min.X - gap
+		right.x0 = g.X + g.Bounds.Max.X + gap
+		if g.Flags&text.FlagTowardOrigin != 0 {
+			left, right = right, left
+		}
+		add(left)
+		add(right)
+	}
+	return segs
+}
+
+// paintSegment fills a decoration segment, snapped to whole pixels.
+func (it *textIterator) paintSegment(gtx layout.Context, s decorationSegment) {
+	if s.x0 >= s.x1 {
+		return
+	}
+	h := s.thickness.Round()
+	if h < 1 {
+		h = 1
+	}
+	r := image.Rect(s.x0.Round(), s.y.Round(), s.x1.Round(), s.y.Round()+h)
+	area := clip.Rect(r.Sub(it.viewport.Min)).Push(gtx.Ops)
+	it.material.Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+	area.Pop()
+}
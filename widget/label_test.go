@@ -5,6 +5,7 @@ import (
 	"math"
 	"testing"
 
+	"gioui.org/font/gofont"
 	"gioui.org/text"
 	"golang.org/x/image/math/fixed"
 )
@@ -227,3 +228,46 @@ func TestGlyphIteratorPadding(t *testing.T) {
 		})
 	}
 }
+
+func TestDecorationSegments(t *testing.T) {
+	shaper := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	shaper.LayoutString(text.Parameters{
+		PxPerEm:  fixed.I(20),
+		MaxWidth: 1000,
+	}, "xgpx")
+	var line []text.Glyph
+	for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+		line = append(line, g)
+	}
+	first, last := line[0], line[len(line)-1]
+
+	segs := decorationSegments(shaper, line, text.Underline, false, nil)
+	if len(segs) != 1 {
+		t.Fatalf("got %d underline segments, want 1", len(segs))
+	}
+	if s := segs[0]; s.x0 != first.X || s.x1 != last.X+last.Advance {
+		t.Errorf("underline spans [%v, %v], want [%v, %v]", s.x0, s.x1, first.X, last.X+last.Advance)
+	}
+	if s := segs[0]; s.y <= fixed.I(int(first.Y)) || s.thickness <= 0 {
+		t.Errorf("underline at %v with thickness %v, want below baseline %v", s.y, s.thickness, first.Y)
+	}
+
+	// The descenders of g and p interrupt the underline.
+	segs = decorationSegments(shaper, line, text.Underline, true, nil)
+	if len(segs) < 2 {
+		t.Fatalf("got %d underline segments skipping ink, want at least 2", len(segs))
+	}
+	for _, g := range line[1:3] {
+		inkMin, inkMax := g.X+g.Bounds.Min.X, g.X+g.Bounds.Max.X
+		for _, s := range segs {
+			if s.x0 < inkMax && s.x1 > inkMin {
+				t.Errorf("segment [%v, %v] overlaps descender ink [%v, %v]", s.x0, s.x1, inkMin, inkMax)
+			}
+		}
+	}
+
+	segs = decorationSegments(shaper, line, text.Strikethrough, true, nil)
+	if len(segs) != 1 || segs[0].y >= fixed.I(int(first.Y)) {
+		t.Errorf("got strikethrough segments %v, want a single segment above the baseline", segs)
+	}
+}
@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"io"
+	"strings"
+
+	"gioui.org/text"
+)
+
+// LineInfo describes a visual line of laid out text.
+type LineInfo struct {
+	// Line is the source line of the visual line, counting from zero. Source
+	// lines are separated by newlines.
+	Line int
+	// Wrapped reports whether the visual line continues the source line of
+	// the visual line before it.
+	Wrapped bool
+	// Baseline is the vertical position of the baseline of the visual line,
+	// relative to the top of the visible text.
+	Baseline int
+	// Ascent and Descent are the distances from the baseline to the top and
+	// bottom of the visual line.
+	Ascent, Descent int
+	// Start and End are the range of runes of the visual line, including its
+	// trailing newline, if any.
+	Start, End int
+}
+
+// VisibleLines returns the visual lines intersecting the viewport, in the
+// order they are laid out.
+func (e *textView) VisibleLines(lines []LineInfo) []LineInfo {
+	e.makeValid()
+	lines = lines[:0]
+	source := 0
+	if e.virt.enabled {
+		// The indexed text starts at a paragraph.
+		source = e.virt.window.start
+	}
+	runes := e.index.firstRune
+	glyphs := e.index.glyphs
+	wrapped := false
+	for _, line := range e.index.lines {
+		lineGlyphs := glyphs[:line.glyphs]
+		glyphs = glyphs[line.glyphs:]
+		n := 0
+		for _, g := range lineGlyphs {
+			n += int(g.Runes)
+		}
+		ascent, descent := line.ascent.Ceil(), line.descent.Ceil()
+		top, bottom := line.yOff-ascent, line.yOff+descent
+		if bottom >= e.scrollOff.Y && top <= e.scrollOff.Y+e.viewSize.Y {
+			lines = append(lines, LineInfo{
+				Line:     source,
+				Wrapped:  wrapped,
+				Baseline: line.yOff - e.scrollOff.Y,
+				Ascent:   ascent,
+				Descent:  descent,
+				Start:    runes,
+				End:      runes + n,
+			})
+		}
+		runes += n
+		wrapped = len(lineGlyphs) > 0 && lineGlyphs[len(lineGlyphs)-1].Flags&text.FlagParagraphBreak == 0
+		if !wrapped {
+			source++
+		}
+	}
+	return lines
+}
+
+// lineIndent returns the indentation of the source line containing the
+// rune offset r, up to r, and the column of r in the source line in runes.
+func (e *textView) lineIndent(r int) (indent string, col int) {
+	var prefix []rune
+	for off := e.ByteOffset(r); off > 0; {
+		c, n, err := e.ReadRuneBefore(off)
+		if n == 0 || (err != nil && err != io.EOF) || c == '\n' {
+			break
+		}
+		off -= int64(n)
+		prefix = append(prefix, c)
+	}
+	col = len(prefix)
+	// prefix is reversed, so the indentation is at its end.
+	var b strings.Builder
+	for i := len(prefix) - 1; i >= 0 && (prefix[i] == ' ' || prefix[i] == '\t'); i-- {
+		b.WriteRune(prefix[i])
+	}
+	return b.String(), col
+}
+
+// VisibleLines returns the visual lines intersecting the viewport of the
+// editor, in the order they are laid out, such as for drawing line numbers
+// next to them. The vertical positions are relative to the top of the
+// editor as laid out by the most recent call to Layout.
+func (e *Editor) VisibleLines(lines []LineInfo) []LineInfo {
+	e.initBuffer()
+	return e.text.VisibleLines(lines)
+}
+
+// insertNewline inserts a newline at the primary caret, followed by the
+// indentation of its line if AutoIndent is set. Single line editors insert
+// a space instead.
+func (e *Editor) insertNewline() int {
+	s := "\n"
+	switch {
+	case e.SingleLine:
+		s = " "
+	case e.AutoIndent:
+		start, end := e.text.Selection()
+		indent, _ := e.text.lineIndent(min(start, end))
+		s += indent
+	}
+	return e.insert(s)
+}
+
+// insertTab inserts spaces at the primary caret up to the next multiple of
+// TabSpaces columns.
+func (e *Editor) insertTab() int {
+	start, end := e.text.Selection()
+	_, col := e.text.lineIndent(min(start, end))
+	return e.insert(strings.Repeat(" ", e.TabSpaces-col%e.TabSpaces))
+}
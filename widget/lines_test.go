@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/font"
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+)
+
+func TestEditorVisibleLines(t *testing.T) {
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(100, 500)),
+		Locale:      english,
+	}
+	shaper := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	e := new(Editor)
+	e.SetText("one\ntwo two two two two two two\n\nthree")
+	e.Layout(gtx, shaper, font.Font{Typeface: "Go Mono"}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	lines := e.VisibleLines(nil)
+	if len(lines) < 5 {
+		t.Fatalf("got %d lines, want a wrapped second line", len(lines))
+	}
+	runes, source := 0, 0
+	for i, l := range lines {
+		if l.Start != runes || l.End < l.Start {
+			t.Errorf("line %d: got runes [%d, %d), want start %d", i, l.Start, l.End, runes)
+		}
+		runes = l.End
+		if !l.Wrapped && i > 0 {
+			source++
+		}
+		if l.Line != source {
+			t.Errorf("line %d: got source line %d, want %d", i, l.Line, source)
+		}
+		if i > 0 && l.Baseline <= lines[i-1].Baseline {
+			t.Errorf("line %d: baseline %d not below the previous line", i, l.Baseline)
+		}
+		if l.Ascent <= 0 || l.Descent <= 0 {
+			t.Errorf("line %d: got ascent %d and descent %d", i, l.Ascent, l.Descent)
+		}
+	}
+	if source != 3 || runes != e.Len() {
+		t.Errorf("got %d source lines of %d runes, want 4 lines of %d runes", source+1, runes, e.Len())
+	}
+	if l := lines[0]; l.Wrapped || l.End != 4 {
+		t.Errorf("got first line %+v", l)
+	}
+	if l := lines[2]; !l.Wrapped || l.Line != 1 {
+		t.Errorf("got wrapped line %+v", l)
+	}
+	// Lines scrolled out of view are not visible.
+	gtx.Constraints = layout.Exact(image.Pt(100, lines[1].Baseline))
+	e.SetCaret(e.Len(), e.Len())
+	e.Layout(gtx, shaper, font.Font{Typeface: "Go Mono"}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	lines = e.VisibleLines(lines)
+	if l := lines[len(lines)-1]; l.Line != 3 || l.End != e.Len() || lines[0].Line == 0 {
+		t.Errorf("got visible lines %+v after scrolling to the end", lines)
+	}
+}
+
+func TestEditorIndent(t *testing.T) {
+	r := new(input.Router)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(500, 500)),
+		Locale:      english,
+		Source:      r.Source(),
+	}
+	shaper := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	e := &Editor{AutoIndent: true, TabSpaces: 4}
+	e.SetText("func f() {\n\t  x\n}")
+	gtx.Execute(key.FocusCmd{Tag: e})
+	press := func(name key.Name) {
+		r.Queue(key.Event{Name: name, State: key.Press})
+		gtx.Ops.Reset()
+		e.Layout(gtx, shaper, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+		r.Frame(gtx.Ops)
+	}
+	e.Layout(gtx, shaper, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	r.Frame(gtx.Ops)
+	e.SetCaret(15, 15)
+	press(key.NameReturn)
+	assertContents(t, e, "func f() {\n\t  x\n\t  \n}", 19, 19)
+	press(key.NameTab)
+	assertContents(t, e, "func f() {\n\t  x\n\t   \n}", 20, 20)
+	press(key.NameTab)
+	assertContents(t, e, "func f() {\n\t  x\n\t       \n}", 24, 24)
+	// The indentation is taken up to the caret.
+	e.SetCaret(12, 12)
+	press(key.NameReturn)
+	assertContents(t, e, "func f() {\n\t\n\t  x\n\t       \n}", 14, 14)
+	e.Undo()
+	assertContents(t, e, "func f() {\n\t  x\n\t       \n}", 12, 12)
+}
@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package material
+
+import (
+	"image"
+	"image/color"
+
+	"gioui.org/f32"
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+)
+
+// AnnotationsStyle configures the presentation of the annotations of an
+// editor: wavy underlines below the annotated text, and a tooltip with the
+// message of the annotation under the pointer.
+type AnnotationsStyle struct {
+	Editor *widget.Editor
+	// Colors are the colors of the underlines, indexed by the kind of
+	// annotation.
+	Colors [widget.AnnotationSpelling + 1]color.NRGBA
+	// Thickness is the width of the underlines, and Wavelength the length of
+	// a period of their waves.
+	Thickness, Wavelength unit.Dp
+	// Font and TextSize are the font and size of the messages.
+	Font     font.Font
+	TextSize unit.Sp
+	// TooltipColor and TooltipBackground are the colors of the tooltips.
+	TooltipColor, TooltipBackground color.NRGBA
+	// TooltipInset is the padding of the tooltips.
+	TooltipInset layout.Inset
+
+	shaper *text.Shaper
+}
+
+// Annotations returns the style of the annotations of an editor.
+func Annotations(th *Theme, editor *widget.Editor) AnnotationsStyle {
+	return AnnotationsStyle{
+		Editor: editor,
+		Colors: [...]color.NRGBA{
+			widget.AnnotationError:    rgb(0xb00020),
+			widget.AnnotationWarning:  rgb(0xe6a100),
+			widget.AnnotationInfo:     rgb(0x2196f3),
+			widget.AnnotationSpelling: rgb(0xd32f2f),
+		},
+		Thickness:         1,
+		Wavelength:        4,
+		Font:              font.Font{Typeface: th.Face},
+		TextSize:          th.TextSize * 14.0 / 16.0,
+		TooltipColor:      th.Palette.Bg,
+		TooltipBackground: th.Palette.Fg,
+		TooltipInset:      layout.UniformInset(4),
+		shaper:            th.Shaper,
+	}
+}
+
+// Layout draws the annotations of the editor. It must be laid out after the
+// editor, at the position of the editor. The tooltip is deferred to draw it
+// on top of other widgets.
+func (a AnnotationsStyle) Layout(gtx layout.Context) layout.Dimensions {
+	var regions []widget.Region
+	for _, ann := range a.Editor.VisibleAnnotations(nil) {
+		regions = a.Editor.Regions(ann.Start, ann.End, regions)
+		col := a.Colors[0]
+		if int(ann.Kind) < len(a.Colors) {
+			col = a.Colors[ann.Kind]
+		}
+		for _, r := range regions {
+			a.squiggle(gtx, r, col)
+		}
+	}
+	if ann, pos, ok := a.Editor.HoveredAnnotation(); ok && ann.Message != "" {
+		a.tooltip(gtx, ann.Message, pos)
+	}
+	return layout.Dimensions{}
+}
+
+// squiggle draws a wavy line below the baseline of the region r.
+func (a AnnotationsStyle) squiggle(gtx layout.Context, r widget.Region, col color.NRGBA) {
+	thickness := float32(gtx.Dp(a.Thickness))
+	half := float32(gtx.Dp(a.Wavelength)) / 2
+	if half <= 0 || r.Bounds.Dx() <= 0 {
+		return
+	}
+	amplitude := half / 2
+	y := float32(r.Bounds.Max.Y-r.Baseline) + amplitude + thickness
+	x, end := float32(r.Bounds.Min.X), float32(r.Bounds.Max.X)
+	var p clip.Path
+	p.Begin(gtx.Ops)
+	p.MoveTo(f32.Pt(x, y))
+	for up := true; x < end; up = !up {
+		ctrl := f32.Pt(x+half/2, y-amplitude*2)
+		if !up {
+			ctrl.Y = y + amplitude*2
+		}
+		p.QuadTo(ctrl, f32.Pt(x+half, y))
+		x += half
+	}
+	paint.FillShape(gtx.Ops, col, clip.Stroke{Path: p.End(), Width: thickness}.Op())
+}
+
+// tooltip draws msg below the pointer position pos.
+func (a AnnotationsStyle) tooltip(gtx layout.Context, msg string, pos image.Point) {
+	colorMacro := op.Record(gtx.Ops)
+	paint.ColorOp{Color: a.TooltipColor}.Add(gtx.Ops)
+	textColor := colorMacro.Stop()
+	gtx.Constraints.Min = image.Point{}
+	macro := op.Record(gtx.Ops)
+	dims := a.TooltipInset.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return widget.Label{}.Layout(gtx, a.shaper, a.Font, a.TextSize, msg, textColor)
+	})
+	label := macro.Stop()
+	macro = op.Record(gtx.Ops)
+	paint.FillShape(gtx.Ops, a.TooltipBackground, clip.Rect{Max: dims.Size}.Op())
+	label.Add(gtx.Ops)
+	tooltip := macro.Stop()
+	// Place the tooltip below the pointer, clear of its cursor.
+	off := op.Offset(pos.Add(image.Pt(0, gtx.Dp(16)))).Push(gtx.Ops)
+	op.Defer(gtx.Ops, tooltip)
+	off.Pop()
+}
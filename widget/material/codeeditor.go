@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package material
+
+import (
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"gioui.org/font"
+	"gioui.org/internal/f32color"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+)
+
+// CodeEditorStyle configures the presentation of an editor for source
+// code, with a gutter of line numbers and a highlight of the line of the
+// caret.
+type CodeEditorStyle struct {
+	EditorStyle
+	// GutterColor is the background color of the gutter.
+	GutterColor color.NRGBA
+	// LineNumberColor is the color of the line numbers.
+	LineNumberColor color.NRGBA
+	// CurrentLineColor is the background color of the line of the caret.
+	CurrentLineColor color.NRGBA
+	// GutterPadding is the space on either side of the line numbers.
+	GutterPadding unit.Dp
+	// MinDigits is the minimum number of digits the gutter has room for.
+	MinDigits int
+	// AutoIndent and TabSpaces configure the editing behavior of the editor,
+	// as described by the fields of widget.Editor.
+	AutoIndent bool
+	TabSpaces  int
+}
+
+// CodeEditor returns the style of an editor of source code, in a monospace
+// font, indenting new lines and inserting 4 spaces for the Tab key.
+func CodeEditor(th *Theme, editor *widget.Editor) CodeEditorStyle {
+	e := Editor(th, editor, "")
+	e.Font = font.Font{Typeface: "monospace"}
+	return CodeEditorStyle{
+		EditorStyle:      e,
+		GutterColor:      f32color.MulAlpha(th.Palette.Fg, 0x10),
+		LineNumberColor:  f32color.MulAlpha(th.Palette.Fg, 0x80),
+		CurrentLineColor: f32color.MulAlpha(th.Palette.ContrastBg, 0x20),
+		GutterPadding:    8,
+		MinDigits:        3,
+		AutoIndent:       true,
+		TabSpaces:        4,
+	}
+}
+
+func (c CodeEditorStyle) Layout(gtx layout.Context) layout.Dimensions {
+	c.Editor.AutoIndent = c.AutoIndent
+	c.Editor.TabSpaces = c.TabSpaces
+	numberColorMacro := op.Record(gtx.Ops)
+	paint.ColorOp{Color: c.LineNumberColor}.Add(gtx.Ops)
+	numberColor := numberColorMacro.Stop()
+
+	// Size the gutter for the line numbers visible in the previous layout.
+	lines := c.Editor.VisibleLines(nil)
+	digits := c.MinDigits
+	if n := len(lines); n > 0 {
+		digits = max(digits, len(strconv.Itoa(lines[n-1].Line+1)))
+	}
+	numbers := widget.Label{Alignment: text.End, MaxLines: 1}
+	ngtx := gtx
+	ngtx.Constraints.Min = image.Point{}
+	macro := op.Record(gtx.Ops)
+	dims := numbers.Layout(ngtx, c.shaper, c.Font, c.TextSize, strings.Repeat("0", digits), numberColor)
+	macro.Stop()
+	numberWidth := dims.Size.X
+	pad := gtx.Dp(c.GutterPadding)
+	gutter := numberWidth + 2*pad
+
+	egtx := gtx
+	egtx.Constraints.Min.X = max(0, gtx.Constraints.Min.X-gutter)
+	egtx.Constraints.Max.X = max(0, gtx.Constraints.Max.X-gutter)
+	macro = op.Record(gtx.Ops)
+	dims = c.EditorStyle.Layout(egtx)
+	editor := macro.Stop()
+	size := image.Pt(dims.Size.X+gutter, dims.Size.Y)
+
+	defer clip.Rect{Max: size}.Push(gtx.Ops).Pop()
+	paint.FillShape(gtx.Ops, c.GutterColor, clip.Rect{Max: image.Pt(gutter, size.Y)}.Op())
+	lines = c.Editor.VisibleLines(lines)
+	caret, _ := c.Editor.Selection()
+	current := -1
+	for _, l := range lines {
+		if l.Start <= caret && caret < l.End || caret == l.End && caret == c.Editor.Len() {
+			current = l.Line
+		}
+	}
+	ngtx.Constraints = layout.Constraints{
+		Min: image.Pt(numberWidth, 0),
+		Max: image.Pt(numberWidth, gtx.Constraints.Max.Y),
+	}
+	for _, l := range lines {
+		if l.Line == current {
+			highlight := image.Rect(gutter, l.Baseline-l.Ascent, size.X, l.Baseline+l.Descent)
+			paint.FillShape(gtx.Ops, c.CurrentLineColor, clip.Rect(highlight).Op())
+		}
+		if l.Wrapped {
+			continue
+		}
+		macro := op.Record(gtx.Ops)
+		ndims := numbers.Layout(ngtx, c.shaper, c.Font, c.TextSize, strconv.Itoa(l.Line+1), numberColor)
+		number := macro.Stop()
+		// Align the baseline of the number with the baseline of the line.
+		off := op.Offset(image.Pt(pad, l.Baseline-(ndims.Size.Y-ndims.Baseline))).Push(gtx.Ops)
+		number.Add(gtx.Ops)
+		off.Pop()
+	}
+	off := op.Offset(image.Pt(gutter, 0)).Push(gtx.Ops)
+	editor.Add(gtx.Ops)
+	off.Pop()
+	return layout.Dimensions{Size: size, Baseline: dims.Baseline}
+}
@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package material
+
+import (
+	"image"
+	"image/color"
+
+	"gioui.org/font"
+	"gioui.org/internal/f32color"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+)
+
+// CompletionStyle configures the presentation of the completions of an
+// editor: the selected completion inline after the caret, and the list of
+// completions in a popup below the caret.
+type CompletionStyle struct {
+	Editor *widget.Editor
+	// Font and TextSize should match the style of the editor for the inline
+	// completion to line up with the editor text.
+	Font     font.Font
+	TextSize unit.Sp
+	// Color is the color of the completions in the list.
+	Color color.NRGBA
+	// InlineColor is the color of the inline completion.
+	InlineColor color.NRGBA
+	// Background is the background color of the list.
+	Background color.NRGBA
+	// SelectedColor is the background color of the selected completion.
+	SelectedColor color.NRGBA
+	// MaxItems limits the number of completions listed at a time.
+	MaxItems int
+	// Inset is the padding of each completion in the list.
+	Inset layout.Inset
+
+	shaper *text.Shaper
+}
+
+// Completions returns the style of the completions of an editor laid out by
+// the style e.
+func Completions(th *Theme, e EditorStyle) CompletionStyle {
+	return CompletionStyle{
+		Editor:        e.Editor,
+		Font:          e.Font,
+		TextSize:      e.TextSize,
+		Color:         th.Palette.Fg,
+		InlineColor:   f32color.MulAlpha(th.Palette.Fg, 0x80),
+		Background:    th.Palette.Bg,
+		SelectedColor: f32color.MulAlpha(th.Palette.ContrastBg, 0x60),
+		MaxItems:      8,
+		Inset:         layout.UniformInset(4),
+		shaper:        th.Shaper,
+	}
+}
+
+// Layout draws the completions at the caret of the editor. It must be laid
+// out after the editor, at the position of the editor. The list is deferred
+// to draw it on top of other widgets. Layout returns the dimensions of the
+// list, if any.
+func (c CompletionStyle) Layout(gtx layout.Context) layout.Dimensions {
+	items, selected := c.Editor.Completions()
+	if len(items) == 0 {
+		return layout.Dimensions{}
+	}
+	gtx.Constraints.Min = image.Point{}
+	caret := c.Editor.CaretCoords().Round()
+	label := widget.Label{MaxLines: 1}
+	if inline := c.Editor.InlineCompletion(); inline != "" {
+		macro := op.Record(gtx.Ops)
+		dims := label.Layout(gtx, c.shaper, c.Font, c.TextSize, inline, c.material(gtx, c.InlineColor))
+		call := macro.Stop()
+		// Align the baseline of the completion with the caret.
+		off := op.Offset(caret.Sub(image.Pt(0, dims.Size.Y-dims.Baseline))).Push(gtx.Ops)
+		call.Add(gtx.Ops)
+		off.Pop()
+	}
+
+	// List the completions around the selected completion.
+	first := 0
+	if n := c.MaxItems; n > 0 && len(items) > n {
+		first = min(max(0, selected-n/2), len(items)-n)
+		items = items[first : first+n]
+	}
+	macro := op.Record(gtx.Ops)
+	var rows []op.CallOp
+	var heights []int
+	width := 0
+	for _, item := range items {
+		txt := item.Label
+		if txt == "" {
+			txt = item.Text
+		}
+		macro := op.Record(gtx.Ops)
+		dims := c.Inset.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			return label.Layout(gtx, c.shaper, c.Font, c.TextSize, txt, c.material(gtx, c.Color))
+		})
+		rows = append(rows, macro.Stop())
+		heights = append(heights, dims.Size.Y)
+		width = max(width, dims.Size.X)
+	}
+	height := 0
+	for _, h := range heights {
+		height += h
+	}
+	size := image.Pt(width, height)
+	paint.FillShape(gtx.Ops, c.Background, clip.Rect{Max: size}.Op())
+	y := 0
+	for i, row := range rows {
+		if first+i == selected {
+			paint.FillShape(gtx.Ops, c.SelectedColor, clip.Rect{Min: image.Pt(0, y), Max: image.Pt(width, y+heights[i])}.Op())
+		}
+		off := op.Offset(image.Pt(0, y)).Push(gtx.Ops)
+		row.Add(gtx.Ops)
+		off.Pop()
+		y += heights[i]
+	}
+	list := macro.Stop()
+
+	// Place the list below the line of the caret.
+	off := op.Offset(caret.Add(image.Pt(0, c.descent(gtx)))).Push(gtx.Ops)
+	op.Defer(gtx.Ops, list)
+	off.Pop()
+	return layout.Dimensions{Size: size}
+}
+
+// descent returns the distance from the baseline to the bottom of a line of
+// text in the font of the completions.
+func (c CompletionStyle) descent(gtx layout.Context) int {
+	macro := op.Record(gtx.Ops)
+	dims := widget.Label{MaxLines: 1}.Layout(gtx, c.shaper, c.Font, c.TextSize, "0", op.CallOp{})
+	macro.Stop()
+	return dims.Baseline
+}
+
+func (c CompletionStyle) material(gtx layout.Context, col color.NRGBA) op.CallOp {
+	macro := op.Record(gtx.Ops)
+	paint.ColorOp{Color: col}.Add(gtx.Ops)
+	return macro.Stop()
+}
@@ -3,6 +3,7 @@
 package material
 
 import (
+	"image"
 	"image/color"
 
 	"gioui.org/font"
@@ -32,7 +33,10 @@ type EditorStyle struct {
 	HintColor color.NRGBA
 	// SelectionColor is the color of the background for selected text.
 	SelectionColor color.NRGBA
-	Editor         *widget.Editor
+	// ErrorColor is the color of the message displayed below the editor if
+	// its text is invalid, as reported by Editor.Err.
+	ErrorColor color.NRGBA
+	Editor     *widget.Editor
 
 	shaper *text.Shaper
 }
@@ -49,6 +53,7 @@ func Editor(th *Theme, editor *widget.Editor, hint string) EditorStyle {
 		Hint:           hint,
 		HintColor:      f32color.MulAlpha(th.Palette.Fg, 0xbb),
 		SelectionColor: f32color.MulAlpha(th.Palette.ContrastBg, 0x60),
+		ErrorColor:     rgb(0xb00020),
 	}
 }
 
@@ -91,9 +96,29 @@ func (e EditorStyle) Layout(gtx layout.Context) layout.Dimensions {
 	if e.Editor.Len() == 0 {
 		call.Add(gtx.Ops)
 	}
+	if err := e.Editor.Err(); err != nil {
+		dims = e.layoutError(gtx, dims, err)
+	}
 	return dims
 }
 
+// layoutError lays out the message of err below the editor of dimensions
+// dims, and returns the dimensions of both.
+func (e EditorStyle) layoutError(gtx layout.Context, dims layout.Dimensions, err error) layout.Dimensions {
+	errorColorMacro := op.Record(gtx.Ops)
+	paint.ColorOp{Color: e.ErrorColor}.Add(gtx.Ops)
+	errorColor := errorColorMacro.Stop()
+	gtx.Constraints.Min = image.Point{}
+	gtx.Constraints.Max.Y = max(0, gtx.Constraints.Max.Y-dims.Size.Y)
+	defer op.Offset(image.Pt(0, dims.Size.Y)).Push(gtx.Ops).Pop()
+	tl := widget.Label{Alignment: e.Editor.Alignment}
+	edims := tl.Layout(gtx, e.shaper, e.Font, e.TextSize*0.8, err.Error(), errorColor)
+	return layout.Dimensions{
+		Size:     image.Pt(max(dims.Size.X, edims.Size.X), dims.Size.Y+edims.Size.Y),
+		Baseline: dims.Baseline + edims.Size.Y,
+	}
+}
+
 func blendDisabledColor(disabled bool, c color.NRGBA) color.NRGBA {
 	if disabled {
 		return f32color.Disabled(c)
@@ -44,6 +44,22 @@ type LabelStyle struct {
 	// LineHeightScale applies a scaling factor to the LineHeight. If zero, a
 	// sensible default will be used.
 	LineHeightScale float32
+	// Decoration draws lines along the text, such as underlines.
+	Decoration text.Decoration
+	// SkipInk interrupts underlines where glyphs descend through them.
+	SkipInk bool
+	// Features is a list of OpenType features to apply when shaping, in the
+	// format of text.Parameters.Features.
+	Features string
+	// Variations sets the variation axes of variable fonts, in the format
+	// of text.Parameters.Variations.
+	Variations string
+	// TabStops are the positions that text following tab characters is
+	// aligned to, as described by text.Parameters.TabStops.
+	TabStops []widget.TabStop
+	// TabWidth is the distance between the tab stops following TabStops.
+	// If zero and TabStops is empty, tab characters are not aligned.
+	TabWidth unit.Sp
 
 	// Shaper is the text shaper used to display this labe. This field is automatically
 	// set using by all constructor functions. If constructing a LabelStyle literal, you
@@ -140,6 +156,12 @@ func (l LabelStyle) Layout(gtx layout.Context) layout.Dimensions {
 		l.State.WrapPolicy = l.WrapPolicy
 		l.State.LineHeight = l.LineHeight
 		l.State.LineHeightScale = l.LineHeightScale
+		l.State.Decoration = l.Decoration
+		l.State.SkipInk = l.SkipInk
+		l.State.Features = l.Features
+		l.State.Variations = l.Variations
+		l.State.TabStops = l.TabStops
+		l.State.TabWidth = l.TabWidth
 		return l.State.Layout(gtx, l.Shaper, l.Font, l.TextSize, textColor, selectColor)
 	}
 	tl := widget.Label{
@@ -149,6 +171,12 @@ func (l LabelStyle) Layout(gtx layout.Context) layout.Dimensions {
 		WrapPolicy:      l.WrapPolicy,
 		LineHeight:      l.LineHeight,
 		LineHeightScale: l.LineHeightScale,
+		Decoration:      l.Decoration,
+		SkipInk:         l.SkipInk,
+		Features:        l.Features,
+		Variations:      l.Variations,
+		TabStops:        l.TabStops,
+		TabWidth:        l.TabWidth,
 	}
 	return tl.Layout(gtx, l.Shaper, l.Font, l.TextSize, l.Text, textColor)
 }
@@ -0,0 +1,382 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+
+	"gioui.org/f32"
+	"gioui.org/internal/ops"
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+)
+
+// Overlay draws popups above the widgets laid out by its Layout method.
+// An application typically has a single Overlay that wraps the content of
+// its window.
+type Overlay struct {
+	// stack contains the open popups, in the order they were opened.
+	stack []*Popup
+	// frame contains the open popups laid out in the current frame.
+	frame []overlayEntry
+
+	viewport image.Rectangle
+	reader   ops.Reader
+	// saved and trans track the transformation stack while locating
+	// popup anchors.
+	saved []f32.Affine2D
+	trans []f32.Affine2D
+	// content holds the content of a popup while locating the anchors of
+	// the popups it opened.
+	content op.Ops
+}
+
+type overlayEntry struct {
+	popup   *Popup
+	content layout.Widget
+	// found tracks whether the anchor transform was located.
+	found bool
+}
+
+// Popup is a widget drawn by an Overlay and anchored to the bounds of
+// another widget.
+type Popup struct {
+	// Placement is the preferred side of the anchor to place the
+	// popup. The popup is flipped to the opposite side if it doesn't fit
+	// the window otherwise.
+	Placement Placement
+
+	open bool
+	// size is the size of the anchor.
+	size image.Point
+	// anchor and bounds are the window coordinates of the anchor and popup
+	// in the most recent frame.
+	anchor image.Rectangle
+	bounds image.Rectangle
+	tag    struct{}
+}
+
+// Placement describes the position of a Popup relative to its anchor.
+type Placement uint8
+
+const (
+	// Below places the popup below its anchor, aligned to the anchor's
+	// left edge.
+	Below Placement = iota
+	// Above places the popup above its anchor, aligned to the anchor's
+	// left edge.
+	Above
+	// Right places the popup to the right of its anchor, aligned to the
+	// anchor's top edge.
+	Right
+	// Left places the popup to the left of its anchor, aligned to the
+	// anchor's top edge.
+	Left
+)
+
+// Open the popup. The popup is stacked above popups opened earlier.
+func (p *Popup) Open() {
+	p.open = true
+}
+
+// Close the popup and every popup stacked above it.
+func (p *Popup) Close() {
+	p.open = false
+}
+
+// Opened reports whether the popup is open.
+func (p *Popup) Opened() bool {
+	return p.open
+}
+
+// Bounds returns the window coordinates of the popup as of the most
+// recent frame it was drawn.
+func (p *Popup) Bounds() image.Rectangle {
+	return p.bounds
+}
+
+// Layout the anchor widget and, if the popup is open, schedule content to
+// be drawn by the Overlay. The content is laid out after the Overlay's
+// child widget, so it must not refer to state that is only valid during
+// this call.
+func (p *Popup) Layout(gtx layout.Context, o *Overlay, anchor, content layout.Widget) layout.Dimensions {
+	dims := anchor(gtx)
+	p.size = dims.Size
+	if !p.open {
+		return dims
+	}
+	// Mark the anchor position for Overlay.Layout, without blocking
+	// events to the anchor.
+	pass := pointer.PassOp{}.Push(gtx.Ops)
+	event.Op(gtx.Ops, &p.anchor)
+	pass.Pop()
+	o.frame = append(o.frame, overlayEntry{popup: p, content: content})
+	return dims
+}
+
+// Update the overlay state, closing the top popup if the Escape key
+// is pressed or if a pointer is pressed outside it.
+func (o *Overlay) Update(gtx layout.Context) {
+	o.prune()
+	for i := len(o.stack) - 1; i >= 0; i-- {
+		p := o.stack[i]
+		for {
+			ev, ok := gtx.Event(pointer.Filter{Target: p, Kinds: pointer.Press})
+			if !ok {
+				break
+			}
+			if e, ok := ev.(pointer.Event); ok && e.Kind == pointer.Press {
+				p.Close()
+			}
+		}
+	}
+	for {
+		ev, ok := gtx.Event(key.Filter{Name: key.NameEscape})
+		if !ok {
+			break
+		}
+		if e, ok := ev.(key.Event); ok && e.State == key.Press {
+			if n := len(o.stack); n > 0 {
+				o.stack[n-1].Close()
+				o.prune()
+			}
+		}
+	}
+	o.prune()
+}
+
+// prune removes closed popups from the stack, closing the popups above
+// them.
+func (o *Overlay) prune() {
+	for i, p := range o.stack {
+		if p.open {
+			continue
+		}
+		for _, p := range o.stack[i:] {
+			p.open = false
+		}
+		for j := i; j < len(o.stack); j++ {
+			o.stack[j] = nil
+		}
+		o.stack = o.stack[:i]
+		break
+	}
+}
+
+// Layout w and draw the open popups above it.
+func (o *Overlay) Layout(gtx layout.Context, w layout.Widget) layout.Dimensions {
+	o.Update(gtx)
+	o.viewport = image.Rectangle{Max: gtx.Constraints.Max}
+	o.frame = o.frame[:0]
+	start := ops.PCFor(&gtx.Ops.Internal)
+	dims := w(gtx)
+	o.locate(gtx.Ops, start, 0)
+	// Lay out the popups in batches, because the content of a popup may
+	// open popups of its own.
+	for laid := 0; laid < len(o.frame); {
+		batch := len(o.frame)
+		o.stackFrame(laid)
+		for _, p := range o.stack {
+			for i := laid; i < batch; i++ {
+				if e := o.frame[i]; e.popup == p && e.found {
+					o.layoutPopup(gtx, e)
+				}
+			}
+		}
+		laid = batch
+	}
+	o.unstackHidden()
+	return dims
+}
+
+// stackFrame adds the popups newly opened in the frame entries from index
+// first to the stack.
+func (o *Overlay) stackFrame(first int) {
+	o.prune()
+outer:
+	for _, e := range o.frame[first:] {
+		for _, p := range o.stack {
+			if p == e.popup {
+				continue outer
+			}
+		}
+		if e.popup.open && e.found {
+			o.stack = append(o.stack, e.popup)
+		}
+	}
+}
+
+// unstackHidden removes the popups whose anchors were not laid out in the
+// frame from the stack, so that they aren't closed by Escape while hidden.
+// They are stacked again when their anchors are laid out.
+func (o *Overlay) unstackHidden() {
+	stack := o.stack[:0]
+	for _, p := range o.stack {
+		for _, e := range o.frame {
+			if e.popup == p && e.found {
+				stack = append(stack, p)
+				break
+			}
+		}
+	}
+	for i := len(stack); i < len(o.stack); i++ {
+		o.stack[i] = nil
+	}
+	o.stack = stack
+}
+
+// locate computes the window coordinates of the anchors of the popups in
+// the frame entries from index first, by replaying the transformations of
+// the operations added since start.
+func (o *Overlay) locate(frame *op.Ops, start ops.PC, first int) {
+	var t f32.Affine2D
+	o.saved = o.saved[:0]
+	o.trans = o.trans[:0]
+	o.reader.ResetAt(&frame.Internal, start)
+	for encOp, ok := o.reader.Decode(); ok; encOp, ok = o.reader.Decode() {
+		switch ops.OpType(encOp.Data[0]) {
+		case ops.TypeSave:
+			id := ops.DecodeSave(encOp.Data)
+			if extra := id - len(o.saved) + 1; extra > 0 {
+				o.saved = append(o.saved, make([]f32.Affine2D, extra)...)
+			}
+			o.saved[id] = t
+		case ops.TypeLoad:
+			t = o.saved[ops.DecodeLoad(encOp.Data)]
+		case ops.TypeTransform:
+			t2, push := ops.DecodeTransform(encOp.Data)
+			if push {
+				o.trans = append(o.trans, t)
+			}
+			t = t.Mul(t2)
+		case ops.TypePopTransform:
+			n := len(o.trans)
+			t = o.trans[n-1]
+			o.trans = o.trans[:n-1]
+		case ops.TypeInput:
+			tag := encOp.Refs[0]
+			for i := first; i < len(o.frame); i++ {
+				e := &o.frame[i]
+				if tag == event.Tag(&e.popup.anchor) {
+					e.found = true
+					e.popup.anchor = transformRect(t, e.popup.size)
+				}
+			}
+		}
+	}
+}
+
+// transformRect returns the bounding box of the rectangle from the origin
+// to size, transformed by t.
+func transformRect(t f32.Affine2D, size image.Point) image.Rectangle {
+	sz := layout.FPt(size)
+	p0 := t.Transform(f32.Point{})
+	p1 := t.Transform(f32.Pt(sz.X, 0))
+	p2 := t.Transform(f32.Pt(0, sz.Y))
+	p3 := t.Transform(sz)
+	lo, hi := p0, p0
+	for _, p := range []f32.Point{p1, p2, p3} {
+		if p.X < lo.X {
+			lo.X = p.X
+		}
+		if p.X > hi.X {
+			hi.X = p.X
+		}
+		if p.Y < lo.Y {
+			lo.Y = p.Y
+		}
+		if p.Y > hi.Y {
+			hi.Y = p.Y
+		}
+	}
+	return image.Rectangle{Min: lo.Round(), Max: hi.Round()}
+}
+
+// layoutPopup lays out the content of a popup and defers drawing it above
+// the rest of the frame.
+func (o *Overlay) layoutPopup(gtx layout.Context, e overlayEntry) {
+	p := e.popup
+	nested := len(o.frame)
+	macro := op.Record(gtx.Ops)
+	cgtx := gtx
+	cgtx.Constraints = layout.Constraints{Max: o.viewport.Size()}
+	dims := e.content(cgtx)
+	call := macro.Stop()
+
+	p.bounds = place(p.Placement, p.anchor, dims.Size, o.viewport)
+	if len(o.frame) > nested {
+		// Locate the anchors of the popups opened by the content, as drawn
+		// at the position of the popup.
+		o.content.Reset()
+		off := op.Offset(p.bounds.Min).Push(&o.content)
+		call.Add(&o.content)
+		off.Pop()
+		o.locate(&o.content, ops.PC{}, nested)
+	}
+
+	macro = op.Record(gtx.Ops)
+	// Catch pointer presses outside the popup.
+	scrim := clip.Rect(o.viewport).Push(gtx.Ops)
+	event.Op(gtx.Ops, p)
+	scrim.Pop()
+	trans := op.Offset(p.bounds.Min).Push(gtx.Ops)
+	area := clip.Rect{Max: dims.Size}.Push(gtx.Ops)
+	// Block pointer events to the scrim.
+	event.Op(gtx.Ops, &p.tag)
+	call.Add(gtx.Ops)
+	area.Pop()
+	trans.Pop()
+	op.Defer(gtx.Ops, macro.Stop())
+}
+
+// place a popup of the specified size next to its anchor, flipping it to
+// the opposite side or shifting it if it overflows viewport.
+func place(pl Placement, anchor image.Rectangle, size image.Point, viewport image.Rectangle) image.Rectangle {
+	var pos image.Point
+	switch pl {
+	case Below, Above:
+		before := anchor.Min.Y - viewport.Min.Y
+		after := viewport.Max.Y - anchor.Max.Y
+		if pl == Below && size.Y > after && before > after {
+			pl = Above
+		} else if pl == Above && size.Y > before && after > before {
+			pl = Below
+		}
+		pos.X = anchor.Min.X
+		pos.Y = anchor.Max.Y
+		if pl == Above {
+			pos.Y = anchor.Min.Y - size.Y
+		}
+	case Right, Left:
+		before := anchor.Min.X - viewport.Min.X
+		after := viewport.Max.X - anchor.Max.X
+		if pl == Right && size.X > after && before > after {
+			pl = Left
+		} else if pl == Left && size.X > before && after > before {
+			pl = Right
+		}
+		pos.X = anchor.Max.X
+		if pl == Left {
+			pos.X = anchor.Min.X - size.X
+		}
+		pos.Y = anchor.Min.Y
+	}
+	// Shift the popup inside the viewport.
+	if d := pos.X + size.X - viewport.Max.X; d > 0 {
+		pos.X -= d
+	}
+	if d := pos.Y + size.Y - viewport.Max.Y; d > 0 {
+		pos.Y -= d
+	}
+	if pos.X < viewport.Min.X {
+		pos.X = viewport.Min.X
+	}
+	if pos.Y < viewport.Min.Y {
+		pos.Y = viewport.Min.Y
+	}
+	return image.Rectangle{Min: pos, Max: pos.Add(size)}
+}
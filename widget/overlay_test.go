@@ -0,0 +1,183 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget_test
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/widget"
+)
+
+func TestOverlayPlacement(t *testing.T) {
+	var (
+		r  input.Router
+		o  widget.Overlay
+		p1 widget.Popup
+		p2 widget.Popup
+	)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Source:      r.Source(),
+		Constraints: layout.Exact(image.Pt(100, 100)),
+	}
+	sized := func(w, h int) layout.Widget {
+		return func(gtx layout.Context) layout.Dimensions {
+			return layout.Dimensions{Size: image.Pt(w, h)}
+		}
+	}
+	frame := func() {
+		gtx.Reset()
+		o.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			st := op.Offset(image.Pt(10, 20)).Push(gtx.Ops)
+			p1.Layout(gtx, &o, sized(30, 10), sized(40, 40))
+			st.Pop()
+			st = op.Offset(image.Pt(50, 80)).Push(gtx.Ops)
+			p2.Layout(gtx, &o, sized(10, 10), sized(80, 30))
+			st.Pop()
+			return layout.Dimensions{Size: gtx.Constraints.Max}
+		})
+		r.Frame(gtx.Ops)
+	}
+	p1.Open()
+	p2.Open()
+	frame()
+	if got, want := p1.Bounds(), image.Rect(10, 30, 50, 70); got != want {
+		t.Errorf("popup 1 bounds: got %v, want %v", got, want)
+	}
+	// The second popup doesn't fit below its anchor, and is shifted left
+	// to fit the window.
+	if got, want := p2.Bounds(), image.Rect(20, 50, 100, 80); got != want {
+		t.Errorf("popup 2 bounds: got %v, want %v", got, want)
+	}
+
+	// Escape closes the top popup.
+	r.Queue(key.Event{Name: key.NameEscape, State: key.Press})
+	frame()
+	if !p1.Opened() || p2.Opened() {
+		t.Errorf("escape: got opened %v, %v, want true, false", p1.Opened(), p2.Opened())
+	}
+
+	// A press inside the popup keeps it open.
+	r.Queue(
+		pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: f32.Pt(20, 40)},
+		pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Position: f32.Pt(20, 40)},
+	)
+	frame()
+	if !p1.Opened() {
+		t.Error("press inside popup closed it")
+	}
+
+	// A press outside closes it.
+	r.Queue(
+		pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: f32.Pt(90, 10)},
+		pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Position: f32.Pt(90, 10)},
+	)
+	frame()
+	if p1.Opened() {
+		t.Error("press outside popup didn't close it")
+	}
+}
+
+func TestPopupCloseStacked(t *testing.T) {
+	var (
+		o      widget.Overlay
+		p1, p2 widget.Popup
+	)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(100, 100)),
+	}
+	w := func(gtx layout.Context) layout.Dimensions {
+		return layout.Dimensions{Size: image.Pt(10, 10)}
+	}
+	frame := func() {
+		gtx.Reset()
+		o.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			p1.Layout(gtx, &o, w, w)
+			p2.Layout(gtx, &o, w, w)
+			return layout.Dimensions{Size: gtx.Constraints.Max}
+		})
+	}
+	p1.Open()
+	frame()
+	p2.Open()
+	frame()
+	p1.Close()
+	frame()
+	if p2.Opened() {
+		t.Error("closing a popup didn't close the popup stacked above it")
+	}
+}
+
+func TestOverlayNested(t *testing.T) {
+	var (
+		r          input.Router
+		o          widget.Overlay
+		menu, sub  widget.Popup
+		hidden     widget.Popup
+		showHidden bool
+	)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Source:      r.Source(),
+		Constraints: layout.Exact(image.Pt(100, 100)),
+	}
+	sized := func(w, h int) layout.Widget {
+		return func(gtx layout.Context) layout.Dimensions {
+			return layout.Dimensions{Size: image.Pt(w, h)}
+		}
+	}
+	sub.Placement = widget.Right
+	frame := func() {
+		gtx.Reset()
+		o.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			st := op.Offset(image.Pt(10, 20)).Push(gtx.Ops)
+			menu.Layout(gtx, &o, sized(30, 10), func(gtx layout.Context) layout.Dimensions {
+				// A submenu opened from the menu.
+				st := op.Offset(image.Pt(5, 5)).Push(gtx.Ops)
+				sub.Layout(gtx, &o, sized(10, 10), sized(20, 20))
+				st.Pop()
+				return layout.Dimensions{Size: image.Pt(40, 40)}
+			})
+			st.Pop()
+			if showHidden {
+				hidden.Layout(gtx, &o, sized(10, 10), sized(10, 10))
+			}
+			return layout.Dimensions{Size: gtx.Constraints.Max}
+		})
+		r.Frame(gtx.Ops)
+	}
+	showHidden = true
+	hidden.Open()
+	frame()
+	showHidden = false
+	menu.Open()
+	sub.Open()
+	frame()
+	frame()
+	if got, want := menu.Bounds(), image.Rect(10, 30, 50, 70); got != want {
+		t.Errorf("menu bounds: got %v, want %v", got, want)
+	}
+	if got, want := sub.Bounds(), image.Rect(25, 35, 45, 55); got != want {
+		t.Errorf("submenu bounds: got %v, want %v", got, want)
+	}
+	// Escape closes the submenu, and then the menu, but not the popup
+	// whose anchor is hidden.
+	for _, want := range [][2]bool{{true, false}, {false, false}} {
+		r.Queue(key.Event{Name: key.NameEscape, State: key.Press})
+		frame()
+		if menu.Opened() != want[0] || sub.Opened() != want[1] {
+			t.Errorf("escape: got opened %v, %v, want %v", menu.Opened(), sub.Opened(), want)
+		}
+	}
+	if !hidden.Opened() {
+		t.Error("escape closed a hidden popup")
+	}
+}
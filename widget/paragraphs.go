@@ -0,0 +1,485 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"bytes"
+	"image"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gioui.org/layout"
+	"gioui.org/text"
+	"golang.org/x/exp/slices"
+	"golang.org/x/image/math/fixed"
+)
+
+// virtualSize is the size in bytes of text above which a textView lays out
+// the paragraphs of the text independently and on demand: an edit reshapes
+// only the paragraphs it affects, and only the paragraphs near the viewport
+// are shaped and indexed. The sizes of the other paragraphs are estimated
+// from the shaped paragraphs, and lines are aligned within the maximum
+// width of the text instead of the width of its widest line.
+const virtualSize = 64 * 1024
+
+// virtualText is the state of a textView whose paragraphs are laid out on
+// demand, as described by virtualSize.
+type virtualText struct {
+	enabled    bool
+	paragraphs []textParagraph
+	// positioned is the number of leading paragraphs whose offsets are up to
+	// date.
+	positioned int
+	// bounds are the horizontal bounds of the shaped paragraphs, computed
+	// again if not valid.
+	bounds struct {
+		minX, maxX int
+		valid      bool
+	}
+	// window is the range of paragraphs indexed by the textView index.
+	window struct {
+		start, end int
+	}
+	// far indexes a paragraph outside of the window.
+	far struct {
+		para  int
+		index glyphIndex
+		valid bool
+	}
+	// metrics are the sums of the metrics of the shaped paragraphs, used to
+	// estimate the layout of the other paragraphs.
+	metrics struct {
+		paragraphs                    int
+		baseline, lineHeight, descent int
+		runes                         int
+		advance                       fixed.Int26_6
+	}
+}
+
+// textParagraph is a paragraph of the text of a textView, laid out
+// independently of the other paragraphs.
+type textParagraph struct {
+	// bytes and runes are the length of the paragraph, including its
+	// trailing newline.
+	bytes, runes int
+	// byteOff, runeOff and lineOff are the offsets of the start of the
+	// paragraph in the text, and yOff is the vertical offset of its glyphs.
+	byteOff, runeOff, lineOff, yOff int
+	// shaped reports whether glyphs and the metrics below are up to date.
+	// Otherwise, the metrics are estimated.
+	shaped bool
+	glyphs []text.Glyph
+	lines  int
+	// baseline and last are the baselines of the first and last lines.
+	baseline, last int
+	// lineHeight is the distance between the baseline of the first line and
+	// the last line of the preceding paragraph.
+	lineHeight int
+	// bounds is the logical bounding box of the glyphs.
+	bounds image.Rectangle
+	// graphemes are the grapheme cluster boundaries of the paragraph, or nil
+	// if the paragraph hasn't been segmented.
+	graphemes []int
+}
+
+// layoutParagraphs shapes and indexes the paragraphs covering the viewport.
+func (e *textView) layoutParagraphs() {
+	v := &e.virt
+	if len(v.paragraphs) == 0 {
+		v.paragraphs = e.scanParagraphs(v.paragraphs, 0, e.rr.Size())
+		if len(v.paragraphs) == 0 {
+			v.paragraphs = append(v.paragraphs, textParagraph{})
+		}
+		v.positioned = 0
+	}
+	for {
+		e.positionParagraphs()
+		start, end := e.visibleParagraphs()
+		shaped := false
+		for k := start; k < end; k++ {
+			if !v.paragraphs[k].shaped {
+				e.shapeParagraph(k)
+				shaped = true
+			}
+		}
+		if !shaped {
+			v.window.start, v.window.end = start, end
+			break
+		}
+	}
+	first := v.paragraphs[v.window.start]
+	e.index.resetAt(first.runeOff, first.lineOff)
+	for k := v.window.start; k < v.window.end; k++ {
+		e.indexParagraph(&e.index, k)
+	}
+	b := &v.bounds
+	if !b.valid {
+		b.minX, b.maxX = 0, 0
+		for _, p := range v.paragraphs {
+			if p.shaped {
+				b.minX = min(b.minX, p.bounds.Min.X)
+				b.maxX = max(b.maxX, p.bounds.Max.X)
+			}
+		}
+		b.valid = true
+	}
+	first, last := v.paragraphs[0], v.paragraphs[len(v.paragraphs)-1]
+	top := first.yOff + first.bounds.Min.Y
+	dims := layout.Dimensions{Size: image.Pt(b.maxX-b.minX, last.yOff+last.bounds.Max.Y-top)}
+	dims.Baseline = dims.Size.Y - (first.yOff + first.baseline)
+	e.dims = dims
+}
+
+// windowCovers reports whether the indexed paragraphs cover the viewport.
+func (e *textView) windowCovers() bool {
+	v := &e.virt
+	if v.window.start > 0 {
+		p := v.paragraphs[v.window.start]
+		if p.yOff+p.bounds.Min.Y > e.scrollOff.Y {
+			return false
+		}
+	}
+	if v.window.end < len(v.paragraphs) {
+		p := v.paragraphs[v.window.end-1]
+		if p.yOff+p.bounds.Max.Y < e.scrollOff.Y+e.viewSize.Y {
+			return false
+		}
+	}
+	return true
+}
+
+// visibleParagraphs returns the range of paragraphs covering the viewport
+// and a margin of half its height around it.
+func (e *textView) visibleParagraphs() (start, end int) {
+	ps := e.virt.paragraphs
+	margin := e.viewSize.Y / 2
+	top, bottom := e.scrollOff.Y-margin, e.scrollOff.Y+e.viewSize.Y+margin
+	start = sort.Search(len(ps), func(i int) bool {
+		return ps[i].yOff+ps[i].bounds.Max.Y >= top
+	})
+	start = min(start, len(ps)-1)
+	end = start + 1
+	for end < len(ps) && ps[end].yOff+ps[end].bounds.Min.Y <= bottom {
+		end++
+	}
+	return start, end
+}
+
+// scanParagraphs appends the paragraphs of the text in the byte range
+// [start, end) to ps. Every paragraph ends with a newline except the last,
+// which is omitted if empty.
+func (e *textView) scanParagraphs(ps []textParagraph, start, end int64) []textParagraph {
+	var buf [4096]byte
+	var p textParagraph
+	for off := start; off < end; {
+		n, _ := e.rr.ReadAt(buf[:min(len(buf), int(end-off))], off)
+		if n == 0 {
+			break
+		}
+		b := buf[:n]
+		if off+int64(n) < end && incompleteRune(b) < n {
+			// Read an incomplete rune at the end of b again with the rest of
+			// its bytes.
+			b = b[:n-incompleteRune(b)]
+		}
+		off += int64(len(b))
+		for len(b) > 0 {
+			line := b
+			if i := bytes.IndexByte(b, '\n'); i != -1 {
+				line = b[:i+1]
+			}
+			b = b[len(line):]
+			p.bytes += len(line)
+			p.runes += utf8.RuneCount(line)
+			if line[len(line)-1] == '\n' {
+				ps = append(ps, p)
+				p = textParagraph{}
+			}
+		}
+	}
+	if p.bytes > 0 {
+		ps = append(ps, p)
+	}
+	return ps
+}
+
+// incompleteRune returns the length of the incomplete rune at the end of b,
+// if any.
+func incompleteRune(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return len(b) - i
+			}
+			break
+		}
+	}
+	return 0
+}
+
+// replaceParagraphs updates the paragraphs after the replacement of the
+// bytes [start, end) of the text with the bytes [start, newEnd).
+func (e *textView) replaceParagraphs(start, end, newEnd int64) {
+	v := &e.virt
+	if len(v.paragraphs) == 0 {
+		return
+	}
+	e.positionParagraphs()
+	i, j := e.paragraphAtByte(int(start)), e.paragraphAtByte(int(end))
+	for _, p := range v.paragraphs[i : j+1] {
+		if b := &v.bounds; p.shaped && (p.bounds.Min.X == b.minX || p.bounds.Max.X == b.maxX) {
+			// The bounds may shrink without p.
+			b.valid = false
+		}
+	}
+	regionEnd := int64(v.paragraphs[j].byteOff+v.paragraphs[j].bytes) + newEnd - end
+	replaced := e.scanParagraphs(nil, int64(v.paragraphs[i].byteOff), regionEnd)
+	v.paragraphs = slices.Replace(v.paragraphs, i, j+1, replaced...)
+	if len(v.paragraphs) == 0 {
+		v.paragraphs = append(v.paragraphs, textParagraph{})
+	}
+	v.positioned = min(v.positioned, i)
+}
+
+// invalidateParagraphs marks every paragraph for reshaping.
+func (e *textView) invalidateParagraphs() {
+	v := &e.virt
+	for i := range v.paragraphs {
+		v.paragraphs[i].shaped = false
+	}
+	v.metrics = virtualText{}.metrics
+	v.positioned = 0
+	v.bounds.valid = false
+}
+
+// positionParagraphs updates the offsets of the paragraphs, estimating the
+// layout of the paragraphs that aren't shaped.
+func (e *textView) positionParagraphs() {
+	v := &e.virt
+	if v.positioned == len(v.paragraphs) {
+		return
+	}
+	v.far.valid = false
+	for k := v.positioned; k < len(v.paragraphs); k++ {
+		p := &v.paragraphs[k]
+		if !p.shaped {
+			e.estimateParagraph(p)
+		}
+		if k == 0 {
+			p.byteOff, p.runeOff, p.lineOff, p.yOff = 0, 0, 0, 0
+			continue
+		}
+		prev := v.paragraphs[k-1]
+		p.byteOff = prev.byteOff + prev.bytes
+		p.runeOff = prev.runeOff + prev.runes
+		p.lineOff = prev.lineOff + prev.lines
+		p.yOff = prev.yOff + prev.last + p.lineHeight - p.baseline
+	}
+	v.positioned = len(v.paragraphs)
+}
+
+// estimateParagraph estimates the layout of p from the metrics of the
+// shaped paragraphs.
+func (e *textView) estimateParagraph(p *textParagraph) {
+	m := e.virt.metrics
+	baseline, lineHeight, descent := e.params.PxPerEm.Ceil(), e.params.PxPerEm.Ceil(), 0
+	if m.paragraphs > 0 {
+		baseline = m.baseline / m.paragraphs
+		lineHeight = m.lineHeight / m.paragraphs
+		descent = m.descent / m.paragraphs
+	}
+	p.lines = 1
+	if m.runes > 0 && e.params.MaxWidth > 0 {
+		width := int64(m.advance) * int64(p.runes) / int64(m.runes)
+		maxWidth := int64(fixed.I(e.params.MaxWidth))
+		p.lines = max(int((width+maxWidth-1)/maxWidth), 1)
+	}
+	p.baseline = baseline
+	p.last = baseline + (p.lines-1)*lineHeight
+	p.lineHeight = lineHeight
+	p.bounds = image.Rect(0, 0, 0, p.last+descent)
+}
+
+// shapeParagraph lays out the paragraph at index k.
+func (e *textView) shapeParagraph(k int) {
+	v := &e.virt
+	p := &v.paragraphs[k]
+	params := e.params
+	// Align every paragraph within the same width.
+	params.MinWidth = max(params.MinWidth, params.MaxWidth)
+	e.spans = e.styleSpans(e.spans[:0], p.runeOff, p.runes)
+	para := e.shaper.ParagraphSpans(params, e.spans, e.paragraphText(*p))
+	final := k == len(v.paragraphs)-1
+	it := textIterator{viewport: image.Rectangle{Max: image.Point{X: math.MaxInt, Y: math.MaxInt}}}
+	p.glyphs = p.glyphs[:0]
+	p.lines = 0
+	var advance fixed.Int26_6
+	glyphs := para.Glyphs()
+	for g, ok := glyphs.Next(); ok; g, ok = glyphs.Next() {
+		if k > 0 && len(p.glyphs) == 0 {
+			g.Flags |= text.FlagParagraphStart
+		}
+		it.processGlyph(g, true)
+		p.glyphs = append(p.glyphs, g)
+		advance += g.Advance
+		if g.Flags&text.FlagLineBreak != 0 {
+			p.lines++
+		}
+		if g.Flags&text.FlagParagraphBreak != 0 && !final {
+			// Omit the empty line following the paragraph when laid out
+			// by itself.
+			break
+		}
+	}
+	p.baseline = int(p.glyphs[0].Y)
+	p.last = int(p.glyphs[len(p.glyphs)-1].Y)
+	p.lineHeight = para.Measure().Lines[0].LineHeight.Round()
+	p.bounds = it.bounds
+	p.shaped = true
+
+	m := &v.metrics
+	m.paragraphs++
+	m.baseline += p.baseline
+	m.lineHeight += p.lineHeight
+	m.descent += p.bounds.Max.Y - p.last
+	m.runes += p.runes
+	m.advance += advance
+	if b := &v.bounds; b.valid {
+		b.minX = min(b.minX, p.bounds.Min.X)
+		b.maxX = max(b.maxX, p.bounds.Max.X)
+	}
+	// Only the paragraphs after p move. The estimates of the paragraphs
+	// before p are kept, so that shaping p doesn't move the text above it.
+	v.positioned = min(v.positioned, k+1)
+}
+
+// paragraphText returns the text displayed for p.
+func (e *textView) paragraphText(p textParagraph) string {
+	var b strings.Builder
+	b.Grow(p.bytes)
+	r := io.NewSectionReader(e.rr, int64(p.byteOff), int64(p.bytes))
+	if e.Mask != 0 {
+		e.maskReader.Reset(r, e.Mask)
+		io.Copy(&b, &e.maskReader)
+	} else {
+		io.Copy(&b, r)
+	}
+	return b.String()
+}
+
+// indexParagraph adds the glyphs of the paragraph at index k to index.
+func (e *textView) indexParagraph(index *glyphIndex, k int) {
+	p := e.virt.paragraphs[k]
+	for _, g := range p.glyphs {
+		g.Y += int32(p.yOff)
+		index.Glyph(g)
+	}
+}
+
+// paragraphIndex returns an index of the paragraph at index k, shaping it
+// if necessary.
+func (e *textView) paragraphIndex(k int) *glyphIndex {
+	v := &e.virt
+	if !v.paragraphs[k].shaped {
+		e.shapeParagraph(k)
+		// The offsets of the other paragraphs changed.
+		e.layoutParagraphs()
+	}
+	if k >= v.window.start && k < v.window.end {
+		return &e.index
+	}
+	if !v.far.valid || v.far.para != k {
+		p := v.paragraphs[k]
+		v.far.index.resetAt(p.runeOff, p.lineOff)
+		e.indexParagraph(&v.far.index, k)
+		v.far.para, v.far.valid = k, true
+	}
+	return &v.far.index
+}
+
+// paragraphAtByte returns the index of the paragraph containing the byte
+// offset off.
+func (e *textView) paragraphAtByte(off int) int {
+	ps := e.virt.paragraphs
+	k := sort.Search(len(ps), func(i int) bool { return ps[i].byteOff > off })
+	return max(k-1, 0)
+}
+
+// paragraphAtRune returns the index of the paragraph containing the rune
+// at index r.
+func (e *textView) paragraphAtRune(r int) int {
+	ps := e.virt.paragraphs
+	k := sort.Search(len(ps), func(i int) bool { return ps[i].runeOff > r })
+	return max(k-1, 0)
+}
+
+// paragraphAtLine returns the index of the paragraph containing the line.
+func (e *textView) paragraphAtLine(line int) int {
+	ps := e.virt.paragraphs
+	k := sort.Search(len(ps), func(i int) bool { return ps[i].lineOff > line })
+	return max(k-1, 0)
+}
+
+// paragraphAtY returns the index of the first paragraph extending below y.
+func (e *textView) paragraphAtY(y int) int {
+	ps := e.virt.paragraphs
+	k := sort.Search(len(ps), func(i int) bool {
+		return ps[i].yOff+ps[i].bounds.Max.Y >= y
+	})
+	return min(k, len(ps)-1)
+}
+
+// paragraphGraphemes returns the grapheme cluster boundaries of the
+// paragraph at index k, relative to its start.
+func (e *textView) paragraphGraphemes(k int) []int {
+	p := &e.virt.paragraphs[k]
+	if p.graphemes == nil {
+		e.paragraphReader.SetSource(io.NewSectionReader(e.rr, int64(p.byteOff), int64(p.bytes)))
+		p.graphemes = append(make([]int, 0), e.paragraphReader.Graphemes()...)
+	}
+	return p.graphemes
+}
+
+// moveParagraphGraphemes is moveByGraphemes for text laid out by paragraph.
+func (e *textView) moveParagraphGraphemes(startRuneidx, graphemes int) int {
+	k := e.paragraphAtRune(startRuneidx)
+	gs := e.paragraphGraphemes(k)
+	if len(gs) == 0 {
+		return startRuneidx
+	}
+	i, _ := slices.BinarySearch(gs, startRuneidx-e.virt.paragraphs[k].runeOff)
+	i += graphemes
+	// The last boundary of a paragraph is the first of the next.
+	for i < 0 && k > 0 {
+		k--
+		gs = e.paragraphGraphemes(k)
+		i += len(gs) - 1
+	}
+	for i >= len(gs) && k < len(e.virt.paragraphs)-1 {
+		i -= len(gs) - 1
+		k++
+		gs = e.paragraphGraphemes(k)
+	}
+	if len(gs) == 0 {
+		return e.virt.paragraphs[k].runeOff
+	}
+	i = max(min(i, len(gs)-1), 0)
+	return e.closestToRune(e.virt.paragraphs[k].runeOff + gs[i]).runes
+}
+
+// paragraphRuneOffset is runeOffset for text laid out by paragraph.
+func (e *textView) paragraphRuneOffset(r int) int {
+	p := e.virt.paragraphs[e.paragraphAtRune(r)]
+	off := p.byteOff
+	for i := p.runeOff; i < r; i++ {
+		_, s, _ := e.ReadRuneAt(int64(off))
+		if s == 0 {
+			break
+		}
+		off += s
+	}
+	return off
+}
@@ -0,0 +1,193 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"fmt"
+	"image"
+	"strings"
+	"testing"
+
+	"gioui.org/font"
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+)
+
+// newVirtualTestViews returns a textView laying out src on demand and a
+// textView laying out all of src, which is forced by a limit on the number
+// of lines.
+func newVirtualTestViews(src string) (virtual, full *textView) {
+	virtual, full = new(textView), &textView{MaxLines: 1 << 20}
+	for _, v := range []*textView{virtual, full} {
+		buf := new(pieceTable)
+		buf.ReplaceRunes(0, 0, src)
+		v.SetSource(buf)
+	}
+	return virtual, full
+}
+
+func virtualTestText(lines int) string {
+	var b strings.Builder
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&b, "line %d of the document", i)
+		if i%7 == 3 {
+			b.WriteString(", which is long enough to wrap across more than one line of the view")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func TestTextViewVirtual(t *testing.T) {
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(300, 200)),
+		Locale:      english,
+	}
+	shaper := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	src := virtualTestText(4000)
+	virtual, full := newVirtualTestViews(src)
+	for _, v := range []*textView{virtual, full} {
+		v.Layout(gtx, shaper, font.Font{}, unit.Sp(10))
+	}
+	if !virtual.virt.enabled || full.virt.enabled {
+		t.Fatalf("got virtual layouts %v and %v, want true and false", virtual.virt.enabled, full.virt.enabled)
+	}
+	shaped := func() int {
+		n := 0
+		for _, p := range virtual.virt.paragraphs {
+			if p.shaped {
+				n++
+			}
+		}
+		return n
+	}
+	if n := shaped(); n == 0 || n > 100 {
+		t.Errorf("got %d shaped paragraphs of %d, want only the visible paragraphs", n, len(virtual.virt.paragraphs))
+	}
+	compare := func() {
+		t.Helper()
+		for r := 0; r <= full.Len(); r += 997 {
+			got, want := virtual.closestToRune(r), full.closestToRune(r)
+			if got.runes != want.runes || got.lineCol != want.lineCol || got.x != want.x || got.y != want.y {
+				t.Errorf("rune %d: got position %+v, want %+v", r, got, want)
+			}
+			if gotOff, wantOff := virtual.ByteOffset(r), full.ByteOffset(r); gotOff != wantOff {
+				t.Errorf("rune %d: got byte offset %d, want %d", r, gotOff, wantOff)
+			}
+			full.SetCaret(r, r)
+			virtual.SetCaret(r, r)
+			gotLine, gotCol := virtual.CaretPos()
+			wantLine, wantCol := full.CaretPos()
+			if gotLine != wantLine || gotCol != wantCol {
+				t.Errorf("rune %d: got caret at %d:%d, want %d:%d", r, gotLine, gotCol, wantLine, wantCol)
+			}
+		}
+		if got, want := virtual.Len(), full.Len(); got != want {
+			t.Errorf("got length %d, want %d", got, want)
+		}
+	}
+	compare()
+
+	// Moving by lines crosses paragraphs that are not indexed.
+	for _, v := range []*textView{virtual, full} {
+		v.SetCaret(0, 0)
+		v.MoveLines(2500, selectionClear)
+		v.MoveCaret(3, 3)
+	}
+	if got, want := virtual.caret.start, full.caret.start; got != want {
+		t.Errorf("got caret %d after moving, want %d", got, want)
+	}
+
+	// Scrolling shapes the paragraphs that become visible.
+	virtual.ScrollRel(0, virtual.FullDimensions().Size.Y/2)
+	virtual.PaintText(gtx, op.CallOp{})
+	scrolled := virtual.virt.paragraphs[virtual.virt.window.start:virtual.virt.window.end]
+	if len(scrolled) == 0 || scrolled[0].runeOff < len([]rune(src))/4 {
+		t.Errorf("got window %+v after scrolling, want paragraphs in the middle of the text", virtual.virt.window)
+	}
+
+	// Edits reshape only the paragraphs they affect.
+	before := shaped()
+	at := virtual.caret.start
+	for _, v := range []*textView{virtual, full} {
+		v.Replace(at, at+10, "inserted\nparagraphs\n")
+		v.Layout(gtx, shaper, font.Font{}, unit.Sp(10))
+	}
+	if n := shaped(); n < before-1 {
+		t.Errorf("got %d shaped paragraphs after an edit, want at least %d", n, before-1)
+	}
+	if got, want := string(virtual.Text(nil)), string(full.Text(nil)); got != want {
+		t.Errorf("text differs after an edit")
+	}
+	compare()
+}
+
+func TestTextViewVirtualShape(t *testing.T) {
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(300, 200)),
+		Locale:      english,
+	}
+	shaper := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	virtual, _ := newVirtualTestViews(virtualTestText(4000))
+	virtual.Layout(gtx, shaper, font.Font{}, unit.Sp(10))
+	v := &virtual.virt
+	k := len(v.paragraphs) / 2
+	if v.paragraphs[k].shaped {
+		t.Fatalf("paragraph %d shaped before it is visible", k)
+	}
+	yOff := v.paragraphs[k].yOff
+	virtual.shapeParagraph(k)
+	// Only the paragraphs after the shaped paragraph are positioned again.
+	if v.positioned != k+1 {
+		t.Errorf("got %d positioned paragraphs after shaping paragraph %d, want %d", v.positioned, k, k+1)
+	}
+	virtual.positionParagraphs()
+	if got := v.paragraphs[k].yOff; got != yOff {
+		t.Errorf("shaped paragraph moved from %d to %d", yOff, got)
+	}
+	virtual.Replace(0, 0, "an inserted paragraph\n")
+	virtual.Layout(gtx, shaper, font.Font{}, unit.Sp(10))
+	var minX, maxX int
+	for _, p := range v.paragraphs {
+		if p.shaped {
+			minX = min(minX, p.bounds.Min.X)
+			maxX = max(maxX, p.bounds.Max.X)
+		}
+	}
+	if got, want := virtual.FullDimensions().Size.X, maxX-minX; got != want {
+		t.Errorf("got width %d, want %d", got, want)
+	}
+}
+
+func TestScanParagraphs(t *testing.T) {
+	for _, src := range []string{"", "a", "a\n", "a\nb", "\n\n", strings.Repeat("é\n", 3000)} {
+		v := new(textView)
+		v.SetSource(newStringSource(src))
+		ps := v.scanParagraphs(nil, 0, int64(len(src)))
+		var b strings.Builder
+		runes := 0
+		off := 0
+		for _, p := range ps {
+			b.WriteString(src[off : off+p.bytes])
+			off += p.bytes
+			runes += p.runes
+			if p.bytes == 0 {
+				t.Errorf("%q: empty paragraph", src)
+			}
+		}
+		if got := b.String(); got != src {
+			t.Errorf("%q: paragraphs cover %q", src, got)
+		}
+		if want := len([]rune(src)); runes != want {
+			t.Errorf("%q: got %d runes, want %d", src, runes, want)
+		}
+		if want := strings.Count(src, "\n"); len(ps) != want && !(len(ps) == want+1 && !strings.HasSuffix(src, "\n")) {
+			t.Errorf("%q: got %d paragraphs", src, len(ps))
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"io"
+	"sort"
+	"unicode/utf8"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/text/runes"
+)
+
+// pieceTable implements a piece table for text editing. The text is a
+// sequence of pieces of an append-only buffer of inserted text, such that
+// the cost of an edit is proportional to the size of the edit and the
+// number of pieces, not to the size of the text.
+type pieceTable struct {
+	// added holds the text of every insertion. Its contents are never
+	// modified once appended.
+	added  []byte
+	pieces []piece
+	// ends are the offsets of the ends of the pieces in the text.
+	ends []int64
+
+	// changed tracks whether the buffer content
+	// has changed since the last call to Changed.
+	changed bool
+}
+
+// piece is a range of the added text of a pieceTable.
+type piece struct {
+	off, len int
+}
+
+var _ TextSource = (*pieceTable)(nil)
+
+func (t *pieceTable) Changed() bool {
+	c := t.changed
+	t.changed = false
+	return c
+}
+
+func (t *pieceTable) Size() int64 {
+	if len(t.ends) == 0 {
+		return 0
+	}
+	return t.ends[len(t.ends)-1]
+}
+
+func (t *pieceTable) ReadAt(p []byte, offset int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if offset >= t.Size() {
+		return 0, io.EOF
+	}
+	var total int
+	for i := t.pieceAt(offset); i < len(t.pieces) && len(p) > 0; i++ {
+		pc := t.pieces[i]
+		start := t.ends[i] - int64(pc.len)
+		n := copy(p, t.added[pc.off+int(offset-start):pc.off+pc.len])
+		p = p[n:]
+		total += n
+		offset += int64(n)
+	}
+	return total, nil
+}
+
+func (t *pieceTable) ReplaceRunes(byteOffset, runeCount int64, s string) {
+	if !utf8.ValidString(s) {
+		s = runes.ReplaceIllFormed().String(s)
+	}
+	end := byteOffset
+	var buf [utf8.UTFMax]byte
+	for ; runeCount > 0 && end < t.Size(); runeCount-- {
+		n, _ := t.ReadAt(buf[:], end)
+		_, size := utf8.DecodeRune(buf[:n])
+		end += int64(size)
+	}
+	t.replace(byteOffset, end, s)
+}
+
+// replace replaces the bytes in the range [start, end) with s.
+func (t *pieceTable) replace(start, end int64, s string) {
+	if start == end && len(s) == 0 {
+		return
+	}
+	t.changed = true
+	i := t.split(start)
+	j := t.split(end)
+	var inserted []piece
+	switch {
+	case len(s) == 0:
+	case i > 0 && t.pieces[i-1].off+t.pieces[i-1].len == len(t.added):
+		// Extend the previous piece, such that typing text doesn't create a
+		// piece for every insertion.
+		t.pieces[i-1].len += len(s)
+		t.added = append(t.added, s...)
+	default:
+		inserted = append(inserted, piece{off: len(t.added), len: len(s)})
+		t.added = append(t.added, s...)
+	}
+	t.pieces = slices.Replace(t.pieces, i, j, inserted...)
+	t.ends = t.ends[:0]
+	var off int64
+	for _, pc := range t.pieces {
+		off += int64(pc.len)
+		t.ends = append(t.ends, off)
+	}
+}
+
+// split splits the piece containing off, if any, such that a piece starts
+// at off. It returns the index of that piece.
+func (t *pieceTable) split(off int64) int {
+	i := t.pieceAt(off)
+	if i == len(t.pieces) {
+		return i
+	}
+	start := t.ends[i] - int64(t.pieces[i].len)
+	if start == off {
+		return i
+	}
+	pc := t.pieces[i]
+	n := int(off - start)
+	t.pieces = slices.Insert(t.pieces, i+1, piece{off: pc.off + n, len: pc.len - n})
+	t.pieces[i].len = n
+	t.ends = slices.Insert(t.ends, i, off)
+	return i + 1
+}
+
+// pieceAt returns the index of the piece containing the byte at off, or
+// the number of pieces if off is at or past the end of the text.
+func (t *pieceTable) pieceAt(off int64) int {
+	return sort.Search(len(t.ends), func(i int) bool {
+		return t.ends[i] > off
+	})
+}
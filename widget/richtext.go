@@ -0,0 +1,228 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"unicode/utf8"
+
+	"gioui.org/font"
+	"gioui.org/gesture"
+	"gioui.org/io/pointer"
+	"gioui.org/io/semantic"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/text"
+	"gioui.org/unit"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// SpanStyle describes the content and style of a span of rich text.
+type SpanStyle struct {
+	// Content is the text of the span.
+	Content string
+	// Font of the span.
+	Font font.Font
+	// Size of the span.
+	Size unit.Sp
+	// BaselineShift moves the span above the baseline if positive, or
+	// below it if negative.
+	BaselineShift unit.Sp
+	// Material sets the paint material of the glyphs and decorations of
+	// the span.
+	Material op.CallOp
+	// Decoration draws lines along the span, such as underlines.
+	Decoration text.Decoration
+	// Interactive spans, such as links, report clicks through
+	// RichText.Update.
+	Interactive bool
+}
+
+// RichText is a widget for laying out and drawing text with multiple
+// styles that wrap together as a single text. Spans of the text can be
+// clicked, for example to follow links.
+type RichText struct {
+	// Alignment specifies the text alignment.
+	Alignment text.Alignment
+	// MaxLines limits the number of lines. Zero means no limit.
+	MaxLines int
+	// Truncator is the text that will be shown at the end of the final
+	// line if MaxLines is exceeded. Defaults to "…" if empty.
+	Truncator string
+	// WrapPolicy configures how displayed text will be broken into lines.
+	WrapPolicy text.WrapPolicy
+	// LineHeight controls the distance between the baselines of lines of text.
+	// If zero, a sensible default will be used.
+	LineHeight unit.Sp
+	// LineHeightScale applies a scaling factor to the LineHeight. If zero, a
+	// sensible default will be used.
+	LineHeightScale float32
+	// SkipInk interrupts underlines where glyphs descend through them.
+	SkipInk bool
+	// Features is a list of OpenType features to apply when shaping, in the
+	// format of text.Parameters.Features.
+	Features string
+	// Variations sets the variation axes of variable fonts, in the format
+	// of text.Parameters.Variations.
+	Variations string
+
+	click gesture.Click
+	// regions are the bounds of the interactive spans of the most recent
+	// layout.
+	regions []spanRegion
+	// pressed is the interactive span under the most recent press, or -1.
+	pressed int
+	spans   []text.Span
+	content []byte
+}
+
+// spanRegion is the bounds of the part of an interactive span on a single
+// line.
+type spanRegion struct {
+	span   int
+	bounds image.Rectangle
+}
+
+// Update the state of the text, and return the index of the next clicked
+// interactive span, if any.
+func (t *RichText) Update(gtx layout.Context) (int, bool) {
+	for {
+		e, ok := t.click.Update(gtx.Source)
+		if !ok {
+			break
+		}
+		switch e.Kind {
+		case gesture.KindPress:
+			t.pressed = t.spanAt(e.Position)
+		case gesture.KindClick:
+			if s := t.spanAt(e.Position); s != -1 && s == t.pressed {
+				t.pressed = -1
+				return s, true
+			}
+		case gesture.KindCancel:
+			t.pressed = -1
+		}
+	}
+	return 0, false
+}
+
+// Hovered reports whether a pointer is over an interactive span.
+func (t *RichText) Hovered() bool {
+	return t.click.Hovered()
+}
+
+// spanAt returns the interactive span at pos, or -1.
+func (t *RichText) spanAt(pos image.Point) int {
+	for _, r := range t.regions {
+		if pos.In(r.bounds) {
+			return r.span
+		}
+	}
+	return -1
+}
+
+// Layout the spans with the given shaper, painting every span with its
+// material.
+func (t *RichText) Layout(gtx layout.Context, lt *text.Shaper, spans []SpanStyle) layout.Dimensions {
+	for {
+		if _, ok := t.Update(gtx); !ok {
+			break
+		}
+	}
+	t.spans = t.spans[:0]
+	t.content = t.content[:0]
+	for _, s := range spans {
+		t.content = append(t.content, s.Content...)
+		t.spans = append(t.spans, text.Span{
+			Runes:         utf8.RuneCountInString(s.Content),
+			Font:          s.Font,
+			PxPerEm:       fixed.I(gtx.Sp(s.Size)),
+			BaselineShift: fixed.I(gtx.Sp(s.BaselineShift)),
+		})
+	}
+	txt := string(t.content)
+	cs := gtx.Constraints
+	params := text.Parameters{
+		MaxLines:        t.MaxLines,
+		Truncator:       t.Truncator,
+		Alignment:       t.Alignment,
+		WrapPolicy:      t.WrapPolicy,
+		MaxWidth:        cs.Max.X,
+		MinWidth:        cs.Min.X,
+		Locale:          gtx.Locale,
+		LineHeight:      fixed.I(gtx.Sp(t.LineHeight)),
+		LineHeightScale: t.LineHeightScale,
+		Features:        t.Features,
+		Variations:      t.Variations,
+	}
+	if len(t.spans) > 0 {
+		// The truncator is styled like the first span.
+		params.Font = t.spans[0].Font
+		params.PxPerEm = t.spans[0].PxPerEm
+	}
+	lt.LayoutSpans(params, t.spans, txt)
+	m := op.Record(gtx.Ops)
+	viewport := image.Rectangle{Max: cs.Max}
+	it := textIterator{
+		viewport: viewport,
+		maxLines: t.MaxLines,
+		skipInk:  t.SkipInk,
+	}
+	semantic.LabelOp(txt).Add(gtx.Ops)
+	t.regions = t.regions[:0]
+	var glyphs [32]text.Glyph
+	line := glyphs[:0]
+	for g, ok := lt.NextGlyph(); ok; g, ok = lt.NextGlyph() {
+		if len(line) > 0 && line[0].Span != g.Span {
+			line = it.paintLine(gtx, lt, line)
+		}
+		var span SpanStyle
+		if g.Span < len(spans) {
+			span = spans[g.Span]
+		}
+		it.material = span.Material
+		it.decoration = span.Decoration
+		var ok bool
+		if line, ok = it.paintGlyph(gtx, lt, g, line); !ok {
+			break
+		}
+		if span.Interactive && it.visible {
+			t.addRegion(g)
+		}
+	}
+	call := m.Stop()
+	viewport.Min = viewport.Min.Add(it.padding.Min)
+	viewport.Max = viewport.Max.Add(it.padding.Max)
+	clipStack := clip.Rect(viewport).Push(gtx.Ops)
+	call.Add(gtx.Ops)
+	for _, r := range t.regions {
+		area := clip.Rect(r.bounds).Push(gtx.Ops)
+		pointer.CursorPointer.Add(gtx.Ops)
+		t.click.Add(gtx.Ops)
+		area.Pop()
+	}
+	dims := layout.Dimensions{Size: it.bounds.Size()}
+	dims.Size = cs.Constrain(dims.Size)
+	dims.Baseline = dims.Size.Y - it.baseline
+	clipStack.Pop()
+	return dims
+}
+
+// addRegion extends the interactive regions to cover the logical bounds of
+// g.
+func (t *RichText) addRegion(g text.Glyph) {
+	b := image.Rectangle{
+		Min: image.Pt(g.X.Floor(), int(g.Y)-g.Ascent.Ceil()),
+		Max: image.Pt((g.X + g.Advance).Ceil(), int(g.Y)+g.Descent.Ceil()),
+	}
+	if n := len(t.regions); n > 0 {
+		r := &t.regions[n-1]
+		if r.span == g.Span && r.bounds.Min.Y == b.Min.Y && r.bounds.Max.Y == b.Max.Y {
+			r.bounds = r.bounds.Union(b)
+			return
+		}
+	}
+	t.regions = append(t.regions, spanRegion{span: g.Span, bounds: b})
+}
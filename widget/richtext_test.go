@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget_test
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+)
+
+func TestRichTextClick(t *testing.T) {
+	var (
+		r  input.Router
+		rt widget.RichText
+	)
+	shaper := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Source:      r.Source(),
+		Constraints: layout.Exact(image.Pt(1000, 100)),
+	}
+	spans := []widget.SpanStyle{
+		{Content: "Visit ", Size: 10},
+		{Content: "the link", Size: 20, Interactive: true},
+		{Content: " now", Size: 10, BaselineShift: unit.Sp(3)},
+	}
+	var clicked []int
+	frame := func() {
+		gtx.Reset()
+		for {
+			s, ok := rt.Update(gtx)
+			if !ok {
+				break
+			}
+			clicked = append(clicked, s)
+		}
+		rt.Layout(gtx, shaper, spans)
+		r.Frame(gtx.Ops)
+	}
+	click := func(x, y float32) {
+		r.Queue(
+			pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: f32.Pt(x, y)},
+			pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Position: f32.Pt(x, y)},
+		)
+		frame()
+	}
+	frame()
+	// A click on the plain text is ignored.
+	click(5, 10)
+	if len(clicked) != 0 {
+		t.Errorf("click on plain text reported spans %v", clicked)
+	}
+	// The link starts after the plain text, which is narrower than 40
+	// pixels at size 10.
+	click(50, 15)
+	if len(clicked) != 1 || clicked[0] != 1 {
+		t.Errorf("click on link: got spans %v, want [1]", clicked)
+	}
+}
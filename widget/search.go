@@ -0,0 +1,512 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"golang.org/x/exp/slices"
+)
+
+// Search describes the text to find in an Editor or Selectable.
+type Search struct {
+	// Pattern is the text to find, or a regular expression in the syntax of
+	// package regexp if Regexp is set.
+	Pattern string
+	// IgnoreCase matches letters regardless of their case.
+	IgnoreCase bool
+	// WholeWord matches only text not preceded or followed by a letter,
+	// digit or underscore.
+	WholeWord bool
+	// Regexp interprets Pattern as a regular expression.
+	Regexp bool
+	// Highlight is the material of the background of the matches. If zero,
+	// the matches are not highlighted.
+	Highlight op.CallOp
+}
+
+// Match is the range of runes of text matching a Search.
+type Match struct {
+	Start, End int
+}
+
+// textSearch is the state of the search of a textView.
+type textSearch struct {
+	query Search
+	re    *regexp.Regexp
+	// local is set if the matches of re are within paragraphs and don't
+	// depend on the text around their paragraphs, so that only the
+	// paragraphs changed by edits are searched again.
+	local bool
+	// matches are the matches of the query, valid unless stale is set or
+	// dirty is pending.
+	matches []Match
+	// locs are the byte offsets of the matches and their submatches,
+	// relative to the start of the match.
+	locs [][]int
+	// current is the index of the current match, or -1.
+	current int
+	// pos is the start of the current match, adjusted for edits.
+	pos int
+	// stale is set if the whole text is to be searched again, and dirty is
+	// the range of runes to search again otherwise.
+	stale bool
+	dirty changedRange
+	// buf is the text searched.
+	buf []byte
+}
+
+// compile returns the regular expression matching s.
+func (s Search) compile() (*regexp.Regexp, error) {
+	pattern := s.Pattern
+	if !s.Regexp {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if s.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// paragraphLocal reports whether re matches only text within a paragraph,
+// regardless of the text around the paragraph.
+func paragraphLocal(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpAnyChar, syntax.OpBeginText, syntax.OpEndText:
+		return false
+	case syntax.OpLiteral:
+		if slices.Contains(re.Rune, '\n') {
+			return false
+		}
+	case syntax.OpCharClass:
+		// Rune is a list of ranges of runes.
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			if re.Rune[i] <= '\n' && '\n' <= re.Rune[i+1] {
+				return false
+			}
+		}
+	}
+	for _, sub := range re.Sub {
+		if !paragraphLocal(sub) {
+			return false
+		}
+	}
+	return true
+}
+
+// Find searches the text for s, and returns the matches. The current match
+// is the first match starting at or after the caret.
+func (e *textView) Find(s Search) ([]Match, error) {
+	re, err := s.compile()
+	if err != nil {
+		return nil, err
+	}
+	e.search.query = s
+	e.search.re = re
+	// The pattern is valid, because it compiled.
+	parsed, _ := syntax.Parse(re.String(), syntax.Perl)
+	e.search.local = parsed != nil && paragraphLocal(parsed)
+	e.search.pos = min(e.caret.start, e.caret.end)
+	e.search.current = 0
+	e.search.stale = true
+	e.refreshMatches()
+	return e.search.matches, nil
+}
+
+// ClearMatches ends the search started by Find.
+func (e *textView) ClearMatches() {
+	e.search.query = Search{}
+	e.search.re = nil
+	e.search.matches = e.search.matches[:0]
+	e.search.locs = e.search.locs[:0]
+	e.search.current = -1
+}
+
+// Matches returns the matches of the search started by Find, found again if
+// the text changed, and the index of the current match, or -1.
+func (e *textView) Matches() ([]Match, int) {
+	e.refreshMatches()
+	if len(e.search.matches) == 0 {
+		return nil, -1
+	}
+	return e.search.matches, e.search.current
+}
+
+// matchAt returns the index of the first match starting at or after the
+// rune offset r, or the number of matches.
+func (e *textView) matchAt(r int) int {
+	ms := e.search.matches
+	return sort.Search(len(ms), func(i int) bool { return ms[i].Start >= r })
+}
+
+// visibleMatch returns the index of the first match ending after the rune
+// offset r, or the number of matches.
+func (e *textView) visibleMatch(r int) int {
+	ms := e.search.matches
+	return sort.Search(len(ms), func(i int) bool { return ms[i].End > r })
+}
+
+// adjustMatches adjusts the matches to the replacement of the runes
+// [start, end) of the text with the runes [start, newEnd), and marks the
+// replacement for searching again.
+func (e *textView) adjustMatches(start, end, newEnd int) {
+	s := &e.search
+	s.pos = adjustPos(s.pos, start, end, newEnd)
+	if s.re == nil || s.stale {
+		return
+	}
+	if !s.local {
+		s.stale = true
+		return
+	}
+	// Drop the matches deleted by the replacement.
+	i := e.visibleMatch(start)
+	matches, locs := s.matches[:i], s.locs[:i]
+	for ; i < len(s.matches); i++ {
+		m := s.matches[i]
+		m.Start = adjustPos(m.Start, start, end, newEnd)
+		m.End = adjustPos(m.End, start, end, newEnd)
+		if m.Start < m.End {
+			matches = append(matches, m)
+			locs = append(locs, s.locs[i])
+		}
+	}
+	s.matches, s.locs = matches, locs
+	s.dirty.add(start, end, newEnd)
+}
+
+// setCurrentMatch makes the first match starting at or after the rune
+// offset r current, wrapping around to the first match.
+func (e *textView) setCurrentMatch(r int) {
+	s := &e.search
+	s.pos = r
+	if len(s.matches) == 0 {
+		s.current = -1
+		return
+	}
+	s.current = e.matchAt(r) % len(s.matches)
+	s.pos = s.matches[s.current].Start
+}
+
+// refreshMatches finds the matches again if the text changed since they
+// were found.
+func (e *textView) refreshMatches() {
+	s := &e.search
+	if s.re == nil {
+		return
+	}
+	switch {
+	case s.stale:
+		s.buf = e.Text(s.buf)
+		s.matches, s.locs = e.findMatches(s.matches[:0], s.locs[:0], 0)
+	case s.dirty.pending:
+		// Search again the paragraphs changed since the matches were found.
+		start, end, startOff, endOff := e.paragraphRange(s.dirty.start, s.dirty.end)
+		size := int(endOff - startOff)
+		if cap(s.buf) < size {
+			s.buf = make([]byte, size)
+		}
+		n, _ := e.ReadAt(s.buf[:size], startOff)
+		s.buf = s.buf[:n]
+		matches, locs := e.findMatches(nil, nil, start)
+		i, j := e.visibleMatch(start), e.matchAt(end)
+		s.matches = slices.Replace(s.matches, i, max(i, j), matches...)
+		s.locs = slices.Replace(s.locs, i, max(i, j), locs...)
+	default:
+		return
+	}
+	s.stale = false
+	s.dirty.pending = false
+	e.setCurrentMatch(s.pos)
+}
+
+// findMatches appends the matches in the text of the search buffer, which
+// starts at the rune offset base, to matches and locs.
+func (e *textView) findMatches(matches []Match, locs [][]int, base int) ([]Match, [][]int) {
+	s := &e.search
+	runes, off := base, 0
+	for _, loc := range s.re.FindAllSubmatchIndex(s.buf, -1) {
+		start, end := loc[0], loc[1]
+		if start == end || s.query.WholeWord && !isWholeWord(s.buf, start, end) {
+			continue
+		}
+		runes += utf8.RuneCount(s.buf[off:start])
+		m := Match{Start: runes}
+		runes += utf8.RuneCount(s.buf[start:end])
+		m.End = runes
+		off = end
+		for i := range loc {
+			if loc[i] != -1 {
+				loc[i] -= start
+			}
+		}
+		matches = append(matches, m)
+		locs = append(locs, loc)
+	}
+	return matches, locs
+}
+
+// isWholeWord reports whether the bytes [start, end) of txt are neither
+// preceded nor followed by a word character.
+func isWholeWord(txt []byte, start, end int) bool {
+	isWord := func(r rune) bool {
+		return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	if r, _ := utf8.DecodeLastRune(txt[:start]); start > 0 && isWord(r) {
+		return false
+	}
+	if r, _ := utf8.DecodeRune(txt[end:]); end < len(txt) && isWord(r) {
+		return false
+	}
+	return true
+}
+
+// FindNext makes the match after the current match current, wrapping
+// around to the first match, and selects it. If backward is set, the match
+// before the current match is made current instead. If the current match
+// isn't selected, it is selected without moving to another match. FindNext
+// reports whether there is a match.
+func (e *textView) FindNext(backward bool) bool {
+	e.refreshMatches()
+	s := &e.search
+	n := len(s.matches)
+	if n == 0 {
+		return false
+	}
+	m := s.matches[s.current]
+	start, end := e.Selection()
+	if m.Start == min(start, end) && m.End == max(start, end) {
+		if backward {
+			s.current = (s.current + n - 1) % n
+		} else {
+			s.current = (s.current + 1) % n
+		}
+		m = s.matches[s.current]
+		s.pos = m.Start
+	}
+	e.ClearCarets()
+	e.SetCaret(m.End, m.Start)
+	return true
+}
+
+// replacement returns the replacement of the match at index i with repl.
+// If the search is a regular expression, the submatches referenced by repl
+// are expanded as described by [regexp.Regexp.Expand].
+func (e *textView) replacement(i int, repl string) string {
+	s := &e.search
+	if !s.query.Regexp {
+		return repl
+	}
+	m := s.matches[i]
+	start := e.ByteOffset(m.Start)
+	src := make([]byte, e.ByteOffset(m.End)-start)
+	e.ReadAt(src, start)
+	return string(s.re.Expand(nil, []byte(repl), src, s.locs[i]))
+}
+
+// MatchRegions returns the visible regions covering the matches, in the
+// form returned by Regions.
+func (e *textView) MatchRegions(regions []Region) []Region {
+	e.refreshMatches()
+	e.makeValid()
+	start, end := e.visibleRunes()
+	var scratch []Region
+	regions = regions[:0]
+	for _, m := range e.search.matches[e.visibleMatch(start):] {
+		if m.Start >= end {
+			break
+		}
+		scratch = e.Regions(m.Start, m.End, scratch)
+		regions = append(regions, scratch...)
+	}
+	return regions
+}
+
+// PaintMatches paints the backgrounds of the visible matches with the
+// Highlight material of the search.
+func (e *textView) PaintMatches(gtx layout.Context) {
+	material := e.search.query.Highlight
+	if material == (op.CallOp{}) {
+		return
+	}
+	e.refreshMatches()
+	e.makeValid()
+	localViewport := image.Rectangle{Max: e.viewSize}
+	docViewport := image.Rectangle{Max: e.viewSize}.Add(e.scrollOff)
+	defer clip.Rect(localViewport).Push(gtx.Ops).Pop()
+	start, end := e.visibleRunes()
+	for _, m := range e.search.matches[e.visibleMatch(start):] {
+		if m.Start >= end {
+			break
+		}
+		e.regions = e.index.locate(docViewport, m.Start, m.End, e.regions)
+		for _, region := range e.regions {
+			area := clip.Rect(region.Bounds).Push(gtx.Ops)
+			material.Add(gtx.Ops)
+			paint.PaintOp{}.Add(gtx.Ops)
+			area.Pop()
+		}
+	}
+}
+
+// Find searches the text of the editor for s, and returns the matches. The
+// matches are found again when the text changes, and are highlighted with
+// s.Highlight. The current match is the first match starting at or after
+// the caret.
+func (e *Editor) Find(s Search) ([]Match, error) {
+	e.initBuffer()
+	return e.text.Find(s)
+}
+
+// Matches returns the matches of the search started by Find, and the index
+// of the current match, or -1 if there are no matches.
+func (e *Editor) Matches() ([]Match, int) {
+	e.initBuffer()
+	return e.text.Matches()
+}
+
+// FindNext selects the current match, or the match after it if it is
+// already selected, and scrolls it into view. It reports whether there is a
+// match.
+func (e *Editor) FindNext() bool {
+	return e.findNext(false)
+}
+
+// FindPrevious is like FindNext, but selects the match before the current
+// match.
+func (e *Editor) FindPrevious() bool {
+	return e.findNext(true)
+}
+
+func (e *Editor) findNext(backward bool) bool {
+	e.initBuffer()
+	if !e.text.FindNext(backward) {
+		return false
+	}
+	e.scrollCaret = true
+	e.scroller.Stop()
+	return true
+}
+
+// ClearMatches ends the search started by Find.
+func (e *Editor) ClearMatches() {
+	e.initBuffer()
+	e.text.ClearMatches()
+}
+
+// MatchRegions returns the visible regions covering the matches, in the
+// form returned by Regions.
+func (e *Editor) MatchRegions(regions []Region) []Region {
+	e.initBuffer()
+	return e.text.MatchRegions(regions)
+}
+
+// ReplaceMatch replaces the current match with repl, and selects the next
+// match. If the search is a regular expression, repl may refer to
+// submatches as described by [regexp.Regexp.Expand]. ReplaceMatch reports
+// whether there was a match to replace.
+func (e *Editor) ReplaceMatch(repl string) bool {
+	e.initBuffer()
+	matches, i := e.text.Matches()
+	if i == -1 {
+		return false
+	}
+	m := matches[i]
+	e.BeginGroup()
+	n := e.replace(m.Start, m.End, e.replacement(i, repl), true)
+	e.text.ClearCarets()
+	e.setCaret(m.Start+n, m.Start+n)
+	e.EndGroup()
+	// Continue after the replacement, so that it isn't matched again.
+	e.text.search.pos, _ = e.text.Selection()
+	e.findNext(false)
+	return true
+}
+
+// ReplaceAll replaces every match with repl, as by ReplaceMatch, in a single
+// step of the undo history. It returns the number of matches replaced.
+func (e *Editor) ReplaceAll(repl string) int {
+	e.initBuffer()
+	matches, _ := e.text.Matches()
+	repls := make([]string, len(matches))
+	for i := range matches {
+		repls[i] = e.replacement(i, repl)
+	}
+	matches = slices.Clone(matches)
+	e.BeginGroup()
+	// Replace from the end, so that the positions of the remaining matches
+	// are unchanged.
+	for i := len(matches) - 1; i >= 0; i-- {
+		m := matches[i]
+		e.replace(m.Start, m.End, repls[i], true)
+	}
+	e.EndGroup()
+	return len(matches)
+}
+
+// replacement is like textView.replacement, but replaces newlines if the
+// editor is single line.
+func (e *Editor) replacement(i int, repl string) string {
+	s := e.text.replacement(i, repl)
+	if e.SingleLine {
+		s = strings.ReplaceAll(s, "\n", " ")
+	}
+	return s
+}
+
+// paintMatches paints the backgrounds of the matches.
+func (e *Editor) paintMatches(gtx layout.Context) {
+	e.initBuffer()
+	e.text.PaintMatches(gtx)
+}
+
+// Find searches the text of the label for s, and returns the matches. The
+// current match is the first match starting at or after the caret.
+func (l *Selectable) Find(s Search) ([]Match, error) {
+	l.initialize()
+	return l.text.Find(s)
+}
+
+// Matches returns the matches of the search started by Find, and the index
+// of the current match, or -1 if there are no matches.
+func (l *Selectable) Matches() ([]Match, int) {
+	l.initialize()
+	return l.text.Matches()
+}
+
+// FindNext selects the current match, or the match after it if it is
+// already selected. It reports whether there is a match.
+func (l *Selectable) FindNext() bool {
+	l.initialize()
+	return l.text.FindNext(false)
+}
+
+// FindPrevious is like FindNext, but selects the match before the current
+// match.
+func (l *Selectable) FindPrevious() bool {
+	l.initialize()
+	return l.text.FindNext(true)
+}
+
+// ClearMatches ends the search started by Find.
+func (l *Selectable) ClearMatches() {
+	l.initialize()
+	l.text.ClearMatches()
+}
+
+// MatchRegions returns the visible regions covering the matches, in the
+// form returned by Regions.
+func (l *Selectable) MatchRegions(regions []Region) []Region {
+	l.initialize()
+	return l.text.MatchRegions(regions)
+}
@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"fmt"
+	"image"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gioui.org/font"
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+)
+
+func TestEditorFind(t *testing.T) {
+	e := new(Editor)
+	e.SetText("Go go gopher, GO! ågo go_")
+	tests := []struct {
+		search Search
+		want   []Match
+	}{
+		{Search{Pattern: "go"}, []Match{{3, 5}, {6, 8}, {19, 21}, {22, 24}}},
+		{Search{Pattern: "go", IgnoreCase: true}, []Match{{0, 2}, {3, 5}, {6, 8}, {14, 16}, {19, 21}, {22, 24}}},
+		{Search{Pattern: "go", IgnoreCase: true, WholeWord: true}, []Match{{0, 2}, {3, 5}, {14, 16}}},
+		{Search{Pattern: `g\w+r`, Regexp: true}, []Match{{6, 12}}},
+		{Search{Pattern: "x*", Regexp: true}, nil},
+	}
+	for _, test := range tests {
+		got, err := e.Find(test.search)
+		if err != nil {
+			t.Fatalf("%+v: %v", test.search, err)
+		}
+		if len(got) == 0 {
+			got = nil
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%+v: got matches %v, want %v", test.search, got, test.want)
+		}
+	}
+	if _, err := e.Find(Search{Pattern: "(", Regexp: true}); err == nil {
+		t.Error("invalid pattern compiled")
+	}
+}
+
+func TestEditorFindNext(t *testing.T) {
+	e := new(Editor)
+	e.SetText("one two one three one")
+	e.SetCaret(5, 5)
+	e.Find(Search{Pattern: "one"})
+	if _, i := e.Matches(); i != 1 {
+		t.Fatalf("got current match %d, want 1", i)
+	}
+	e.FindNext()
+	assertContents(t, e, "one two one three one", 11, 8)
+	e.FindNext()
+	assertContents(t, e, "one two one three one", 21, 18)
+	e.FindNext()
+	assertContents(t, e, "one two one three one", 3, 0)
+	e.FindPrevious()
+	assertContents(t, e, "one two one three one", 21, 18)
+	// Edits keep the current match.
+	e.SetCaret(0, 0)
+	e.Insert("zero ")
+	if ms, i := e.Matches(); i != 2 || ms[i] != (Match{23, 26}) {
+		t.Errorf("got current match %d of %v, want 2", i, ms)
+	}
+	e.ClearMatches()
+	if e.FindNext() {
+		t.Error("found a match after ClearMatches")
+	}
+}
+
+func TestEditorFindEdits(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&b, "go %d gopher\n", i)
+	}
+	for _, search := range []Search{
+		{Pattern: "go"},
+		{Pattern: `\d+ go`, Regexp: true},
+		// Matches spanning paragraphs are found again in the whole text.
+		{Pattern: `r\ngo`, Regexp: true},
+	} {
+		e := new(Editor)
+		e.SetText(b.String())
+		e.Find(search)
+		e.SetCaret(len("go 0 gopher\ngo 1 "), len("go 0 gopher\ngo 1 go"))
+		e.Insert("go go")
+		e.SetCaret(len("go 0 gopher"), len("go 0 gopher\n"))
+		e.Insert(" ")
+		got, _ := e.Matches()
+		if search.Pattern == "go" && len(e.text.search.buf) >= e.Len() {
+			t.Errorf("%+v: searched %d bytes after an edit, want only the edited paragraphs", search, len(e.text.search.buf))
+		}
+		want := new(Editor)
+		want.SetText(e.Text())
+		wantMatches, _ := want.Find(search)
+		if !reflect.DeepEqual(got, wantMatches) {
+			t.Errorf("%+v: got matches %v after edits, want %v", search, got, wantMatches)
+		}
+	}
+}
+
+func TestEditorFindRandomEdits(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	pieces := []string{"foo", "fo", "o", " ", "\n", "foo\nfoo"}
+	e := new(Editor)
+	e.SetText(strings.Repeat("foo bar\n", 50))
+	search := Search{Pattern: "foo"}
+	e.Find(search)
+	for i := 0; i < 500; i++ {
+		start := rnd.Intn(e.Len() + 1)
+		end := min(start+rnd.Intn(8), e.Len())
+		e.SetCaret(start, end)
+		e.Insert(pieces[rnd.Intn(len(pieces))])
+		got, _ := e.Matches()
+		fresh := new(Editor)
+		fresh.SetText(e.Text())
+		want, _ := fresh.Find(search)
+		if len(got) != 0 || len(want) != 0 {
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("edit %d: got matches %v, want %v", i, got, want)
+			}
+		}
+	}
+}
+
+func TestEditorReplace(t *testing.T) {
+	e := new(Editor)
+	e.SetText("a-1 b-2 c-3")
+	e.Find(Search{Pattern: `(\w)-(\d)`, Regexp: true})
+	if !e.ReplaceMatch("$2$1$1") {
+		t.Fatal("no match replaced")
+	}
+	assertContents(t, e, "1aa b-2 c-3", 7, 4)
+	if n := e.ReplaceAll("${2}x"); n != 2 {
+		t.Errorf("replaced %d matches, want 2", n)
+	}
+	assertContents(t, e, "1aa 2x 3x", 6, 4)
+	if _, i := e.Matches(); i != -1 {
+		t.Errorf("got current match %d after replacing every match", i)
+	}
+	// Replacing every match is a single step of the undo history.
+	e.Undo()
+	assertContents(t, e, "1aa b-2 c-3", 11, 8)
+	e.Undo()
+	assertContents(t, e, "a-1 b-2 c-3", 3, 0)
+	// The search continues after the replacement.
+	e.SetText("aa")
+	e.SetCaret(0, 0)
+	e.Find(Search{Pattern: "a"})
+	e.ReplaceMatch("ba")
+	assertContents(t, e, "baa", 3, 2)
+	e.ReplaceMatch("ba")
+	assertContents(t, e, "baba", 2, 1)
+}
+
+func TestSelectableFind(t *testing.T) {
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(200, 200)),
+		Locale:      english,
+	}
+	shaper := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	l := new(Selectable)
+	l.SetText("one two\none")
+	ms, err := l.Find(Search{Pattern: "one"})
+	if err != nil || len(ms) != 2 {
+		t.Fatalf("got matches %v, %v", ms, err)
+	}
+	l.Layout(gtx, shaper, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	if !l.FindNext() || l.SelectedText() != "one" {
+		t.Fatal("match not selected")
+	}
+	if start, end := l.Selection(); start != 3 || end != 0 {
+		t.Errorf("got selection (%d, %d), want (3, 0)", start, end)
+	}
+	regions := l.MatchRegions(nil)
+	if len(regions) != 2 || regions[0].Bounds.Min.Y >= regions[1].Bounds.Min.Y {
+		t.Errorf("got match regions %v", regions)
+	}
+	l.SetText("two")
+	if ms, i := l.Matches(); len(ms) != 0 || i != -1 {
+		t.Errorf("got matches %v in new text", ms)
+	}
+}
@@ -20,13 +20,13 @@ import (
 	"gioui.org/unit"
 )
 
-// stringSource is an immutable textSource with a fixed string
+// stringSource is an immutable TextSource with a fixed string
 // value.
 type stringSource struct {
 	reader *strings.Reader
 }
 
-var _ textSource = stringSource{}
+var _ TextSource = stringSource{}
 
 func newStringSource(str string) stringSource {
 	return stringSource{
@@ -61,6 +61,22 @@ type Selectable struct {
 	Truncator string
 	// WrapPolicy configures how displayed text will be broken into lines.
 	WrapPolicy text.WrapPolicy
+	// Decoration draws lines along the text, such as underlines.
+	Decoration text.Decoration
+	// SkipInk interrupts underlines where glyphs descend through them.
+	SkipInk bool
+	// Features is a list of OpenType features to apply when shaping, in the
+	// format of text.Parameters.Features.
+	Features string
+	// Variations sets the variation axes of variable fonts, in the format
+	// of text.Parameters.Variations.
+	Variations string
+	// TabStops are the positions that text following tab characters is
+	// aligned to, as described by text.Parameters.TabStops.
+	TabStops []TabStop
+	// TabWidth is the distance between the tab stops following TabStops.
+	// If zero and TabStops is empty, tab characters are not aligned.
+	TabWidth unit.Sp
 	// LineHeight controls the distance between the baselines of lines of text.
 	// If zero, a sensible default will be used.
 	LineHeight unit.Sp
@@ -180,8 +196,20 @@ func (l *Selectable) Update(gtx layout.Context) bool {
 
 // Layout clips to the dimensions of the selectable, updates the shaped text, configures input handling, and paints
 // the text and selection rectangles. The provided textMaterial and selectionMaterial ops are used to set the
-// paint material for the text and selection rectangles, respectively.
+// paint material for the text and selection rectangles, respectively. If the locale of gtx has a vertical text
+// direction, the text is laid out in columns.
 func (l *Selectable) Layout(gtx layout.Context, lt *text.Shaper, font font.Font, size unit.Sp, textMaterial, selectionMaterial op.CallOp) layout.Dimensions {
+	if verticalText(gtx) {
+		return layoutVertical(gtx, func(gtx layout.Context) layout.Dimensions {
+			return l.layout(gtx, lt, font, size, textMaterial, selectionMaterial)
+		})
+	}
+	return l.layout(gtx, lt, font, size, textMaterial, selectionMaterial)
+}
+
+// layout is like Layout, but lays out vertical text as if it were
+// horizontal.
+func (l *Selectable) layout(gtx layout.Context, lt *text.Shaper, font font.Font, size unit.Sp, textMaterial, selectionMaterial op.CallOp) layout.Dimensions {
 	l.Update(gtx)
 	l.text.LineHeight = l.LineHeight
 	l.text.LineHeightScale = l.LineHeightScale
@@ -189,6 +217,12 @@ func (l *Selectable) Layout(gtx layout.Context, lt *text.Shaper, font font.Font,
 	l.text.MaxLines = l.MaxLines
 	l.text.Truncator = l.Truncator
 	l.text.WrapPolicy = l.WrapPolicy
+	l.text.Decoration = l.Decoration
+	l.text.SkipInk = l.SkipInk
+	l.text.Features = l.Features
+	l.text.Variations = l.Variations
+	l.text.TabStops = l.TabStops
+	l.text.TabWidth = l.TabWidth
 	l.text.Layout(gtx, lt, font, size)
 	dims := l.text.Dimensions()
 	defer clip.Rect(image.Rectangle{Max: dims.Size}).Push(gtx.Ops).Pop()
@@ -198,6 +232,7 @@ func (l *Selectable) Layout(gtx layout.Context, lt *text.Shaper, font font.Font,
 	l.clicker.Add(gtx.Ops)
 	l.dragger.Add(gtx.Ops)
 
+	l.text.PaintMatches(gtx)
 	l.paintSelection(gtx, selectionMaterial)
 	l.paintText(gtx, textMaterial)
 	return dims
@@ -350,7 +385,7 @@ func (e *Selectable) command(gtx layout.Context, k key.Event) {
 		}
 		return
 	}
-	switch k.Name {
+	switch verticalKey(gtx, k.Name) {
 	case key.NameUpArrow:
 		e.text.MoveLines(-1, selAct)
 	case key.NameDownArrow:
@@ -9,6 +9,7 @@ import (
 	"gioui.org/font/gofont"
 	"gioui.org/io/input"
 	"gioui.org/io/key"
+	"gioui.org/io/system"
 	"gioui.org/layout"
 	"gioui.org/op"
 	"gioui.org/text"
@@ -119,3 +120,41 @@ func TestSelectableConfigurations(t *testing.T) {
 		}
 	}
 }
+
+func TestSelectableVertical(t *testing.T) {
+	gtx := layout.Context{
+		Ops: new(op.Ops),
+		Constraints: layout.Constraints{
+			Max: image.Pt(300, 50),
+		},
+		Locale: system.Locale{Language: "ja", Direction: system.TTB},
+	}
+	cache := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	s := new(Selectable)
+	s.SetText("漢字漢字漢字漢字")
+	dims := s.Layout(gtx, cache, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	if dims.Size.Y > 50 || dims.Size.X >= dims.Size.Y {
+		t.Fatalf("got size %v, want columns at most 50 tall", dims.Size)
+	}
+	first := s.Regions(0, 1, nil)
+	second := s.Regions(1, 2, nil)
+	last := s.Regions(7, 8, nil)
+	if len(first) != 1 || len(second) != 1 || len(last) != 1 {
+		t.Fatalf("got regions %v %v %v, want one each", first, second, last)
+	}
+	// The first column is on the right, and flows downwards.
+	if got := first[0].Bounds; got.Min.Y != 0 || got.Max.X != dims.Size.X {
+		t.Errorf("first rune at %v, want top right of %v", got, dims.Size)
+	}
+	if a, b := first[0].Bounds, second[0].Bounds; b.Min.Y < a.Max.Y || b.Min.X != a.Min.X {
+		t.Errorf("second rune at %v, want below %v", b, a)
+	}
+	if a, b := first[0].Bounds, last[0].Bounds; b.Max.X > a.Min.X {
+		t.Errorf("last rune at %v, want left of %v", b, a)
+	}
+	s.SetCaret(1, 1)
+	caret := s.text.CaretCoords()
+	if b := second[0].Bounds; caret.Y < float32(b.Min.Y-1) || caret.Y > float32(b.Min.Y+1) || caret.X < float32(b.Min.X) || caret.X > float32(b.Max.X) {
+		t.Errorf("caret at %v, want top of %v", caret, b)
+	}
+}
@@ -0,0 +1,210 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"sort"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"golang.org/x/exp/slices"
+)
+
+// TextStyle styles a range of the text of an Editor, such as a token
+// colored by a syntax highlighter.
+type TextStyle struct {
+	// Start and End are the rune offsets of the start and end of the range.
+	Start, End int
+	// Material paints the glyphs of the range in place of the text
+	// material of the Editor, if set.
+	Material op.CallOp
+	// Background paints the background of the range, if set.
+	Background op.CallOp
+	// Weight and Style replace the weight and style of the font of the
+	// range, if not zero.
+	Weight font.Weight
+	Style  font.Style
+}
+
+// shapes reports whether the style affects the layout of the text.
+func (s TextStyle) shapes() bool {
+	return s.Weight != 0 || s.Style != 0
+}
+
+// SetStyles replaces the styled ranges of the text. The ranges are sorted by
+// their start, and clipped such that they don't overlap.
+func (e *textView) SetStyles(styles []TextStyle) {
+	old := e.shapingStyles(nil)
+	e.styles = append(e.styles[:0], styles...)
+	slices.SortStableFunc(e.styles, func(a, b TextStyle) int {
+		return a.Start - b.Start
+	})
+	clipped := e.styles[:0]
+	end := 0
+	for _, s := range e.styles {
+		s.Start = max(s.Start, end)
+		if s.Start >= s.End {
+			continue
+		}
+		end = s.End
+		clipped = append(clipped, s)
+	}
+	e.styles = clipped
+	// Lay out again only the paragraphs whose shaping styles changed.
+	styles = e.shapingStyles(nil)
+	i := 0
+	for i < len(old) && i < len(styles) && old[i] == styles[i] {
+		i++
+	}
+	j, k := len(old), len(styles)
+	for j > i && k > i && old[j-1] == styles[k-1] {
+		j--
+		k--
+	}
+	if i == j && i == k {
+		return
+	}
+	start, end := e.Len(), 0
+	for _, s := range append(old[i:j], styles[i:k]...) {
+		start, end = min(start, s.Start), max(end, s.End)
+	}
+	startOff, endOff := e.ByteOffset(start), e.ByteOffset(end)
+	e.invalidateRange(startOff, endOff, endOff)
+}
+
+// shapingStyles appends to styles the styled ranges that affect the layout
+// of the text, without their materials, and returns the result.
+func (e *textView) shapingStyles(styles []TextStyle) []TextStyle {
+	for _, s := range e.styles {
+		if s.shapes() {
+			styles = append(styles, TextStyle{Start: s.Start, End: s.End, Weight: s.Weight, Style: s.Style})
+		}
+	}
+	return styles
+}
+
+// Styles returns the styled ranges of the text, adjusted for the edits since
+// they were set.
+func (e *textView) Styles() []TextStyle {
+	return slices.Clone(e.styles)
+}
+
+// stylesShape reports whether any style affects the layout of the text.
+func (e *textView) stylesShape() bool {
+	for _, s := range e.styles {
+		if s.shapes() {
+			return true
+		}
+	}
+	return false
+}
+
+// adjustStyles adjusts the styled ranges to the replacement of the runes
+// [start, end) of the text with the runes [start, newEnd).
+func (e *textView) adjustStyles(start, end, newEnd int) {
+	styles := e.styles[:0]
+	for _, s := range e.styles {
+		s.Start = adjustPos(s.Start, start, end, newEnd)
+		s.End = adjustPos(s.End, start, end, newEnd)
+		if s.Start < s.End {
+			styles = append(styles, s)
+		}
+	}
+	e.styles = styles
+}
+
+// styleAt returns the index of the first style ending after the rune at
+// offset r, or len(e.styles).
+func (e *textView) styleAt(r int) int {
+	return sort.Search(len(e.styles), func(i int) bool {
+		return e.styles[i].End > r
+	})
+}
+
+// styleSpans appends to spans the spans for laying out the n runes of text
+// starting at rune offset start, and returns the result. It returns spans
+// unchanged if no style affects the layout of the runes.
+func (e *textView) styleSpans(spans []text.Span, start, n int) []text.Span {
+	end := start + n
+	pos := start
+	first := len(spans)
+	for _, s := range e.styles[e.styleAt(start):] {
+		if s.Start >= end {
+			break
+		}
+		if !s.shapes() {
+			continue
+		}
+		if s.Start > pos {
+			spans = append(spans, text.Span{Runes: s.Start - pos, Font: e.params.Font})
+			pos = s.Start
+		}
+		f := e.params.Font
+		if s.Weight != 0 {
+			f.Weight = s.Weight
+		}
+		if s.Style != 0 {
+			f.Style = s.Style
+		}
+		spanEnd := min(s.End, end)
+		spans = append(spans, text.Span{Runes: spanEnd - pos, Font: f})
+		pos = spanEnd
+	}
+	if len(spans) > first && pos < end {
+		spans = append(spans, text.Span{Runes: end - pos, Font: e.params.Font})
+	}
+	return spans
+}
+
+// PaintBackgrounds paints the backgrounds of the styled ranges of the text.
+func (e *textView) PaintBackgrounds(gtx layout.Context) {
+	e.makeValid()
+	localViewport := image.Rectangle{Max: e.viewSize}
+	docViewport := image.Rectangle{Max: e.viewSize}.Add(e.scrollOff)
+	defer clip.Rect(localViewport).Push(gtx.Ops).Pop()
+	start, end := e.visibleRunes()
+	for _, s := range e.styles[e.styleAt(start):] {
+		if s.Start >= end {
+			break
+		}
+		if s.Background == (op.CallOp{}) {
+			continue
+		}
+		e.regions = e.index.locate(docViewport, s.Start, s.End, e.regions)
+		for _, region := range e.regions {
+			area := clip.Rect(region.Bounds).Push(gtx.Ops)
+			s.Background.Add(gtx.Ops)
+			paint.PaintOp{}.Add(gtx.Ops)
+			area.Pop()
+		}
+	}
+}
+
+// visibleRunes returns the range of runes of the indexed lines that
+// intersect the viewport.
+func (e *textView) visibleRunes() (start, end int) {
+	start = e.index.firstRune
+	glyphs := e.index.glyphs
+	for _, line := range e.index.lines {
+		if line.descent.Ceil()+line.yOff >= e.scrollOff.Y {
+			break
+		}
+		for _, g := range glyphs[:line.glyphs] {
+			start += int(g.Runes)
+		}
+		glyphs = glyphs[line.glyphs:]
+	}
+	end = start
+	for _, g := range glyphs {
+		if int(g.Y)-g.Ascent.Ceil() > e.scrollOff.Y+e.viewSize.Y {
+			break
+		}
+		end += int(g.Runes)
+	}
+	return start, end
+}
@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gioui.org/font"
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+)
+
+func TestEditorStyles(t *testing.T) {
+	e := new(Editor)
+	e.SetText("select name from users")
+	e.SetStyles([]TextStyle{
+		{Start: 17, End: 22},
+		{Start: 0, End: 6, Weight: font.Bold},
+		{Start: 4, End: 9},
+		{Start: 12, End: 16, Style: font.Italic},
+	})
+	want := []TextStyle{
+		{Start: 0, End: 6, Weight: font.Bold},
+		{Start: 6, End: 9},
+		{Start: 12, End: 16, Style: font.Italic},
+		{Start: 17, End: 22},
+	}
+	if got := e.Styles(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got styles %+v, want %+v", got, want)
+	}
+	// Insert at the start of a range, within a range and at the end of a
+	// range.
+	e.SetCaret(12, 12)
+	e.Insert("x")
+	e.SetCaret(2, 2)
+	e.Insert("yy")
+	e.SetCaret(8, 8)
+	e.Insert("z")
+	want = []TextStyle{
+		{Start: 0, End: 8, Weight: font.Bold},
+		{Start: 8, End: 12},
+		{Start: 15, End: 20, Style: font.Italic},
+		{Start: 21, End: 26},
+	}
+	if got := e.Styles(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got styles %+v after insertions, want %+v", got, want)
+	}
+	// Delete the end of a range, a range and the start of a range.
+	e.SetCaret(7, 23)
+	e.Delete(1)
+	want = []TextStyle{
+		{Start: 0, End: 7, Weight: font.Bold},
+		{Start: 7, End: 10},
+	}
+	if got, text := e.Styles(), e.Text(); !reflect.DeepEqual(got, want) || text != "seyylecers" {
+		t.Fatalf("got styles %+v of %q after deletion, want %+v of %q", got, text, want, "seyylecers")
+	}
+	e.SetText("")
+	if got := e.Styles(); len(got) != 0 {
+		t.Errorf("got styles %+v of empty text", got)
+	}
+}
+
+// TestEditorStylesLayout ensures that styles replacing the font are laid out,
+// also when the text is laid out on demand.
+func TestEditorStylesLayout(t *testing.T) {
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(1000, 200)),
+		Locale:      english,
+	}
+	shaper := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	red := func() op.CallOp {
+		m := op.Record(gtx.Ops)
+		paint.ColorOp{Color: color.NRGBA{R: 0xff, A: 0xff}}.Add(gtx.Ops)
+		return m.Stop()
+	}()
+	for _, txt := range []string{
+		"wide words\nwide words\n",
+		strings.Repeat("wide words\n", virtualSize/10),
+	} {
+		e := new(Editor)
+		e.SetText(txt)
+		e.Layout(gtx, shaper, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+		e.SetCaret(4, 4)
+		plain := e.CaretCoords().X
+		e.SetStyles([]TextStyle{{Start: 0, End: 4, Weight: font.Bold, Material: red, Background: red}})
+		e.Layout(gtx, shaper, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+		if bold := e.CaretCoords().X; bold <= plain {
+			t.Errorf("virtual %v: caret after bold text at %v, want after %v", e.text.virt.enabled, bold, plain)
+		}
+		e.SetCaret(15, 15)
+		if line, col := e.CaretPos(); line != 1 || col != 4 {
+			t.Errorf("virtual %v: caret at (%d, %d), want (1, 4)", e.text.virt.enabled, line, col)
+		}
+	}
+}
+
+// TestEditorStylesReshape ensures that setting styles lays out again only
+// the paragraphs whose font changed.
+func TestEditorStylesReshape(t *testing.T) {
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(1000, 200)),
+		Locale:      english,
+	}
+	shaper := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	e := new(Editor)
+	e.SetText(strings.Repeat("wide words\n", virtualSize/10))
+	e.SetStyles([]TextStyle{{Start: 0, End: 4, Weight: font.Bold}})
+	e.Layout(gtx, shaper, font.Font{}, unit.Sp(10), op.CallOp{}, op.CallOp{})
+	if !e.text.virt.enabled {
+		t.Fatal("large text not laid out on demand")
+	}
+	shaped := func() []bool {
+		var s []bool
+		for _, p := range e.text.virt.paragraphs[:5] {
+			s = append(s, p.shaped)
+		}
+		return s
+	}
+	red := op.Record(gtx.Ops).Stop()
+	e.SetStyles([]TextStyle{{Start: 0, End: 4, Weight: font.Bold, Material: red}})
+	if got, want := shaped(), []bool{true, true, true, true, true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got shaped paragraphs %v after changing the material, want %v", got, want)
+	}
+	e.SetStyles([]TextStyle{{Start: 0, End: 4, Weight: font.Bold}, {Start: 26, End: 30, Style: font.Italic}})
+	if got, want := shaped(), []bool{true, true, false, true, true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got shaped paragraphs %v after adding a style, want %v", got, want)
+	}
+}
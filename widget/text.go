@@ -6,11 +6,13 @@ import (
 	"io"
 	"math"
 	"sort"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 
 	"gioui.org/f32"
 	"gioui.org/font"
+	"gioui.org/io/system"
 	"gioui.org/layout"
 	"gioui.org/op"
 	"gioui.org/op/clip"
@@ -21,11 +23,12 @@ import (
 	"golang.org/x/image/math/fixed"
 )
 
-// textSource provides text data for use in widgets. If the underlying data type
+// TextSource provides text data for use in widgets, such as the document
+// model of an application displayed by an [Editor]. If the underlying data type
 // can fail due to I/O errors, it is the responsibility of that type to provide
 // its own mechanism to surface and handle those errors. They will not always
 // be returned by widgets using these functions.
-type textSource interface {
+type TextSource interface {
 	io.ReaderAt
 	// Size returns the total length of the data in bytes.
 	Size() int64
@@ -38,6 +41,50 @@ type textSource interface {
 	ReplaceRunes(byteOffset int64, runeCount int64, replacement string)
 }
 
+// ChangeReporter is implemented by a TextSource whose contents may change
+// other than through its ReplaceRunes method, such as a document edited
+// collaboratively or a file modified by another program. Widgets displaying
+// the source adjust their caret and selection to the reported changes, and
+// lay out again only the text affected by them when the text is large.
+type ChangeReporter interface {
+	// Changes returns the changes made to the contents other than by
+	// ReplaceRunes since the last call to Changes, in the order they were
+	// made. Changed must also report such changes.
+	//
+	// An Editor adjusts its undo history to the changes, and discards the
+	// steps of the history that overlap them.
+	Changes() []TextChange
+}
+
+// TextChange describes the replacement of a range of the contents of a
+// TextSource. The offsets are relative to the contents before the change,
+// after the changes preceding it.
+type TextChange struct {
+	// Start and End are the byte offsets of the replaced text.
+	Start, End int64
+	// StartRune is the rune offset of Start.
+	StartRune int
+	// Runes is the number of runes in the replaced text.
+	Runes int
+	// Size is the length in bytes of the replacement text, and NewRunes its
+	// number of runes.
+	Size     int64
+	NewRunes int
+}
+
+// textCaret is a caret and the selection it extends.
+type textCaret struct {
+	// xoff is the offset to the current position when moving between lines.
+	xoff fixed.Int26_6
+	// start is the current caret position in runes, and also the start position of
+	// selected text. end is the end position of selected text. If start
+	// == end, then there's no selection. Note that it's possible (and
+	// common) that the caret (start) is after the end, e.g. after
+	// Shift-DownArrow.
+	start int
+	end   int
+}
+
 // textView provides efficient shaping and indexing of interactive text. When provided
 // with a TextSource, textView will shape and cache the runes within that source.
 // It provides methods for configuring a viewport onto the shaped text which can
@@ -65,11 +112,27 @@ type textView struct {
 	// Newline characters are not masked. When non-zero, the unmasked contents
 	// are accessed by Len, Text, and SetText.
 	Mask rune
+	// Decoration draws lines along the text, such as underlines.
+	Decoration text.Decoration
+	// SkipInk interrupts underlines where glyphs descend through them.
+	SkipInk bool
+	// Features is a list of OpenType features to apply when shaping, in the
+	// format of text.Parameters.Features.
+	Features string
+	// Variations sets the variation axes of variable fonts, in the format
+	// of text.Parameters.Variations.
+	Variations string
+	// TabStops are the positions that text following tab characters is
+	// aligned to, as described by text.Parameters.TabStops.
+	TabStops []TabStop
+	// TabWidth is the distance between the tab stops following TabStops.
+	// If zero and TabStops is empty, tab characters are not aligned.
+	TabWidth unit.Sp
 
 	params     text.Parameters
 	shaper     *text.Shaper
 	seekCursor int64
-	rr         textSource
+	rr         TextSource
 	maskReader maskReader
 	// graphemes tracks the indices of grapheme cluster boundaries within rr.
 	graphemes []int
@@ -85,18 +148,31 @@ type textView struct {
 	offIndex []offEntry
 
 	index glyphIndex
-
-	caret struct {
-		// xoff is the offset to the current position when moving between lines.
-		xoff fixed.Int26_6
-		// start is the current caret position in runes, and also the start position of
-		// selected text. end is the end position of selected text. If start
-		// == end, then there's no selection. Note that it's possible (and
-		// common) that the caret (start) is after the end, e.g. after
-		// Shift-DownArrow.
-		start int
-		end   int
+	// styles are the styled ranges of the text, sorted and not overlapping.
+	styles []TextStyle
+	// spans is the scratch memory for the spans of styled text.
+	spans []text.Span
+	// tabs are the TabWidth, TabStops and metric params.TabStops was
+	// converted from.
+	tabs struct {
+		width  unit.Sp
+		stops  []TabStop
+		metric unit.Metric
 	}
+	// virt lays out large text on demand, as described by virtualSize.
+	virt virtualText
+
+	caret textCaret
+	// carets are the secondary carets, such as added by Alt+click.
+	carets []textCaret
+	// search is the state of the search started by Find.
+	search textSearch
+	// annotations are the annotations of the text set by SetAnnotations, and
+	// checked are the annotations of a Checker, both sorted by start.
+	annotations, checked []Annotation
+	// unchecked is the range of runes changed since the text was annotated
+	// by a Checker.
+	unchecked changedRange
 
 	scrollOff image.Point
 }
@@ -119,8 +195,17 @@ func (e *textView) FullDimensions() layout.Dimensions {
 
 // SetSource initializes the underlying data source for the Text. This
 // must be done before invoking any other methods on Text.
-func (e *textView) SetSource(source textSource) {
+func (e *textView) SetSource(source TextSource) {
 	e.rr = source
+	e.virt.paragraphs = e.virt.paragraphs[:0]
+	e.styles = e.styles[:0]
+	e.carets = e.carets[:0]
+	e.search.pos = 0
+	e.search.stale = true
+	e.annotations = e.annotations[:0]
+	e.checked = e.checked[:0]
+	// The length in bytes bounds the length in runes.
+	e.unchecked = changedRange{end: int(source.Size()), pending: true}
 	e.invalidate()
 	e.seekCursor = 0
 }
@@ -152,7 +237,7 @@ func (e *textView) ReadRuneBefore(off int64) (rune, int, error) {
 }
 
 func (e *textView) makeValid() {
-	if e.valid {
+	if e.valid && (!e.virt.enabled || e.windowCovers()) {
 		return
 	}
 	e.layoutText(e.shaper)
@@ -161,18 +246,30 @@ func (e *textView) makeValid() {
 
 func (e *textView) closestToRune(runeIdx int) combinedPos {
 	e.makeValid()
-	pos, _ := e.index.closestToRune(runeIdx)
+	index := &e.index
+	if e.virt.enabled {
+		index = e.paragraphIndex(e.paragraphAtRune(runeIdx))
+	}
+	pos, _ := index.closestToRune(runeIdx)
 	return pos
 }
 
 func (e *textView) closestToLineCol(line, col int) combinedPos {
 	e.makeValid()
-	return e.index.closestToLineCol(screenPos{line: line, col: col})
+	index := &e.index
+	if e.virt.enabled {
+		index = e.paragraphIndex(e.paragraphAtLine(line))
+	}
+	return index.closestToLineCol(screenPos{line: line, col: col})
 }
 
 func (e *textView) closestToXY(x fixed.Int26_6, y int) combinedPos {
 	e.makeValid()
-	return e.index.closestToXY(x, y)
+	index := &e.index
+	if e.virt.enabled {
+		index = e.paragraphIndex(e.paragraphAtY(y))
+	}
+	return index.closestToXY(x, y)
 }
 
 func (e *textView) closestToXYGraphemes(x fixed.Int26_6, y int) combinedPos {
@@ -285,6 +382,22 @@ func (e *textView) Layout(gtx layout.Context, lt *text.Shaper, font font.Font, s
 		e.params.LineHeightScale = e.LineHeightScale
 		e.invalidate()
 	}
+	if e.Features != e.params.Features {
+		e.params.Features = e.Features
+		e.invalidate()
+	}
+	if e.Variations != e.params.Variations {
+		e.params.Variations = e.Variations
+		e.invalidate()
+	}
+	if t := &e.tabs; t.width != e.TabWidth || t.metric != gtx.Metric || !slices.Equal(t.stops, e.TabStops) {
+		t.width, t.metric = e.TabWidth, gtx.Metric
+		t.stops = append(t.stops[:0], e.TabStops...)
+		if tabs := tabStops(gtx, e.TabWidth, e.TabStops); tabs != e.params.TabStops {
+			e.params.TabStops = tabs
+			e.invalidate()
+		}
+	}
 
 	e.makeValid()
 
@@ -298,29 +411,42 @@ func (e *textView) Layout(gtx layout.Context, lt *text.Shaper, font font.Font, s
 // PaintSelection clips and paints the visible text selection rectangles using
 // the provided material to fill the rectangles.
 func (e *textView) PaintSelection(gtx layout.Context, material op.CallOp) {
+	e.makeValid()
 	localViewport := image.Rectangle{Max: e.viewSize}
 	docViewport := image.Rectangle{Max: e.viewSize}.Add(e.scrollOff)
 	defer clip.Rect(localViewport).Push(gtx.Ops).Pop()
-	e.regions = e.index.locate(docViewport, e.caret.start, e.caret.end, e.regions)
-	for _, region := range e.regions {
-		area := clip.Rect(region.Bounds).Push(gtx.Ops)
-		material.Add(gtx.Ops)
-		paint.PaintOp{}.Add(gtx.Ops)
-		area.Pop()
+	for i := -1; i < len(e.carets); i++ {
+		c := e.caret
+		if i >= 0 {
+			c = e.carets[i]
+		}
+		if c.start == c.end {
+			continue
+		}
+		e.regions = e.index.locate(docViewport, c.start, c.end, e.regions)
+		for _, region := range e.regions {
+			area := clip.Rect(region.Bounds).Push(gtx.Ops)
+			material.Add(gtx.Ops)
+			paint.PaintOp{}.Add(gtx.Ops)
+			area.Pop()
+		}
 	}
 }
 
 // PaintText clips and paints the visible text glyph outlines using the provided
 // material to fill the glyphs.
 func (e *textView) PaintText(gtx layout.Context, material op.CallOp) {
+	e.makeValid()
 	m := op.Record(gtx.Ops)
 	viewport := image.Rectangle{
 		Min: e.scrollOff,
 		Max: e.viewSize.Add(e.scrollOff),
 	}
 	it := textIterator{
-		viewport: viewport,
-		material: material,
+		viewport:   viewport,
+		material:   material,
+		decoration: e.Decoration,
+		skipInk:    e.SkipInk,
 	}
 
 	startGlyph := 0
@@ -330,13 +456,35 @@ func (e *textView) PaintText(gtx layout.Context, material op.CallOp) {
 		}
 		startGlyph += line.glyphs
 	}
+	// Track the styled range of the glyphs, if any.
+	r, _ := e.visibleRunes()
+	style := e.styleAt(r)
+	lineStyle := -1
 	var glyphs [32]text.Glyph
 	line := glyphs[:0]
 	for _, g := range e.index.glyphs[startGlyph:] {
+		for style < len(e.styles) && e.styles[style].End <= r {
+			style++
+		}
+		gStyle := -1
+		if style < len(e.styles) && e.styles[style].Start <= r && e.styles[style].Material != (op.CallOp{}) {
+			gStyle = style
+		}
+		if gStyle != lineStyle {
+			if len(line) > 0 {
+				line = it.paintLine(gtx, e.shaper, line)
+			}
+			lineStyle = gStyle
+			it.material = material
+			if gStyle != -1 {
+				it.material = e.styles[gStyle].Material
+			}
+		}
 		var ok bool
 		if line, ok = it.paintGlyph(gtx, e.shaper, g, line); !ok {
 			break
 		}
+		r += int(g.Runes)
 	}
 
 	call := m.Stop()
@@ -360,23 +508,35 @@ func (e *textView) caretWidth(gtx layout.Context) int {
 // before painting to set the appropriate paint material.
 func (e *textView) PaintCaret(gtx layout.Context, material op.CallOp) {
 	carWidth2 := e.caretWidth(gtx)
-	caretPos, carAsc, carDesc := e.CaretInfo()
+	for i := -1; i < len(e.carets); i++ {
+		c := e.caret
+		if i >= 0 {
+			c = e.carets[i]
+		}
+		caretPos, carAsc, carDesc := e.caretInfo(c.start)
 
-	carRect := image.Rectangle{
-		Min: caretPos.Sub(image.Pt(carWidth2, carAsc)),
-		Max: caretPos.Add(image.Pt(carWidth2, carDesc)),
-	}
-	cl := image.Rectangle{Max: e.viewSize}
-	carRect = cl.Intersect(carRect)
-	if !carRect.Empty() {
-		defer clip.Rect(carRect).Push(gtx.Ops).Pop()
-		material.Add(gtx.Ops)
-		paint.PaintOp{}.Add(gtx.Ops)
+		carRect := image.Rectangle{
+			Min: caretPos.Sub(image.Pt(carWidth2, carAsc)),
+			Max: caretPos.Add(image.Pt(carWidth2, carDesc)),
+		}
+		cl := image.Rectangle{Max: e.viewSize}
+		carRect = cl.Intersect(carRect)
+		if !carRect.Empty() {
+			area := clip.Rect(carRect).Push(gtx.Ops)
+			material.Add(gtx.Ops)
+			paint.PaintOp{}.Add(gtx.Ops)
+			area.Pop()
+		}
 	}
 }
 
 func (e *textView) CaretInfo() (pos image.Point, ascent, descent int) {
-	caretStart := e.closestToRune(e.caret.start)
+	return e.caretInfo(e.caret.start)
+}
+
+// caretInfo is like CaretInfo for a caret at rune offset r.
+func (e *textView) caretInfo(r int) (pos image.Point, ascent, descent int) {
+	caretStart := e.closestToRune(r)
 
 	ascent = caretStart.ascent.Ceil()
 	descent = caretStart.descent.Ceil()
@@ -476,6 +636,12 @@ func (e *textView) Truncated() bool {
 }
 
 func (e *textView) layoutText(lt *text.Shaper) {
+	e.virt.enabled = lt != nil && !e.SingleLine && e.params.MaxLines == 0 && e.rr.Size() > virtualSize
+	if e.virt.enabled {
+		e.layoutParagraphs()
+		return
+	}
+	e.virt.paragraphs = e.virt.paragraphs[:0]
 	e.Seek(0, io.SeekStart)
 	var r io.Reader = e
 	if e.Mask != 0 {
@@ -485,7 +651,16 @@ func (e *textView) layoutText(lt *text.Shaper) {
 	e.index.reset()
 	it := textIterator{viewport: image.Rectangle{Max: image.Point{X: math.MaxInt, Y: math.MaxInt}}}
 	if lt != nil {
-		lt.Layout(e.params, r)
+		if e.stylesShape() {
+			b := bufio.NewReader(r)
+			var sb strings.Builder
+			b.WriteTo(&sb)
+			str := sb.String()
+			e.spans = e.styleSpans(e.spans[:0], 0, utf8.RuneCountInString(str))
+			lt.LayoutSpans(e.params, e.spans, str)
+		} else {
+			lt.Layout(e.params, r)
+		}
 		for {
 			g, ok := lt.NextGlyph()
 			if !it.processGlyph(g, ok) {
@@ -525,7 +700,17 @@ func (e *textView) CaretPos() (line, col int) {
 // editor itself.
 func (e *textView) CaretCoords() f32.Point {
 	pos := e.closestToRune(e.caret.start)
-	return f32.Pt(float32(pos.x)/64-float32(e.scrollOff.X), float32(pos.y-e.scrollOff.Y))
+	p := f32.Pt(float32(pos.x)/64-float32(e.scrollOff.X), float32(pos.y-e.scrollOff.Y))
+	if e.vertical() {
+		p = verticalTransform(e.viewSize.Y).Transform(p)
+	}
+	return p
+}
+
+// vertical reports whether the text is laid out vertically, and rotated
+// for display by layoutVertical.
+func (e *textView) vertical() bool {
+	return e.params.Locale.Direction.Axis() == system.Vertical
 }
 
 // indexRune returns the latest rune index and byte offset no later than r.
@@ -548,6 +733,10 @@ func (e *textView) indexRune(r int) offEntry {
 // runeOffset returns the byte offset into e.rr of the r'th rune.
 // r must be a valid rune index, usually returned by closestPosition.
 func (e *textView) runeOffset(r int) int {
+	if e.virt.enabled {
+		e.makeValid()
+		return e.paragraphRuneOffset(r)
+	}
 	const runesPerIndexEntry = 50
 	entry := e.indexRune(r)
 	lastEntry := e.offIndex[len(e.offIndex)-1].runes
@@ -565,6 +754,7 @@ func (e *textView) runeOffset(r int) int {
 func (e *textView) invalidate() {
 	e.offIndex = e.offIndex[:0]
 	e.valid = false
+	e.invalidateParagraphs()
 }
 
 // Replace the text between start and end with s. Indices are in runes.
@@ -576,25 +766,148 @@ func (e *textView) Replace(start, end int, s string) int {
 	startPos := e.closestToRune(start)
 	endPos := e.closestToRune(end)
 	startOff := e.runeOffset(startPos.runes)
+	endOff := e.runeOffset(endPos.runes)
 	replaceSize := endPos.runes - startPos.runes
 	sc := utf8.RuneCountInString(s)
 	newEnd := startPos.runes + sc
 
+	size := e.rr.Size()
 	e.rr.ReplaceRunes(int64(startOff), int64(replaceSize), s)
-	adjust := func(pos int) int {
-		switch {
-		case newEnd < pos && pos <= endPos.runes:
-			pos = newEnd
-		case endPos.runes < pos:
-			diff := newEnd - endPos.runes
-			pos = pos + diff
+	e.adjustCarets(startPos.runes, endPos.runes, newEnd)
+	e.adjustStyles(startPos.runes, endPos.runes, newEnd)
+	e.adjustMatches(startPos.runes, endPos.runes, newEnd)
+	e.adjustAnnotations(startPos.runes, endPos.runes, newEnd)
+	e.invalidateRange(int64(startOff), int64(endOff), int64(endOff)+e.rr.Size()-size)
+	return sc
+}
+
+// adjustPos returns the rune offset pos adjusted to the replacement of the
+// runes [start, end) of the text with the runes [start, newEnd).
+func adjustPos(pos, start, end, newEnd int) int {
+	switch {
+	case newEnd < pos && pos <= end:
+		pos = newEnd
+	case end < pos:
+		pos += newEnd - end
+	}
+	return pos
+}
+
+// changedRange is a range of runes of a text changed by edits, if pending.
+type changedRange struct {
+	start, end int
+	pending    bool
+}
+
+// add adjusts the range to the replacement of the runes [start, end) of the
+// text with the runes [start, newEnd), and extends it to cover the
+// replacement.
+func (r *changedRange) add(start, end, newEnd int) {
+	if !r.pending {
+		*r = changedRange{start: start, end: newEnd, pending: true}
+		return
+	}
+	r.start = min(adjustPos(r.start, start, end, newEnd), start)
+	r.end = max(adjustPos(r.end, start, end, newEnd), newEnd)
+}
+
+// paragraphRange extends the range of runes [start, end) to the paragraphs
+// containing it, without their trailing newlines. It returns the extended
+// range and its byte offsets.
+func (e *textView) paragraphRange(start, end int) (int, int, int64, int64) {
+	n := e.Len()
+	start, end = min(start, n), min(end, n)
+	startOff, endOff := e.ByteOffset(start), e.ByteOffset(end)
+	for startOff > 0 {
+		r, s, _ := e.ReadRuneBefore(startOff)
+		if s == 0 || r == '\n' {
+			break
 		}
-		return pos
+		startOff -= int64(s)
+		start--
 	}
-	e.caret.start = adjust(e.caret.start)
-	e.caret.end = adjust(e.caret.end)
-	e.invalidate()
-	return sc
+	for {
+		r, s, _ := e.ReadRuneAt(endOff)
+		if s == 0 || r == '\n' {
+			break
+		}
+		endOff += int64(s)
+		end++
+	}
+	return start, end, startOff, endOff
+}
+
+// invalidateRange invalidates the layout after the replacement of the bytes
+// [start, end) of the text with the bytes [start, newEnd).
+func (e *textView) invalidateRange(start, end, newEnd int64) {
+	if !e.virt.enabled {
+		e.invalidate()
+		return
+	}
+	// Reshape only the paragraphs affected by the replacement.
+	e.replaceParagraphs(start, end, newEnd)
+	e.offIndex = e.offIndex[:0]
+	e.valid = false
+}
+
+// applyChanges adjusts the caret and layout to the changes reported by the
+// source, if it is a ChangeReporter, and returns the changes.
+func (e *textView) applyChanges() []TextChange {
+	r, ok := e.rr.(ChangeReporter)
+	if !ok {
+		return nil
+	}
+	changes := r.Changes()
+	if len(changes) == 0 {
+		return nil
+	}
+	// The layout is invalidated once for the bytes [start, end) of the text
+	// before the changes, replaced by the bytes [start, newEnd) after them.
+	start, end, newEnd := changes[0].Start, changes[0].Start, changes[0].Start
+	for _, c := range changes {
+		runeEnd, runeNewEnd := c.StartRune+c.Runes, c.StartRune+c.NewRunes
+		e.adjustCarets(c.StartRune, runeEnd, runeNewEnd)
+		e.adjustStyles(c.StartRune, runeEnd, runeNewEnd)
+		e.adjustMatches(c.StartRune, runeEnd, runeNewEnd)
+		e.adjustAnnotations(c.StartRune, runeEnd, runeNewEnd)
+		if c.Start < start {
+			start = c.Start
+		}
+		if c.End > newEnd {
+			end += c.End - newEnd
+			newEnd = c.End
+		}
+		newEnd += c.Size - (c.End - c.Start)
+	}
+	e.invalidateRange(start, end, newEnd)
+	return changes
+}
+
+// byteRunes returns the number of runes before the byte offset off, using
+// only the layout of the text before off.
+func (e *textView) byteRunes(off int64) int {
+	var base offEntry
+	if e.virt.enabled && len(e.virt.paragraphs) > 0 {
+		e.positionParagraphs()
+		p := e.virt.paragraphs[e.paragraphAtByte(int(off))]
+		base = offEntry{runes: p.runeOff, bytes: p.byteOff}
+	} else {
+		for _, entry := range e.offIndex {
+			if int64(entry.bytes) > off {
+				break
+			}
+			base = entry
+		}
+	}
+	for int64(base.bytes) < off {
+		_, s, err := e.ReadRuneAt(int64(base.bytes))
+		if err != nil {
+			break
+		}
+		base.bytes += s
+		base.runes++
+	}
+	return base.runes
 }
 
 // MovePages moves the caret position by vertical pages of text, ensuring that
@@ -612,6 +925,10 @@ func (e *textView) MovePages(pages int, selAct selectionAction) {
 // moveByGraphemes returns the rune index resulting from moving the
 // specified number of grapheme clusters from startRuneidx.
 func (e *textView) moveByGraphemes(startRuneidx, graphemes int) int {
+	if e.virt.enabled {
+		e.makeValid()
+		return e.moveParagraphGraphemes(startRuneidx, graphemes)
+	}
 	if len(e.graphemes) == 0 {
 		return startRuneidx
 	}
@@ -786,7 +1103,7 @@ func (e *textView) SelectedText(buf []byte) []byte {
 	buf = buf[:end-start]
 	n, _ := e.rr.ReadAt(buf, int64(start))
 	// There is no way to reasonably handle a read error here. We rely upon
-	// implementations of textSource to provide other ways to signal errors
+	// implementations of TextSource to provide other ways to signal errors
 	// if the user cares about that, and here we use whatever data we were
 	// able to read.
 	return buf[:n]
@@ -837,9 +1154,16 @@ func (e *textView) ReadAt(p []byte, offset int64) (int, error) {
 
 // Regions returns visible regions covering the rune range [start,end).
 func (e *textView) Regions(start, end int, regions []Region) []Region {
+	e.makeValid()
 	viewport := image.Rectangle{
 		Min: e.scrollOff,
 		Max: e.viewSize.Add(e.scrollOff),
 	}
-	return e.index.locate(viewport, start, end, regions)
+	regions = e.index.locate(viewport, start, end, regions)
+	if e.vertical() {
+		for i := range regions {
+			regions[i].Bounds = verticalRect(regions[i].Bounds, e.viewSize.Y)
+		}
+	}
+	return regions
 }
@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+
+	"gioui.org/f32"
+	"gioui.org/io/key"
+	"gioui.org/io/system"
+	"gioui.org/layout"
+	"gioui.org/op"
+)
+
+// verticalText reports whether the locale of gtx lays out vertical text.
+func verticalText(gtx layout.Context) bool {
+	return gtx.Locale.Direction.Axis() == system.Vertical
+}
+
+// layoutVertical lays out w, which lays out text as if it were horizontal,
+// rotated for vertical text. The constraints of w are transposed, such that
+// its widths are the heights of the rotated content.
+func layoutVertical(gtx layout.Context, w layout.Widget) layout.Dimensions {
+	cs := gtx.Constraints
+	gtx.Constraints = layout.Constraints{
+		Min: image.Pt(cs.Min.Y, cs.Min.X),
+		Max: image.Pt(cs.Max.Y, cs.Max.X),
+	}
+	m := op.Record(gtx.Ops)
+	dims := w(gtx)
+	call := m.Stop()
+	defer op.Affine(verticalTransform(dims.Size.Y)).Push(gtx.Ops).Pop()
+	call.Add(gtx.Ops)
+	return layout.Dimensions{Size: image.Pt(dims.Size.Y, dims.Size.X)}
+}
+
+// verticalTransform rotates text of the given height clockwise, such that
+// its first line is on the right.
+func verticalTransform(height int) f32.Affine2D {
+	return f32.NewAffine2D(0, -1, float32(height), 1, 0, 0)
+}
+
+// verticalRect returns the rotated form of a rectangle in the coordinates of
+// text of the given height.
+func verticalRect(r image.Rectangle, height int) image.Rectangle {
+	return image.Rect(height-r.Max.Y, r.Min.X, height-r.Min.Y, r.Max.X)
+}
+
+// verticalKey maps the arrow keys to their meaning in horizontal text, for
+// navigating vertical text: down moves forward in a line and left moves to
+// the next line.
+func verticalKey(gtx layout.Context, name key.Name) key.Name {
+	if !verticalText(gtx) {
+		return name
+	}
+	switch name {
+	case key.NameDownArrow:
+		return key.NameRightArrow
+	case key.NameUpArrow:
+		return key.NameLeftArrow
+	case key.NameLeftArrow:
+		return key.NameDownArrow
+	case key.NameRightArrow:
+		return key.NameUpArrow
+	}
+	return name
+}